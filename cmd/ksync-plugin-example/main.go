@@ -0,0 +1,231 @@
+// ksync-plugin-example is a reference plugin serving a `file` fetcher and a `max-size`
+// validator over unix socket
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"arhat.dev/pkg/log"
+	"github.com/spf13/pflag"
+
+	"arhat.dev/ksync/pkg/fetcher"
+	"arhat.dev/ksync/pkg/plugin"
+	"arhat.dev/ksync/pkg/validator"
+)
+
+func main() {
+	var socket string
+	pflag.StringVar(&socket, "socket", "/var/run/ksync/example.sock", "unix socket to listen on")
+	pflag.Parse()
+
+	if err := run(socket); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "failed to run plugin: %v", err)
+		os.Exit(1)
+	}
+}
+
+func run(socket string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	_ = os.Remove(socket)
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Close() }()
+
+	srv := plugin.NewServer(ctx, log.Log.WithName("plugin"),
+		map[string]fetcher.FactoryFunc{"file": newFileFetcher},
+		map[string]validator.FactoryFunc{"max-size": newMaxSizeValidator},
+	)
+
+	return srv.Serve(l)
+}
+
+// newFileFetcher reads files in `dir` as data periodically (config `interval`, defaults to 10s),
+// file names are used as data keys
+func newFileFetcher(ctx context.Context, logger log.Interface, config *fetcher.Config) (fetcher.Interface, error) {
+	dir := config.Plugin["dir"]
+	if dir == "" {
+		return nil, fmt.Errorf("dir is required")
+	}
+
+	interval := 10 * time.Second
+	if s, ok := config.Plugin["interval"]; ok {
+		var err error
+		interval, err = time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval: %w", err)
+		}
+	}
+
+	return &fileFetcher{
+		ctx:      ctx,
+		log:      logger,
+		dir:      dir,
+		interval: interval,
+		dataCh:   make(chan map[string][]byte),
+		mu:       new(sync.RWMutex),
+	}, nil
+}
+
+type fileFetcher struct {
+	ctx      context.Context
+	log      log.Interface
+	dir      string
+	interval time.Duration
+
+	dataCh chan map[string][]byte
+
+	lastMessageAt time.Time
+	readErr       error
+	mu            *sync.RWMutex
+}
+
+func (f *fileFetcher) Start(stop <-chan struct{}) error {
+	go func() {
+		defer close(f.dataCh)
+
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+
+		for {
+			data, err := f.read()
+			func() {
+				f.mu.Lock()
+				defer f.mu.Unlock()
+
+				f.readErr = err
+				if err == nil {
+					f.lastMessageAt = time.Now()
+				}
+			}()
+
+			if err != nil {
+				f.log.I("failed to read files", log.Error(err))
+			} else {
+				select {
+				case f.dataCh <- data:
+				case <-stop:
+					return
+				case <-f.ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			case <-f.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (f *fileFetcher) read() (map[string][]byte, error) {
+	files, err := ioutil.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]byte)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		data[file.Name()], err = ioutil.ReadFile(filepath.Join(f.dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+func (f *fileFetcher) Retrieve() <-chan map[string][]byte {
+	return f.dataCh
+}
+
+func (f *fileFetcher) Stop() error {
+	return nil
+}
+
+func (f *fileFetcher) Status() fetcher.Status {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return fetcher.Status{
+		Method:        "file",
+		Connected:     f.readErr == nil && !f.lastMessageAt.IsZero(),
+		LastMessageAt: f.lastMessageAt,
+	}
+}
+
+// newMaxSizeValidator rejects data larger than config `maxBytes`
+func newMaxSizeValidator(_ context.Context, _ log.Interface, config *validator.Config) (validator.Interface, error) {
+	maxBytes, err := strconv.Atoi(config.Plugin["maxBytes"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid maxBytes: %w", err)
+	}
+
+	return &maxSizeValidator{dataKeys: config.DataKeys, maxBytes: maxBytes}, nil
+}
+
+type maxSizeValidator struct {
+	dataKeys []string
+	maxBytes int
+}
+
+func (v *maxSizeValidator) Validate(_ *validator.ValidationContext, data map[string][]byte) *validator.DataMsg {
+	result := &validator.DataMsg{
+		Data:   make(map[string][]byte),
+		Errors: make(map[string]error),
+	}
+
+	check := func(k string, d []byte) {
+		if len(d) > v.maxBytes {
+			result.Errors[k] = fmt.Errorf("data size %d exceeds %d bytes", len(d), v.maxBytes)
+			return
+		}
+
+		result.Data[k] = d
+	}
+
+	if len(v.dataKeys) == 0 {
+		for k, d := range data {
+			check(k, d)
+		}
+		return result
+	}
+
+	for _, k := range v.dataKeys {
+		if d, ok := data[k]; ok {
+			check(k, d)
+		}
+	}
+
+	return result
+}
@@ -5,9 +5,13 @@ import (
 	"os"
 
 	"arhat.dev/ksync/pkg/cmd"
+	"arhat.dev/ksync/pkg/validator"
 )
 
 func main() {
+	// exec validators run commands with resource limits through ksync itself
+	validator.RunExecLimitsShim()
+
 	if err := cmd.NewKsyncCmd().Execute(); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "failed to run ksync: %v", err)
 	}
@@ -8,8 +8,13 @@ require (
 	github.com/goiiot/libmqtt v0.9.6
 	github.com/itchyny/gojq v0.11.2
 	github.com/spf13/cobra v1.1.1
+	github.com/spf13/pflag v1.0.5
+	go.opentelemetry.io/otel v0.13.0
 	go.uber.org/multierr v1.6.0
 	golang.org/x/net v0.0.0-20201110031124-69a78807bb2b
+	golang.org/x/sys v0.0.0-20201113233024-12cec1faf1ba
+	google.golang.org/grpc v1.33.2
+	google.golang.org/protobuf v1.25.0
 	gopkg.in/yaml.v2 v2.3.0
 	gopkg.in/yaml.v3 v3.0.0-20200615113413-eeeca48fe776
 	k8s.io/api v0.19.4
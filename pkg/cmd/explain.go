@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"arhat.dev/ksync/pkg/conf"
+	"arhat.dev/ksync/pkg/controller"
+)
+
+func newExplainCmd(appCtx *context.Context, config *conf.KsyncConfig) *cobra.Command {
+	var (
+		files      []string
+		kubeconfig string
+		offline    bool
+		output     string
+	)
+
+	explainCmd := &cobra.Command{
+		Use:   "explain <kind>/<namespace>/<name>",
+		Short: "Show configmaps and secrets can trigger reload of the workload",
+		Long: `Show configmaps and secrets can trigger reload of the workload, kind is one of pod, deploy, ds, sts
+
+Objects in manifest files (-f) are used before looking up in the cluster,
+use --offline to explain manifest files only`,
+		Example: `  ksync explain deploy/default/foo
+  ksync explain --offline -f manifests.yaml deploy/default/foo`,
+		Args:          cobra.ExactArgs(1),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var objects []runtime.Object
+			for _, f := range files {
+				objs, err := readManifestFile(f)
+				if err != nil {
+					return err
+				}
+
+				objects = append(objects, objs...)
+			}
+
+			var kubeClient kubeclient.Interface
+			if !offline {
+				clientConfig := config.Ksync.KubeClient
+				if kubeconfig != "" {
+					clientConfig.KubeconfigPath = kubeconfig
+				}
+
+				var err error
+				kubeClient, _, err = clientConfig.NewKubeClient(nil, false)
+				if err != nil {
+					return fmt.Errorf("failed to create kube client (use --offline for manifests only): %w", err)
+				}
+			}
+
+			explanation, err := controller.Explain(*appCtx, kubeClient, objects, args[0])
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(explanation)
+			case "", "text":
+				return printExplanation(cmd.OutOrStdout(), explanation)
+			default:
+				return fmt.Errorf("unsupported output format %q", output)
+			}
+		},
+	}
+
+	flags := explainCmd.Flags()
+	flags.StringSliceVarP(&files, "filename", "f", nil, "manifest files to explain, use - for stdin")
+	flags.StringVar(&kubeconfig, "kubeconfig", os.Getenv("KUBECONFIG"), "path to the kubeconfig file")
+	flags.BoolVar(&offline, "offline", false, "do not access the cluster")
+	flags.StringVarP(&output, "output", "o", "text", "output format, one of text, json")
+
+	return explainCmd
+}
+
+func readManifestFile(file string) ([]runtime.Object, error) {
+	var r io.Reader
+	if file == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open manifest file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+
+		r = f
+	}
+
+	var (
+		objects []runtime.Object
+		decoder = scheme.Codecs.UniversalDeserializer()
+		reader  = yaml.NewYAMLReader(bufio.NewReader(r))
+	)
+	for {
+		doc, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+
+			return nil, fmt.Errorf("failed to read manifest file %s: %w", file, err)
+		}
+
+		if len(strings.TrimSpace(string(doc))) == 0 {
+			continue
+		}
+
+		obj, _, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			// ignore unknown objects, they are not related
+			if runtime.IsNotRegisteredError(err) {
+				continue
+			}
+
+			return nil, fmt.Errorf("failed to decode manifest in %s: %w", file, err)
+		}
+
+		list, ok := obj.(*corev1.List)
+		if !ok {
+			objects = append(objects, obj)
+			continue
+		}
+
+		for _, item := range list.Items {
+			o, _, err := decoder.Decode(item.Raw, nil, nil)
+			if err != nil {
+				if runtime.IsNotRegisteredError(err) {
+					continue
+				}
+
+				return nil, fmt.Errorf("failed to decode list item in %s: %w", file, err)
+			}
+
+			objects = append(objects, o)
+		}
+	}
+}
+
+func printExplanation(w io.Writer, e *controller.Explanation) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	_, _ = fmt.Fprintf(tw, "%s/%s/%s\n", e.Kind, e.Namespace, e.Name)
+	if e.Kind != "pod" {
+		_, _ = fmt.Fprintln(tw, "\nWorkload triggers (rolling update):")
+		printExplainedTriggers(tw, e.Triggers, true)
+	}
+
+	for _, p := range e.Pods {
+		_, _ = fmt.Fprintf(tw, "\nPod %s triggers (pod deletion)", p.Name)
+		if p.Owner != "" {
+			_, _ = fmt.Fprintf(tw, ", owned by %s", p.Owner)
+		}
+		_, _ = fmt.Fprintln(tw, ":")
+
+		if p.Error != "" {
+			_, _ = fmt.Fprintf(tw, "  error: %s\n", p.Error)
+			continue
+		}
+
+		printExplainedTriggers(tw, p.Triggers, false)
+	}
+
+	if len(e.Notes) != 0 {
+		_, _ = fmt.Fprintln(tw, "\nNotes:")
+		for _, n := range e.Notes {
+			_, _ = fmt.Fprintf(tw, "  - %s\n", n)
+		}
+	}
+
+	return tw.Flush()
+}
+
+func printExplainedTriggers(w io.Writer, triggers []controller.ExplainedTrigger, showStamped bool) {
+	if len(triggers) == 0 {
+		_, _ = fmt.Fprintln(w, "  <none>")
+		return
+	}
+
+	if showStamped {
+		_, _ = fmt.Fprintln(w, "  CONFIG\tKEY\tSOURCES\tCURRENT HASH\tSTAMPED HASH\t")
+	} else {
+		_, _ = fmt.Fprintln(w, "  CONFIG\tKEY\tSOURCES\tCURRENT HASH\t")
+	}
+	for _, t := range triggers {
+		key := t.Key
+		if key == "" {
+			key = "*"
+		}
+
+		current := shortHash(t.CurrentHash)
+		if t.CurrentHash == "" {
+			current = "<not found>"
+		}
+
+		if !showStamped {
+			_, _ = fmt.Fprintf(w, "  %s %s/%s\t%s\t%s\t%s\t\n",
+				t.Kind, t.Namespace, t.Name, key, strings.Join(t.Sources, ", "), current)
+			continue
+		}
+
+		stamped := shortHash(t.StampedHash)
+		switch {
+		case t.StampedHash == "":
+			stamped = "<not stamped>"
+		case t.StampedHash != t.CurrentHash:
+			stamped += " (outdated)"
+		}
+
+		_, _ = fmt.Fprintf(w, "  %s %s/%s\t%s\t%s\t%s\t%s\t\n",
+			t.Kind, t.Namespace, t.Name, key, strings.Join(t.Sources, ", "), current, stamped)
+	}
+}
+
+func shortHash(h string) string {
+	if len(h) > 12 {
+		return h[:12]
+	}
+
+	return h
+}
@@ -2,15 +2,10 @@ package cmd
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"reflect"
 
-	"arhat.dev/pkg/envhelper"
 	"arhat.dev/pkg/kubehelper"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -18,12 +13,13 @@ import (
 
 	"arhat.dev/pkg/log"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
+	"github.com/spf13/pflag"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	"arhat.dev/ksync/pkg/conf"
 	"arhat.dev/ksync/pkg/constant"
 	"arhat.dev/ksync/pkg/controller"
+	"arhat.dev/ksync/pkg/plugin"
 )
 
 func NewKsyncCmd() *cobra.Command {
@@ -32,6 +28,7 @@ func NewKsyncCmd() *cobra.Command {
 		configFile   string
 		config       = new(conf.KsyncConfig)
 		cliLogConfig = new(log.Config)
+		reloadCh     = make(chan struct{}, 1)
 	)
 
 	ksyncCmd := &cobra.Command{
@@ -43,72 +40,34 @@ func NewKsyncCmd() *cobra.Command {
 				return nil
 			}
 
-			var err error
-			flags := cmd.Flags()
-			if flags.Changed("config") {
-				var configBytes []byte
-				configBytes, err = ioutil.ReadFile(configFile)
-				if err != nil {
-					return fmt.Errorf("failed to read config file %s: %v", configFile, err)
-				}
-
-				if err = yaml.Unmarshal(configBytes, config); err != nil {
-					return fmt.Errorf("failed to unmarshal config file %s: %v", configFile, err)
-				}
-			}
-
-			logConfigSet := config.Ksync.Log
-			if len(logConfigSet) > 0 {
-				if flags.Changed("log.format") {
-					logConfigSet[0].Format = cliLogConfig.Format
-				}
-
-				if flags.Changed("log.level") {
-					logConfigSet[0].Level = cliLogConfig.Level
-				}
-
-				if flags.Changed("log.file") {
-					logConfigSet[0].File = cliLogConfig.File
+			var onReload func()
+			if !cmd.HasParent() {
+				// only reload config when running controller
+				onReload = func() {
+					select {
+					case reloadCh <- struct{}{}:
+					default:
+						// reload already pending
+					}
 				}
-			} else {
-				logConfigSet = append(logConfigSet, *cliLogConfig)
-			}
-
-			if err = cmd.ParseFlags(os.Args); err != nil {
-				return err
-			}
-
-			err = log.SetDefaultLogger(logConfigSet)
-			if err != nil {
-				return err
 			}
 
-			var exit context.CancelFunc
-			appCtx, exit = context.WithCancel(context.Background())
-
-			sigCh := make(chan os.Signal, 1)
-			signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-			go func() {
-				exitCount := 0
-				for sig := range sigCh {
-					switch sig {
-					case os.Interrupt, syscall.SIGTERM:
-						exitCount++
-						if exitCount == 1 {
-							exit()
-						} else {
-							os.Exit(1)
-						}
-						//case syscall.SIGHUP:
-						//	// force reload
-					}
-				}
-			}()
+			var err error
+			appCtx, err = conf.ReadConfig(cmd, &configFile, cliLogConfig, config, onReload)
 
-			return nil
+			return err
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return run(appCtx, config)
+			return run(appCtx, config, reloadCh, func() (*conf.KsyncConfig, error) {
+				newConfig := new(conf.KsyncConfig)
+				err := conf.ReloadConfig(cmd, configFile,
+					flagsForKsync(newConfig, new(log.Config)), cliLogConfig, newConfig)
+				if err != nil {
+					return nil, err
+				}
+
+				return newConfig, nil
+			})
 		},
 	}
 
@@ -116,19 +75,34 @@ func NewKsyncCmd() *cobra.Command {
 	// config file
 	flags.StringVarP(&configFile, "config", "c",
 		constant.DefaultKsyncConfigFile, "path to the ksync config file")
-	flags.BoolVar(&config.Ksync.Namespaced, "namespaced", false,
+	flags.AddFlagSet(flagsForKsync(config, cliLogConfig))
+
+	ksyncCmd.AddCommand(newExplainCmd(&appCtx, config))
+
+	return ksyncCmd
+}
+
+func flagsForKsync(config *conf.KsyncConfig, cliLogConfig *log.Config) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("ksync", pflag.ExitOnError)
+
+	fs.BoolVar(&config.Ksync.Namespaced, "namespaced", false,
 		"watch deployed namespace only")
-	flags.DurationVar(&config.Ksync.ReloadDelay, "reloadDelay",
+	fs.DurationVar(&config.Ksync.ReloadDelay, "reloadDelay",
 		constant.DefaultWorkloadReloadDelay, "set delay before reloading a workload")
-	flags.StringSliceVar(&config.Ksync.IgnoredNamespaces, "ignoredNamespaces",
+	fs.StringSliceVar(&config.Ksync.IgnoredNamespaces, "ignoredNamespaces",
 		nil, "ignore these namespaces when namespaced is true")
 
-	flags.AddFlagSet(kubehelper.FlagsForControllerConfig("ksync", "", cliLogConfig, &config.Ksync.ControllerConfig))
+	fs.AddFlagSet(kubehelper.FlagsForControllerConfig("ksync", "", cliLogConfig, &config.Ksync.ControllerConfig))
 
-	return ksyncCmd
+	return fs
 }
 
-func run(appCtx context.Context, config *conf.KsyncConfig) error {
+func run(
+	appCtx context.Context,
+	config *conf.KsyncConfig,
+	reloadCh <-chan struct{},
+	loadConfig func() (*conf.KsyncConfig, error),
+) error {
 	logger := log.Log.WithName("ksync")
 
 	logger.I("creating kube client for initialization")
@@ -137,57 +111,77 @@ func run(appCtx context.Context, config *conf.KsyncConfig) error {
 		return fmt.Errorf("failed to create kube client from kubeconfig: %w", err)
 	}
 
-	_, mtHandler, err := config.Ksync.Metrics.CreateIfEnabled(true)
-	if err != nil {
-		return fmt.Errorf("failed to create metrics provider: %w", err)
-	}
-
-	if mtHandler != nil {
-		mux := http.NewServeMux()
-		mux.Handle(config.Ksync.Metrics.HTTPPath, mtHandler)
-
-		tlsConfig, err2 := config.Ksync.Metrics.TLS.GetTLSConfig(true)
-		if err2 != nil {
-			return fmt.Errorf("failed to get tls config for metrics listener: %w", err2)
-		}
-
-		srv := &http.Server{
-			Handler:   mux,
-			Addr:      config.Ksync.Metrics.Endpoint,
-			TLSConfig: tlsConfig,
-		}
-
-		go func() {
-			err2 = srv.ListenAndServe()
-			if err2 != nil && !errors.Is(err2, http.ErrServerClosed) {
-				panic(err2)
-			}
-		}()
-	}
-
-	logger.I("creating controller")
-	ctrl, err := controller.NewController(appCtx, config)
-	if err != nil {
-		return fmt.Errorf("failed to create controller: %w", err)
-	}
-
 	evb := record.NewBroadcaster()
 	watchEventLogging := evb.StartLogging(func(format string, args ...interface{}) {
 		logger.I(fmt.Sprintf(format, args...), log.String("source", "event"))
 	})
 	watchEventRecording := evb.StartRecordingToSink(&typedcorev1.EventSinkImpl{
-		Interface: kubeClient.CoreV1().Events(envhelper.ThisPodNS()),
+		// events are created in the namespace of the involved object
+		Interface: kubeClient.CoreV1().Events(""),
 	})
 	defer func() {
 		watchEventLogging.Stop()
 		watchEventRecording.Stop()
 	}()
 
+	eventRecorder := evb.NewRecorder(scheme.Scheme, corev1.EventSource{
+		Component: "ksync",
+	})
+
+	err = plugin.Register(&config.Ksync.Plugins)
+	if err != nil {
+		return fmt.Errorf("failed to register plugins: %w", err)
+	}
+
+	logger.I("creating controller")
+	ctrl, err := controller.NewController(appCtx, config, eventRecorder)
+	if err != nil {
+		return fmt.Errorf("failed to create controller: %w", err)
+	}
+
+	telemetrySrv := newTelemetryServer(ctrl.DebugHandler(), ctrl.HealthHandler())
+	err = telemetrySrv.apply(&config.Ksync.Metrics, &config.Ksync.Debug, &config.Ksync.Health)
+	if err != nil {
+		return err
+	}
+	defer telemetrySrv.close()
+
+	go func(current *conf.KsyncConfig) {
+		reloadLogger := logger
+		for range reloadCh {
+			reloadLogger.I("reloading config")
+			newConfig, err2 := loadConfig()
+			if err2 != nil {
+				reloadLogger.I("failed to load new config, keep using the old one", log.Error(err2))
+				continue
+			}
+
+			if !reflect.DeepEqual(newConfig.Ksync.Log, current.Ksync.Log) {
+				err2 = conf.ReloadDefaultLogger(newConfig.Ksync.Log)
+				if err2 != nil {
+					reloadLogger.I("failed to reload logger", log.Error(err2))
+				} else {
+					reloadLogger = log.Log.WithName("ksync")
+				}
+			}
+
+			err2 = telemetrySrv.apply(&newConfig.Ksync.Metrics, &newConfig.Ksync.Debug, &newConfig.Ksync.Health)
+			if err2 != nil {
+				reloadLogger.I("failed to apply new telemetry config", log.Error(err2))
+			}
+
+			err2 = plugin.Register(&newConfig.Ksync.Plugins)
+			if err2 != nil {
+				reloadLogger.I("failed to register new plugins", log.Error(err2))
+			}
+
+			ctrl.Reload(newConfig)
+			current = newConfig
+		}
+	}(config)
+
 	logger.V("creating leader elector")
-	elector, err := config.Ksync.LeaderElection.CreateElector("ksync", kubeClient,
-		evb.NewRecorder(scheme.Scheme, corev1.EventSource{
-			Component: "ksync",
-		}),
+	elector, err := config.Ksync.LeaderElection.CreateElector("ksync", kubeClient, eventRecorder,
 		//  elected
 		func(ctx context.Context) {
 			logger.I("starting controller")
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"arhat.dev/pkg/log"
+	"arhat.dev/pkg/perfhelper"
+
+	"arhat.dev/ksync/pkg/conf"
+	"arhat.dev/ksync/pkg/constant"
+)
+
+func newTelemetryServer(debugHandler, healthHandler http.Handler) *telemetryServer {
+	return &telemetryServer{
+		logger:        log.Log.WithName("telemetry"),
+		debugHandler:  debugHandler,
+		healthHandler: healthHandler,
+		mu:            new(sync.Mutex),
+	}
+}
+
+// telemetryServer serves metrics, debug and health api, can be reconfigured at runtime
+type telemetryServer struct {
+	logger log.Interface
+
+	// metrics handler is created only once since metrics provider is global
+	handler       http.Handler
+	handlerFormat string
+
+	debugHandler  http.Handler
+	healthHandler http.Handler
+
+	config       *perfhelper.MetricsConfig
+	debugConfig  *conf.DebugConfig
+	healthConfig *conf.HealthConfig
+	srv          *http.Server
+	mu           *sync.Mutex
+}
+
+func (s *telemetryServer) apply(
+	config *perfhelper.MetricsConfig,
+	debugConfig *conf.DebugConfig,
+	healthConfig *conf.HealthConfig,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.config != nil && reflect.DeepEqual(*s.config, *config) &&
+		s.debugConfig != nil && reflect.DeepEqual(*s.debugConfig, *debugConfig) &&
+		s.healthConfig != nil && reflect.DeepEqual(*s.healthConfig, *healthConfig) {
+		return nil
+	}
+
+	if s.handler == nil {
+		_, mtHandler, err := config.CreateIfEnabled(true)
+		if err != nil {
+			return fmt.Errorf("failed to create metrics provider: %w", err)
+		}
+
+		s.handler = mtHandler
+		s.handlerFormat = config.Format
+	} else if config.Enabled && config.Format != s.handlerFormat {
+		s.logger.I("metrics format change requires restart, keep using the old one",
+			log.String("old", s.handlerFormat), log.String("new", config.Format))
+	}
+
+	s.closeServer()
+
+	cfg := *config
+	s.config = &cfg
+	debugCfg := *debugConfig
+	s.debugConfig = &debugCfg
+	healthCfg := *healthConfig
+	s.healthConfig = &healthCfg
+
+	metricsEnabled := config.Enabled && s.handler != nil
+	debugEnabled := debugConfig.Enabled && s.debugHandler != nil
+	healthEnabled := healthConfig.Enabled && s.healthHandler != nil
+	if !metricsEnabled && !debugEnabled && !healthEnabled {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	if metricsEnabled {
+		mux.Handle(config.HTTPPath, s.handler)
+	}
+
+	if debugEnabled {
+		debugPath := debugConfig.HTTPPath
+		if debugPath == "" {
+			debugPath = constant.DefaultDebugHTTPPath
+		}
+
+		mux.Handle(debugPath, withDebugAuth(&debugCfg.Auth, s.debugHandler))
+	}
+
+	if healthEnabled {
+		healthPath := healthConfig.HTTPPath
+		if healthPath == "" {
+			healthPath = constant.DefaultHealthHTTPPath
+		}
+
+		mux.Handle(healthPath, s.healthHandler)
+	}
+
+	tlsConfig, err := config.TLS.GetTLSConfig(true)
+	if err != nil {
+		return fmt.Errorf("failed to get tls config for metrics listener: %w", err)
+	}
+
+	l, err := net.Listen("tcp", config.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to listen for telemetry: %w", err)
+	}
+
+	srv := &http.Server{
+		Handler:   mux,
+		Addr:      config.Endpoint,
+		TLSConfig: tlsConfig,
+	}
+
+	go func() {
+		var err2 error
+		if tlsConfig != nil {
+			err2 = srv.ServeTLS(l, "", "")
+		} else {
+			err2 = srv.Serve(l)
+		}
+
+		if err2 != nil && !errors.Is(err2, http.ErrServerClosed) {
+			s.logger.E("telemetry server exited", log.Error(err2))
+		}
+	}()
+
+	s.srv = srv
+
+	return nil
+}
+
+func (s *telemetryServer) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closeServer()
+}
+
+func (s *telemetryServer) closeServer() {
+	if s.srv == nil {
+		return
+	}
+
+	_ = s.srv.Close()
+	s.srv = nil
+}
+
+// withDebugAuth requires the request to match one of the configured auth methods
+func withDebugAuth(config *conf.DebugAuthConfig, h http.Handler) http.Handler {
+	if config.BearerToken == "" && config.Username == "" {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if config.BearerToken != "" {
+			auth := r.Header.Get("Authorization")
+			if strings.HasPrefix(auth, "Bearer ") &&
+				subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(config.BearerToken)) == 1 {
+				h.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if config.Username != "" {
+			username, password, ok := r.BasicAuth()
+			if ok &&
+				subtle.ConstantTimeCompare([]byte(username), []byte(config.Username)) == 1 &&
+				subtle.ConstantTimeCompare([]byte(password), []byte(config.Password)) == 1 {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("WWW-Authenticate", `Basic realm="ksync"`)
+		}
+
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"arhat.dev/ksync/pkg/conf"
+)
+
+func TestWithDebugAuth(t *testing.T) {
+	h := withDebugAuth(&conf.DebugAuthConfig{BearerToken: "token"},
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		expectStatus  int
+	}{
+		{name: "Bearer Token", authorization: "Bearer token", expectStatus: http.StatusOK},
+		{name: "Bare Token", authorization: "token", expectStatus: http.StatusUnauthorized},
+		{name: "Wrong Token", authorization: "Bearer foo", expectStatus: http.StatusUnauthorized},
+		{name: "No Token", expectStatus: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != test.expectStatus {
+				t.Errorf("unexpected status %d", rec.Code)
+			}
+		})
+	}
+}
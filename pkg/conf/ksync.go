@@ -20,16 +20,132 @@ import (
 	"time"
 
 	"arhat.dev/pkg/kubehelper"
+	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/plugin"
 )
 
 type KsyncConfig struct {
 	Ksync KsyncAppConfig `json:"ksync" yaml:"ksync"`
 }
 
+func (c *KsyncConfig) GetLogConfig() log.ConfigSet {
+	return c.Ksync.Log
+}
+
+func (c *KsyncConfig) SetLogConfig(l log.ConfigSet) {
+	c.Ksync.Log = l
+}
+
 type KsyncAppConfig struct {
 	kubehelper.ControllerConfig `json:",inline" yaml:",inline"`
 
 	Namespaced        bool          `json:"namespaced" yaml:"namespaced"`
 	ReloadDelay       time.Duration `json:"reloadDelay" yaml:"reloadDelay"`
-	IgnoredNamespaces []string      `json:"ignoredNamespaces"`
+	IgnoredNamespaces []string      `json:"ignoredNamespaces" yaml:"ignoredNamespaces"`
+
+	Debug DebugConfig `json:"debug" yaml:"debug"`
+
+	Health HealthConfig `json:"health" yaml:"health"`
+
+	// Sync restricts where synced data can be written
+	Sync SyncConfig `json:"sync" yaml:"sync"`
+
+	// Mirror restricts namespaces configs can be mirrored from and to
+	Mirror MirrorConfig `json:"mirror" yaml:"mirror"`
+
+	// Plugins serving fetcher and validator methods
+	Plugins plugin.Methods `json:"plugins" yaml:"plugins"`
+}
+
+// DebugConfig for the debug http api served on the metrics listener
+type DebugConfig struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	HTTPPath string `json:"httpPath" yaml:"httpPath"`
+
+	Auth DebugAuthConfig `json:"auth" yaml:"auth"`
+}
+
+// HealthConfig for the liveness http api served on the metrics listener
+type HealthConfig struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	HTTPPath string `json:"httpPath" yaml:"httpPath"`
+}
+
+// SyncConfig restricts sync targets, sync targets are limited to the namespace of the object
+// annotated with the sync config by default
+type SyncConfig struct {
+	// AllowedTargetNamespaces are namespaces sync targets can be written in besides the namespace
+	// of the annotated object, `*` allows all namespaces
+	AllowedTargetNamespaces []string `json:"allowedTargetNamespaces" yaml:"allowedTargetNamespaces"`
+
+	// AllowNamespaceSelector allows targets with namespace selector, selected namespaces are
+	// still limited by AllowedTargetNamespaces
+	AllowNamespaceSelector bool `json:"allowNamespaceSelector" yaml:"allowNamespaceSelector"`
+}
+
+// TargetNamespaceAllowed checks whether data synced for object in namespace can be written in
+// targetNamespace
+func (c *SyncConfig) TargetNamespaceAllowed(namespace, targetNamespace string) bool {
+	if namespace == targetNamespace {
+		return true
+	}
+
+	for _, ns := range c.AllowedTargetNamespaces {
+		if ns == "*" || ns == targetNamespace {
+			return true
+		}
+	}
+
+	return false
+}
+
+// systemNamespaces are never mirrored to unless explicitly included
+var systemNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// MirrorConfig restricts mirroring, nothing is mirrored by default
+type MirrorConfig struct {
+	// AllowedSourceNamespaces are namespaces configs can be mirrored from, `*` allows all namespaces
+	AllowedSourceNamespaces []string `json:"allowedSourceNamespaces" yaml:"allowedSourceNamespaces"`
+
+	// ExcludedNamespaces are never mirrored to
+	ExcludedNamespaces []string `json:"excludedNamespaces" yaml:"excludedNamespaces"`
+
+	// IncludeSystemNamespaces allows mirroring to kube-system, kube-public and kube-node-lease
+	IncludeSystemNamespaces bool `json:"includeSystemNamespaces" yaml:"includeSystemNamespaces"`
+}
+
+// SourceNamespaceAllowed checks whether configs in namespace can be mirrored
+func (c *MirrorConfig) SourceNamespaceAllowed(namespace string) bool {
+	for _, ns := range c.AllowedSourceNamespaces {
+		if ns == "*" || ns == namespace {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TargetNamespaceAllowed checks whether configs can be mirrored to namespace
+func (c *MirrorConfig) TargetNamespaceAllowed(namespace string) bool {
+	excluded := c.ExcludedNamespaces
+	if !c.IncludeSystemNamespaces {
+		excluded = append(excluded[:len(excluded):len(excluded)], systemNamespaces...)
+	}
+
+	for _, ns := range excluded {
+		if ns == namespace {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DebugAuthConfig for debug http api, auth is not required if none of these is set,
+// request is authorized if it matches any of the configured method
+type DebugAuthConfig struct {
+	BearerToken string `json:"bearerToken" yaml:"bearerToken"`
+	Username    string `json:"username" yaml:"username"`
+	Password    string `json:"password" yaml:"password"`
 }
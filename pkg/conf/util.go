@@ -17,16 +17,19 @@ limitations under the License.
 package conf
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"arhat.dev/pkg/envhelper"
 	"arhat.dev/pkg/log"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"gopkg.in/yaml.v2"
 
 	"arhat.dev/ksync/pkg/constant"
@@ -37,16 +40,130 @@ type Config interface {
 	SetLogConfig(log.ConfigSet)
 }
 
+// ReadConfig reads config file and command line flags into config, set default logger
+// and returns the application context
+//
+// onReload (if not nil) is called when SIGHUP received or content of the config file changed
 func ReadConfig(
 	cmd *cobra.Command,
 	configFile *string,
 	cliLogConfig *log.Config,
 	config Config,
+	onReload func(),
 ) (context.Context, error) {
 	flags := cmd.Flags()
-	configBytes, err := ioutil.ReadFile(*configFile)
-	if err != nil && flags.Changed("config") {
-		return nil, fmt.Errorf("failed to read config file %s: %v", *configFile, err)
+	err := readConfigFile(*configFile, flags.Changed("config"), config)
+	if err != nil {
+		return nil, err
+	}
+
+	overrideLogConfig(flags, cliLogConfig, config)
+
+	if err = cmd.ParseFlags(os.Args); err != nil {
+		return nil, err
+	}
+
+	err = log.SetDefaultLogger(config.GetLogConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to set default logger: %w", err)
+	}
+
+	appCtx, exit := context.WithCancel(context.WithValue(context.Background(), constant.ContextKeyConfig, config))
+
+	sigCh := make(chan os.Signal, 1)
+	if onReload != nil {
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+		go watchConfigFile(appCtx, *configFile, constant.DefaultConfigFileCheckInterval, onReload)
+	} else {
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	}
+
+	go func() {
+		exitCount := 0
+		for sig := range sigCh {
+			switch sig {
+			case os.Interrupt, syscall.SIGTERM:
+				exitCount++
+				if exitCount == 1 {
+					exit()
+				} else {
+					os.Exit(1)
+				}
+			case syscall.SIGHUP:
+				// force reload
+				onReload()
+			}
+		}
+	}()
+
+	return appCtx, nil
+}
+
+// ReloadConfig reads config file into a fresh config with flags bound to fs,
+// command line flags explicitly set in cmd take precedence over the config file
+// as they do in ReadConfig
+func ReloadConfig(
+	cmd *cobra.Command,
+	configFile string,
+	fs *pflag.FlagSet,
+	cliLogConfig *log.Config,
+	config Config,
+) error {
+	flags := cmd.Flags()
+	err := readConfigFile(configFile, flags.Changed("config"), config)
+	if err != nil {
+		return err
+	}
+
+	overrideLogConfig(flags, cliLogConfig, config)
+
+	// command line flags take precedence over config file
+	err = applyChangedFlags(flags, fs)
+	if err != nil {
+		return fmt.Errorf("failed to apply command line flags: %w", err)
+	}
+
+	return nil
+}
+
+func applyChangedFlags(from, to *pflag.FlagSet) (err error) {
+	from.Visit(func(f *pflag.Flag) {
+		target := to.Lookup(f.Name)
+		if target == nil || err != nil {
+			return
+		}
+
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			if tsv, ok := target.Value.(pflag.SliceValue); ok {
+				err = tsv.Replace(sv.GetSlice())
+				return
+			}
+		}
+
+		err = target.Value.Set(f.Value.String())
+	})
+
+	return
+}
+
+// ReloadDefaultLogger replaces the default logger with a new one created from config
+//
+// loggers derived from the old default logger are not affected
+func ReloadDefaultLogger(config log.ConfigSet) error {
+	l, err := log.New("", config)
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+
+	log.Log = l
+	return nil
+}
+
+func readConfigFile(configFile string, required bool, config Config) error {
+	configBytes, err := ioutil.ReadFile(configFile)
+	if err != nil && required {
+		return fmt.Errorf("failed to read config file %s: %v", configFile, err)
 	}
 
 	if len(configBytes) > 0 {
@@ -64,10 +181,14 @@ func ReadConfig(
 		})
 
 		if err = yaml.Unmarshal([]byte(configStr), config); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal config file %s: %v", *configFile, err)
+			return fmt.Errorf("failed to unmarshal config file %s: %v", configFile, err)
 		}
 	}
 
+	return nil
+}
+
+func overrideLogConfig(flags *pflag.FlagSet, cliLogConfig *log.Config, config Config) {
 	logConfigSet := config.GetLogConfig()
 	if len(logConfigSet) > 0 {
 		if flags.Changed("log.format") {
@@ -85,36 +206,35 @@ func ReadConfig(
 		logConfigSet = append(logConfigSet, *cliLogConfig)
 	}
 	config.SetLogConfig(logConfigSet)
+}
 
-	if err = cmd.ParseFlags(os.Args); err != nil {
-		return nil, err
-	}
+// watchConfigFile checks content of the config file periodically and calls onChange
+// when changed, polling is used since config file is usually mounted from a configmap
+// and updated by kubelet with symlink swapping
+func watchConfigFile(ctx context.Context, configFile string, interval time.Duration, onChange func()) {
+	last, _ := ioutil.ReadFile(configFile)
 
-	err = log.SetDefaultLogger(logConfigSet)
-	if err != nil {
-		return nil, fmt.Errorf("failed to set default logger: %w", err)
-	}
+	tk := time.NewTicker(interval)
+	defer tk.Stop()
 
-	appCtx, exit := context.WithCancel(context.WithValue(context.Background(), constant.ContextKeyConfig, config))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tk.C:
+		}
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		exitCount := 0
-		for sig := range sigCh {
-			switch sig {
-			case os.Interrupt, syscall.SIGTERM:
-				exitCount++
-				if exitCount == 1 {
-					exit()
-				} else {
-					os.Exit(1)
-				}
-				//case syscall.SIGHUP:
-				//	// force reload
-			}
+		current, err := ioutil.ReadFile(configFile)
+		if err != nil {
+			// file can be missing during update
+			continue
 		}
-	}()
 
-	return appCtx, nil
+		if bytes.Equal(last, current) {
+			continue
+		}
+
+		last = current
+		onChange()
+	}
 }
@@ -0,0 +1,128 @@
+package conf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"arhat.dev/pkg/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func TestReloadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ksync-conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	flagsFor := func(config *KsyncConfig) *pflag.FlagSet {
+		fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		fs.DurationVar(&config.Ksync.ReloadDelay, "reloadDelay", time.Second, "")
+		fs.StringSliceVar(&config.Ksync.IgnoredNamespaces, "ignoredNamespaces", nil, "")
+		return fs
+	}
+
+	tests := []struct {
+		name      string
+		content   string
+		cliArgs   []string
+		expectErr bool
+
+		expectReloadDelay time.Duration
+		expectIgnored     []string
+	}{
+		{
+			name: "Valid",
+			content: `ksync:
+  reloadDelay: 5s
+  ignoredNamespaces: [foo]
+  sync:
+    allowedTargetNamespaces: [bar]
+`,
+			expectReloadDelay: 5 * time.Second,
+			expectIgnored:     []string{"foo"},
+		},
+		{
+			name: "Command Line Precedence",
+			content: `ksync:
+  reloadDelay: 5s
+  ignoredNamespaces: [foo]
+`,
+			cliArgs:           []string{"--reloadDelay=10s"},
+			expectReloadDelay: 10 * time.Second,
+			expectIgnored:     []string{"foo"},
+		},
+		{
+			name:      "Invalid",
+			content:   "ksync: [",
+			expectErr: true,
+		},
+		{
+			name: "Invalid Type",
+			content: `ksync:
+  reloadDelay: [5s]
+`,
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configFile := filepath.Join(dir, "config.yaml")
+			if err := ioutil.WriteFile(configFile, []byte(test.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := &cobra.Command{}
+			cmd.Flags().String("config", "", "")
+			cmd.Flags().AddFlagSet(flagsFor(new(KsyncConfig)))
+			if err := cmd.Flags().Parse(append([]string{"--config=" + configFile}, test.cliArgs...)); err != nil {
+				t.Fatal(err)
+			}
+
+			config := new(KsyncConfig)
+			err := ReloadConfig(cmd, configFile, flagsFor(config), &log.Config{Level: "info"}, config)
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("invalid config not rejected")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if config.Ksync.ReloadDelay != test.expectReloadDelay {
+				t.Errorf("unexpected reload delay %v", config.Ksync.ReloadDelay)
+			}
+
+			if len(config.Ksync.IgnoredNamespaces) != len(test.expectIgnored) ||
+				config.Ksync.IgnoredNamespaces[0] != test.expectIgnored[0] {
+				t.Errorf("unexpected ignored namespaces %v", config.Ksync.IgnoredNamespaces)
+			}
+
+			if len(config.Ksync.Log) != 1 || config.Ksync.Log[0].Level != "info" {
+				t.Errorf("command line log config not applied: %v", config.Ksync.Log)
+			}
+		})
+	}
+
+	t.Run("Missing File", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("config", "", "")
+		if err := cmd.Flags().Parse([]string{"--config=" + filepath.Join(dir, "missing.yaml")}); err != nil {
+			t.Fatal(err)
+		}
+
+		config := new(KsyncConfig)
+		err := ReloadConfig(cmd, filepath.Join(dir, "missing.yaml"), flagsFor(config), &log.Config{}, config)
+		if err == nil {
+			t.Error("missing config file not rejected")
+		}
+	})
+}
@@ -32,6 +32,24 @@ const (
 
 	// AnnotationSyncConfig to instruct controller how to sync config
 	AnnotationSyncConfig = "ksync.arhat.dev/sync-config-ref"
+
+	// AnnotationGuardOverride on sync target to bypass guard validators once, removed after
+	// the next update
+	AnnotationGuardOverride = "ksync.arhat.dev/guard-override"
+
+	// AnnotationMirrorNamespaces is the label selector of namespaces to mirror the
+	// configmap/secret to, empty selects all namespaces
+	AnnotationMirrorNamespaces = "ksync.arhat.dev/mirror-namespaces"
+
+	// AnnotationPublishConfig to instruct controller how to publish config
+	AnnotationPublishConfig = "ksync.arhat.dev/publish-config-ref"
+
+	// AnnotationMirrorSource on mirrored copies, in the form of `<namespace>/<name>`
+	AnnotationMirrorSource = "ksync.arhat.dev/mirror-source"
+
+	// AnnotationSyncStatus on sync targets, status of the syncer in json, updated when health
+	// of fetchers changed
+	AnnotationSyncStatus = "ksync.arhat.dev/sync-status"
 )
 
 const (
@@ -20,4 +20,13 @@ type ContextKey string
 
 const (
 	ContextKeyConfig = ContextKey("config")
+
+	// ContextKeyDataGetter for getter of data in configmaps and secrets
+	ContextKeyDataGetter = ContextKey("dataGetter")
+
+	// ContextKeyNamespace for the namespace of the sync config
+	ContextKeyNamespace = ContextKey("namespace")
+
+	// ContextKeySyncTarget for the object synced by the syncer
+	ContextKeySyncTarget = ContextKey("syncTarget")
 )
@@ -22,4 +22,10 @@ const (
 	DefaultKsyncConfigFile = "/etc/ksync/config.yaml"
 
 	DefaultWorkloadReloadDelay = 5 * time.Second
+
+	DefaultConfigFileCheckInterval = 10 * time.Second
+
+	DefaultDebugHTTPPath = "/debug/ksync"
+
+	DefaultHealthHTTPPath = "/healthz"
 )
@@ -22,6 +22,22 @@ const (
 
 	LabelActionValueReload = "reload"
 	LabelActionValueSync   = "sync"
+	LabelActionValueMirror = "mirror"
+)
+
+// Label on objects managed by ksync, objects without it are never modified
+const (
+	LabelManagedBy = "ksync.arhat.dev/managed-by"
+
+	LabelManagedByValueMirror = "mirror"
+	LabelManagedByValueSync   = "sync"
+)
+
+// Label to select configs to be published to remote sources, can be used with action label
+const (
+	LabelPublish = "ksync.arhat.dev/publish"
+
+	LabelPublishValueEnabled = "true"
 )
 
 const (
@@ -23,7 +23,7 @@ func getTriggerMetaAndData(
 		stringData, binaryData = o.Data, o.BinaryData
 		kind, namespace, name = configKindCM, o.Namespace, o.Name
 	case *corev1.Secret:
-		kind, namespace, name = configKindCM, o.Namespace, o.Name
+		kind, namespace, name = configKindSecret, o.Namespace, o.Name
 		stringData, binaryData = o.StringData, o.Data
 	}
 
@@ -32,7 +32,7 @@ func getTriggerMetaAndData(
 
 func (c *Controller) OnConfigResourceAdded(obj interface{}) *reconcile.Result {
 	kind, ns, name, stringData, binaryData := getTriggerMetaAndData(obj)
-	logger := c.logger.WithFields(
+	logger := c.getLogger().WithFields(
 		log.String("kind", string(kind)),
 		log.String("namespace", ns),
 		log.String("name", name),
@@ -45,7 +45,8 @@ func (c *Controller) OnConfigResourceAdded(obj interface{}) *reconcile.Result {
 	// 		 we should consider add annotation to these config maps
 	c.updateTriggerSourceHashes(buildTriggerSourceHash(kind, ns, name, stringData, binaryData))
 
-	if o, ok := obj.(metav1.ObjectMetaAccessor); ok && isConfigRequireSynced(o) {
+	if o, ok := obj.(metav1.ObjectMetaAccessor); ok &&
+		(isConfigRequireSynced(o) || isConfigRequireMirrored(o) || isConfigRequirePublished(o)) {
 		return &reconcile.Result{NextAction: queue.ActionUpdate}
 	}
 
@@ -54,7 +55,7 @@ func (c *Controller) OnConfigResourceAdded(obj interface{}) *reconcile.Result {
 
 func (c *Controller) OnConfigResourceUpdated(oldObj, newObj interface{}) *reconcile.Result {
 	kind, ns, name, stringData, binaryData := getTriggerMetaAndData(newObj)
-	logger := c.logger.WithFields(
+	logger := c.getLogger().WithFields(
 		log.String("kind", string(kind)),
 		log.String("namespace", ns),
 		log.String("name", name),
@@ -65,15 +66,32 @@ func (c *Controller) OnConfigResourceUpdated(oldObj, newObj interface{}) *reconc
 	c.notifyUpdate(logger, buildTriggerSourceHash(kind, ns, name, stringData, binaryData))
 
 	var (
+		ref     = createConfigRef(kind, ns, name, "")
 		created bool
 		err     error
 	)
 
 	newMeta, ok := newObj.(metav1.ObjectMetaAccessor)
+	if ok {
+		if err = c.handleMirrorUpdate(logger, ref, newMeta); err != nil {
+			return &reconcile.Result{Err: err}
+		}
+
+		if err = c.handlePublish(logger, ref, newMeta, stringData, binaryData); err != nil {
+			logger.I("failed to publish config", log.Error(err))
+			return &reconcile.Result{Err: err}
+		}
+	}
+
+	if err = c.handlePublishConfigUpdate(logger, ref); err != nil {
+		logger.I("failed to update publishers", log.Error(err))
+		return &reconcile.Result{Err: err}
+	}
+
 	if ok && isConfigRequireSynced(newMeta) {
 		// this config needs to be synced with remote source
 		logger.D("ensuring syncer for this config")
-		created, err = c.ensureSyncer(createConfigRef(kind, ns, name, ""), newMeta, nil)
+		created, err = c.ensureSyncer(ref, newMeta, nil)
 		if err != nil {
 			logger.I(err.Error())
 			return &reconcile.Result{Err: err}
@@ -94,7 +112,7 @@ func (c *Controller) OnConfigResourceUpdated(oldObj, newObj interface{}) *reconc
 			if newAnno[constant.AnnotationSyncConfig] != oldAnno[constant.AnnotationSyncConfig] {
 				// syncer config ref changed, need to remove old syncer
 				logger.D("removing old syncer due to config ref changed")
-				err := c.removeSyncer(old, nil)
+				_, err := c.removeSyncer(&ref, old, nil)
 				if err != nil {
 					logger.I("failed to remove syncer", log.Error(err))
 					return &reconcile.Result{Err: err}
@@ -108,7 +126,7 @@ func (c *Controller) OnConfigResourceUpdated(oldObj, newObj interface{}) *reconc
 
 func (c *Controller) OnConfigResourceDeleting(obj interface{}) *reconcile.Result {
 	kind, ns, name, stringData, binaryData := getTriggerMetaAndData(obj)
-	logger := c.logger.WithFields(
+	logger := c.getLogger().WithFields(
 		log.String("type",
 			string(kind)),
 		log.String("namespace", ns),
@@ -119,9 +137,29 @@ func (c *Controller) OnConfigResourceDeleting(obj interface{}) *reconcile.Result
 
 	c.removeTriggerSourceHashes(buildTriggerSourceHash(kind, ns, name, stringData, binaryData))
 
+	ref := createConfigRef(kind, ns, name, "")
+	c.removePublisher(ref)
+	c.forgetSyncedData(ref)
+
+	if err := c.removeMirrors(ref); err != nil {
+		logger.I("failed to remove mirrors", log.Error(err))
+		return &reconcile.Result{Err: err}
+	}
+
+	if o, ok := obj.(metav1.ObjectMetaAccessor); ok {
+		if srcNS, srcName, isCopy := getMirrorSource(o); isCopy {
+			// recreate if still selected
+			err := c.ensureMirrorsOf(createConfigRef(kind, srcNS, srcName, ""))
+			if err != nil {
+				logger.I("failed to ensure mirrors", log.Error(err))
+				return &reconcile.Result{Err: err}
+			}
+		}
+	}
+
 	if o, ok := obj.(metav1.ObjectMetaAccessor); ok && isConfigRequireSynced(o) {
 		logger.D("removing config syncer if any")
-		err := c.removeSyncer(o, nil)
+		_, err := c.removeSyncer(&ref, o, nil)
 		if err != nil {
 			logger.I("failed to remove syncer", log.Error(err))
 			return &reconcile.Result{Err: err}
@@ -133,3 +171,25 @@ func (c *Controller) OnConfigResourceDeleting(obj interface{}) *reconcile.Result
 
 	return nil
 }
+
+// handleMirrorUpdate ensures mirrors if the object is a mirror source (or removes mirrors if
+// not any more), or ensures mirrors of its source if the object is a mirrored copy
+func (c *Controller) handleMirrorUpdate(logger log.Interface, ref configRef, md metav1.ObjectMetaAccessor) error {
+	var err error
+	switch srcNS, srcName, isCopy := getMirrorSource(md); {
+	case isConfigRequireMirrored(md):
+		logger.D("ensuring mirrors for this config")
+		err = c.ensureMirrors(ref, md)
+	case isCopy:
+		// revert changes to the copy
+		err = c.ensureMirrorsOf(createConfigRef(ref.kind, srcNS, srcName, ""))
+	default:
+		err = c.removeMirrors(ref)
+	}
+
+	if err != nil {
+		logger.I("failed to ensure mirrors", log.Error(err))
+	}
+
+	return err
+}
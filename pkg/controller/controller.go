@@ -3,7 +3,9 @@ package controller
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"arhat.dev/pkg/backoff"
@@ -23,90 +25,182 @@ import (
 	informerscorev1 "k8s.io/client-go/informers/core/v1"
 	kubeclient "k8s.io/client-go/kubernetes"
 	kubecache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 
 	"arhat.dev/ksync/pkg/conf"
 	"arhat.dev/ksync/pkg/constant"
 )
 
-func NewController(appCtx context.Context, config *conf.KsyncConfig) (*Controller, error) {
-	var (
-		kubeClient, _, err = config.Ksync.KubeClient.NewKubeClient(nil, true)
-		namespace          = corev1.NamespaceAll
-		fieldSelector      = fields.Everything().String()
-		ctrlCtx, exitCtrl  = context.WithCancel(appCtx)
-	)
-	_ = exitCtrl
-
+func NewController(
+	appCtx context.Context,
+	config *conf.KsyncConfig,
+	recorder record.EventRecorder,
+) (*Controller, error) {
+	kubeClient, _, err := config.Ksync.KubeClient.NewKubeClient(nil, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create kube client for controller: %w", err)
 	}
 
+	ctrlCtx, exitCtrl := context.WithCancel(appCtx)
+
+	ctrl := &Controller{
+		ctx:  ctrlCtx,
+		exit: exitCtrl,
+
+		kubeClient: kubeClient,
+		recorder:   recorder,
+
+		reloadDelay: int64(config.Ksync.ReloadDelay),
+		configCh:    make(chan *conf.KsyncConfig, 1),
+
+		reloadTriggerIndex:      make(map[configRef]map[reloadObjectKey]struct{}),
+		reloadTriggerSourceHash: make(map[configRef]string),
+		mu:                      new(sync.RWMutex),
+
+		syncerTriggerIndex: make(map[configRef]*syncerSpec),
+		syncerMu:           new(sync.RWMutex),
+
+		mirrorSources: make(map[configRef]struct{}),
+		mirrorMu:      new(sync.RWMutex),
+
+		publishers:   make(map[configRef]*publisherSpec),
+		syncedHashes: make(map[configRef]string),
+		publishMu:    new(sync.RWMutex),
+
+		pendingReloads:       make(map[reloadObjectKey]*reloadSpec),
+		pendingSyncerUpdates: make(map[configRef]*syncerSpec),
+		schedMu:              new(sync.RWMutex),
+	}
+
+	// schedulers are not recreated on reload to keep scheduled jobs
+	ctrl.reloadRec = reconcile.NewCore(ctrlCtx, &reconcile.Options{
+		Logger:          log.Log.WithName("sched:reload"),
+		RequireCache:    true,
+		BackoffStrategy: backoff.NewStrategy(time.Second, time.Minute, 2, 0),
+		Handlers: reconcile.HandleFuncs{
+			OnAdded: ctrl.handleWorkloadReload,
+		},
+	})
+
+	ctrl.syncRec = reconcile.NewCore(ctrlCtx, &reconcile.Options{
+		Logger:          log.Log.WithName("sched:sync"),
+		RequireCache:    true,
+		BackoffStrategy: backoff.NewStrategy(time.Second, time.Minute, 2, 0),
+		Handlers: reconcile.HandleFuncs{
+			OnAdded: ctrl.handleSyncerConfigUpdate,
+		},
+	})
+
+	ctrl.logger.Store(log.Log.WithName("controller"))
+	ctrl.syncPolicy.Store(&config.Ksync.Sync)
+	ctrl.mirrorPolicy.Store(&config.Ksync.Mirror)
+
+	scope, err := ctrl.resolveWatchScope(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrl.setupInformers(scope)
+	ctrl.registerObservers()
+
+	return ctrl, nil
+}
+
+// watchScope defines what objects are visible to the controller
+type watchScope struct {
+	namespace     string
+	fieldSelector string
+}
+
+func (c *Controller) resolveWatchScope(config *conf.KsyncConfig) (*watchScope, error) {
+	scope := &watchScope{
+		namespace:     corev1.NamespaceAll,
+		fieldSelector: fields.Everything().String(),
+	}
+
 	if config.Ksync.Namespaced {
-		namespace = envhelper.ThisPodNS()
-	} else {
-		var (
-			enabledNamespaces  []string
-			disabledNamespaces = config.Ksync.IgnoredNamespaces
-		)
-		err = func() error {
-			nsProbeCtx, cancelProbe := context.WithTimeout(appCtx, 10*time.Second)
-			defer cancelProbe()
-
-			disableReq, err2 := labels.NewRequirement(constant.LabelDisabled, selection.Exists, nil)
-			if err2 != nil {
-				return fmt.Errorf("failed to create ns disabled requirement: %w", err2)
-			}
+		scope.namespace = envhelper.ThisPodNS()
+		return scope, nil
+	}
 
-			// find disabled namespaces
-			nsList, err2 := kubeClient.CoreV1().Namespaces().List(nsProbeCtx, metav1.ListOptions{
-				LabelSelector: labels.NewSelector().Add(*disableReq).String(),
-			})
-			if err2 != nil {
-				if errors.IsForbidden(err2) {
-					return nil
-				}
-				return fmt.Errorf("failed to get disabled namespacs: %w", err2)
-			}
-			for _, ns := range nsList.Items {
-				disabledNamespaces = append(disabledNamespaces, ns.Name)
-			}
+	var (
+		enabledNamespaces  []string
+		disabledNamespaces = append([]string{}, config.Ksync.IgnoredNamespaces...)
+	)
+	err := func() error {
+		nsProbeCtx, cancelProbe := context.WithTimeout(c.ctx, 10*time.Second)
+		defer cancelProbe()
 
-			enableReq, err2 := labels.NewRequirement(constant.LabelEnabled, selection.Exists, nil)
-			if err2 != nil {
-				return fmt.Errorf("failed to create ns enabled requirement: %w", err2)
-			}
+		disableReq, err2 := labels.NewRequirement(constant.LabelDisabled, selection.Exists, nil)
+		if err2 != nil {
+			return fmt.Errorf("failed to create ns disabled requirement: %w", err2)
+		}
 
-			// find disabled namespaces
-			nsList, err2 = kubeClient.CoreV1().Namespaces().List(nsProbeCtx, metav1.ListOptions{
-				LabelSelector: labels.NewSelector().Add(*enableReq).String(),
-			})
-			if err2 != nil {
-				// we have checked permission before
-				return fmt.Errorf("failed to get enabled namespacs: %w", err2)
-			}
-			for _, ns := range nsList.Items {
-				enabledNamespaces = append(enabledNamespaces, ns.Name)
+		// find disabled namespaces
+		nsList, err2 := c.kubeClient.CoreV1().Namespaces().List(nsProbeCtx, metav1.ListOptions{
+			LabelSelector: labels.NewSelector().Add(*disableReq).String(),
+		})
+		if err2 != nil {
+			if errors.IsForbidden(err2) {
+				return nil
 			}
+			return fmt.Errorf("failed to get disabled namespacs: %w", err2)
+		}
+		for _, ns := range nsList.Items {
+			disabledNamespaces = append(disabledNamespaces, ns.Name)
+		}
 
-			return nil
-		}()
-		if err != nil {
-			return nil, fmt.Errorf("failed to determine namespaces to watch: %w", err)
+		enableReq, err2 := labels.NewRequirement(constant.LabelEnabled, selection.Exists, nil)
+		if err2 != nil {
+			return fmt.Errorf("failed to create ns enabled requirement: %w", err2)
 		}
 
-		// only use enabled namespaces if specified
-		if len(enabledNamespaces) > 0 {
-			// TODO: kubernetes field selector do not support logic 'or', we should implement ours
-		} else {
-			var selectors []fields.Selector
-			for _, ns := range disabledNamespaces {
-				selectors = append(selectors, fields.OneTermNotEqualSelector("metadata.namespace", ns))
-			}
-			fieldSelector = fields.AndSelectors(selectors...).String()
+		// find disabled namespaces
+		nsList, err2 = c.kubeClient.CoreV1().Namespaces().List(nsProbeCtx, metav1.ListOptions{
+			LabelSelector: labels.NewSelector().Add(*enableReq).String(),
+		})
+		if err2 != nil {
+			// we have checked permission before
+			return fmt.Errorf("failed to get enabled namespacs: %w", err2)
 		}
+		for _, ns := range nsList.Items {
+			enabledNamespaces = append(enabledNamespaces, ns.Name)
+		}
+
+		return nil
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine namespaces to watch: %w", err)
 	}
 
-	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+	// only use enabled namespaces if specified
+	if len(enabledNamespaces) > 0 {
+		// TODO: kubernetes field selector do not support logic 'or', we should implement ours
+	} else {
+		// sort to make field selector comparable
+		sort.Strings(disabledNamespaces)
+
+		var selectors []fields.Selector
+		for _, ns := range disabledNamespaces {
+			selectors = append(selectors, fields.OneTermNotEqualSelector("metadata.namespace", ns))
+		}
+		scope.fieldSelector = fields.AndSelectors(selectors...).String()
+	}
+
+	return scope, nil
+}
+
+// setupInformers creates informers and reconcilers for objects in scope, they are
+// running with their own context so we can rebuild them on config reload
+func (c *Controller) setupInformers(scope *watchScope) {
+	var (
+		namespace     = scope.namespace
+		fieldSelector = scope.fieldSelector
+	)
+
+	informerCtx, stopInformers := context.WithCancel(c.ctx)
+
+	informerFactory := informers.NewSharedInformerFactory(c.kubeClient, 0)
 
 	configResourceInformerFactory := informerscorev1.New(informerFactory, namespace, func(options *metav1.ListOptions) {
 		options.FieldSelector = fieldSelector
@@ -132,45 +226,39 @@ func NewController(appCtx context.Context, config *conf.KsyncConfig) (*Controlle
 	})
 	podInformer := podInformerFactory.Pods().Informer()
 
-	ctrl := &Controller{
-		ctx:  ctrlCtx,
-		exit: exitCtrl,
-
-		kubeClient: kubeClient,
-
-		logger: log.Log.WithName("controller"),
-
-		informerFactory: informerFactory,
-
-		informersSyncWait: []kubecache.InformerSynced{
-			cmInformer.HasSynced,
-			secretInformer.HasSynced,
-
-			deployInformer.HasSynced,
-			dsInformer.HasSynced,
-			stsInformer.HasSynced,
-			podInformer.HasSynced,
-		},
+	// namespaces are watched for mirroring when not namespaced
+	var nsInformer kubecache.SharedIndexInformer
+	if namespace == corev1.NamespaceAll {
+		nsInformer = informerFactory.Core().V1().Namespaces().Informer()
+	}
 
-		cmInformer:     cmInformer,
-		secretInformer: secretInformer,
+	c.scope = scope
+	c.informerCtx = informerCtx
+	c.stopInformers = stopInformers
+	c.informerFactory = informerFactory
 
-		dsInformer:     dsInformer,
-		deployInformer: deployInformer,
-		stsInformer:    stsInformer,
-		podInformer:    podInformer,
+	c.informersSyncWait = []kubecache.InformerSynced{
+		cmInformer.HasSynced,
+		secretInformer.HasSynced,
 
-		reloadDelay: config.Ksync.ReloadDelay,
+		deployInformer.HasSynced,
+		dsInformer.HasSynced,
+		stsInformer.HasSynced,
+		podInformer.HasSynced,
+	}
 
-		reloadTriggerIndex:      make(map[configRef]map[reloadObjectKey]struct{}),
-		reloadTriggerSourceHash: make(map[configRef]string),
-		mu:                      new(sync.RWMutex),
+	c.informers.Store(&informerSet{
+		cm:     cmInformer,
+		secret: secretInformer,
 
-		syncerTriggerIndex: make(map[configRef]*syncerSpec),
-		syncerMu:           new(sync.RWMutex),
-	}
+		ds:     dsInformer,
+		deploy: deployInformer,
+		sts:    stsInformer,
+		pod:    podInformer,
+		ns:     nsInformer,
+	})
 
-	ctrl.listActions = []func() error{
+	c.listActions = []func() error{
 		// config resources
 		func() error {
 			_, err := configResourceInformerFactory.ConfigMaps().Lister().List(labels.Everything())
@@ -203,117 +291,127 @@ func NewController(appCtx context.Context, config *conf.KsyncConfig) (*Controlle
 		return &reconcile.Result{NextAction: queue.ActionUpdate}
 	}
 
-	ctrl.cmRec = kubehelper.NewKubeInformerReconciler(ctrlCtx, cmInformer, reconcile.Options{
+	c.cmRec = kubehelper.NewKubeInformerReconciler(informerCtx, cmInformer, reconcile.Options{
 		Logger:       log.Log.WithName("conf:cm"),
 		RequireCache: true,
 		Handlers: reconcile.HandleFuncs{
-			OnAdded:    ctrl.OnConfigResourceAdded,
-			OnUpdated:  ctrl.OnConfigResourceUpdated,
-			OnDeleting: ctrl.OnConfigResourceDeleting,
-			OnDeleted:  ctrl.OnConfigResourceDeleting,
+			OnAdded:    c.OnConfigResourceAdded,
+			OnUpdated:  c.OnConfigResourceUpdated,
+			OnDeleting: c.OnConfigResourceDeleting,
+			OnDeleted:  c.OnConfigResourceDeleting,
 		},
 	})
 
-	ctrl.secretRec = kubehelper.NewKubeInformerReconciler(ctrlCtx, secretInformer, reconcile.Options{
+	c.secretRec = kubehelper.NewKubeInformerReconciler(informerCtx, secretInformer, reconcile.Options{
 		Logger:       log.Log.WithName("conf:secrets"),
 		RequireCache: true,
 		Handlers: reconcile.HandleFuncs{
-			OnAdded:    ctrl.OnConfigResourceAdded,
-			OnUpdated:  ctrl.OnConfigResourceUpdated,
-			OnDeleting: ctrl.OnConfigResourceDeleting,
-			OnDeleted:  ctrl.OnConfigResourceDeleting,
+			OnAdded:    c.OnConfigResourceAdded,
+			OnUpdated:  c.OnConfigResourceUpdated,
+			OnDeleting: c.OnConfigResourceDeleting,
+			OnDeleted:  c.OnConfigResourceDeleting,
 		},
 	})
 
-	ctrl.deployRec = kubehelper.NewKubeInformerReconciler(ctrlCtx, deployInformer, reconcile.Options{
+	c.deployRec = kubehelper.NewKubeInformerReconciler(informerCtx, deployInformer, reconcile.Options{
 		Logger:       log.Log.WithName("reload:deploy"),
 		RequireCache: true,
 		Handlers: reconcile.HandleFuncs{
 			OnAdded:    nextUpdate,
-			OnUpdated:  ctrl.OnReloadResourceUpdated,
-			OnDeleting: ctrl.OnReloadResourceDeleting,
-			OnDeleted:  ctrl.OnReloadResourceDeleting,
+			OnUpdated:  c.OnReloadResourceUpdated,
+			OnDeleting: c.OnReloadResourceDeleting,
+			OnDeleted:  c.OnReloadResourceDeleting,
 		},
 	})
 
-	ctrl.dsRec = kubehelper.NewKubeInformerReconciler(ctrlCtx, dsInformer, reconcile.Options{
+	c.dsRec = kubehelper.NewKubeInformerReconciler(informerCtx, dsInformer, reconcile.Options{
 		Logger:       log.Log.WithName("reload:ds"),
 		RequireCache: true,
 		Handlers: reconcile.HandleFuncs{
 			OnAdded:    nextUpdate,
-			OnUpdated:  ctrl.OnReloadResourceUpdated,
-			OnDeleting: ctrl.OnReloadResourceDeleting,
-			OnDeleted:  ctrl.OnReloadResourceDeleting,
+			OnUpdated:  c.OnReloadResourceUpdated,
+			OnDeleting: c.OnReloadResourceDeleting,
+			OnDeleted:  c.OnReloadResourceDeleting,
 		},
 	})
 
-	ctrl.stsRec = kubehelper.NewKubeInformerReconciler(ctrlCtx, stsInformer, reconcile.Options{
+	c.stsRec = kubehelper.NewKubeInformerReconciler(informerCtx, stsInformer, reconcile.Options{
 		Logger:       log.Log.WithName("reload:sts"),
 		RequireCache: true,
 		Handlers: reconcile.HandleFuncs{
 			OnAdded:    nextUpdate,
-			OnUpdated:  ctrl.OnReloadResourceUpdated,
-			OnDeleting: ctrl.OnReloadResourceDeleting,
-			OnDeleted:  ctrl.OnReloadResourceDeleting,
+			OnUpdated:  c.OnReloadResourceUpdated,
+			OnDeleting: c.OnReloadResourceDeleting,
+			OnDeleted:  c.OnReloadResourceDeleting,
 		},
 	})
 
-	ctrl.podRec = kubehelper.NewKubeInformerReconciler(ctrlCtx, podInformer, reconcile.Options{
+	c.podRec = kubehelper.NewKubeInformerReconciler(informerCtx, podInformer, reconcile.Options{
 		Logger:       log.Log.WithName("reload:pod"),
 		RequireCache: true,
 		Handlers: reconcile.HandleFuncs{
 			OnAdded:    nextUpdate,
-			OnUpdated:  ctrl.OnPodUpdated,
+			OnUpdated:  c.OnPodUpdated,
 			OnDeleting: nextUpdate,
 			OnDeleted:  nextUpdate,
 		},
 	})
 
-	ctrl.reloadRec = reconcile.NewCore(ctrlCtx, &reconcile.Options{
-		Logger:          log.Log.WithName("sched:reload"),
-		RequireCache:    true,
-		BackoffStrategy: backoff.NewStrategy(time.Second, time.Minute, 2, 0),
-		Handlers: reconcile.HandleFuncs{
-			OnAdded: ctrl.handleWorkloadReload,
-		},
-	})
+	c.reconcilesStart = []func() error{
+		c.cmRec.Start,
+		c.secretRec.Start,
 
-	ctrl.syncRec = reconcile.NewCore(ctrlCtx, &reconcile.Options{
-		Logger:          log.Log.WithName("sched:sync"),
-		RequireCache:    true,
-		BackoffStrategy: backoff.NewStrategy(time.Second, time.Minute, 2, 0),
-		Handlers: reconcile.HandleFuncs{
-			OnAdded: ctrl.handleSyncerConfigUpdate,
-		},
-	})
+		c.deployRec.Start,
+		c.dsRec.Start,
+		c.stsRec.Start,
+		c.podRec.Start,
+	}
 
-	ctrl.reconcilesStart = []func() error{
-		ctrl.cmRec.Start,
-		ctrl.secretRec.Start,
+	c.reconcileUntil = []func(<-chan struct{}){
+		c.cmRec.ReconcileUntil,
+		c.secretRec.ReconcileUntil,
 
-		ctrl.deployRec.Start,
-		ctrl.dsRec.Start,
-		ctrl.stsRec.Start,
-		ctrl.podRec.Start,
+		c.deployRec.ReconcileUntil,
+		c.dsRec.ReconcileUntil,
+		c.stsRec.ReconcileUntil,
+		c.podRec.ReconcileUntil,
 
-		ctrl.reloadRec.Start,
-		ctrl.syncRec.Start,
+		c.reloadRec.ReconcileUntil,
+		c.syncRec.ReconcileUntil,
 	}
 
-	ctrl.reconcileUntil = []func(<-chan struct{}){
-		ctrl.cmRec.ReconcileUntil,
-		ctrl.secretRec.ReconcileUntil,
-
-		ctrl.deployRec.ReconcileUntil,
-		ctrl.dsRec.ReconcileUntil,
-		ctrl.stsRec.ReconcileUntil,
-		ctrl.podRec.ReconcileUntil,
+	if nsInformer != nil {
+		c.nsRec = kubehelper.NewKubeInformerReconciler(informerCtx, nsInformer, reconcile.Options{
+			Logger:       log.Log.WithName("mirror:ns"),
+			RequireCache: true,
+			Handlers: reconcile.HandleFuncs{
+				OnAdded:   c.OnNamespaceAdded,
+				OnUpdated: c.OnNamespaceUpdated,
+			},
+		})
 
-		ctrl.reloadRec.ReconcileUntil,
-		ctrl.syncRec.ReconcileUntil,
+		c.informersSyncWait = append(c.informersSyncWait, nsInformer.HasSynced)
+		c.listActions = append(c.listActions, func() error {
+			_, err := informerFactory.Core().V1().Namespaces().Lister().List(labels.Everything())
+			return err
+		})
+		c.reconcilesStart = append(c.reconcilesStart, c.nsRec.Start)
+		c.reconcileUntil = append(c.reconcileUntil, c.nsRec.ReconcileUntil)
 	}
+}
 
-	return ctrl, nil
+// informerSet is a snapshot of informers created by setupInformers
+type informerSet struct {
+	cm     kubecache.SharedIndexInformer
+	secret kubecache.SharedIndexInformer
+
+	ds     kubecache.SharedIndexInformer
+	deploy kubecache.SharedIndexInformer
+	sts    kubecache.SharedIndexInformer
+	pod    kubecache.SharedIndexInformer
+
+	// ns is nil when namespaced
+	ns kubecache.SharedIndexInformer
 }
 
 type Controller struct {
@@ -321,9 +419,20 @@ type Controller struct {
 	exit context.CancelFunc
 
 	kubeClient kubeclient.Interface
+	recorder   record.EventRecorder
+
+	// logger is a log.Interface, replaced on config reload while used by schedulers
+	logger atomic.Value
 
-	logger          log.Interface
+	// config updates to be applied
+	configCh chan *conf.KsyncConfig
+
+	// informers and reconcilers, recreated on config reload
+	scope           *watchScope
+	informerCtx     context.Context
+	stopInformers   context.CancelFunc
 	informerFactory informers.SharedInformerFactory
+	reconcilesWG    sync.WaitGroup
 
 	informersSyncWait []kubecache.InformerSynced
 	listActions       []func() error
@@ -337,18 +446,23 @@ type Controller struct {
 	dsRec     *kubehelper.KubeInformerReconciler
 	stsRec    *kubehelper.KubeInformerReconciler
 	podRec    *kubehelper.KubeInformerReconciler
+	nsRec     *kubehelper.KubeInformerReconciler
+
+	// informers is an *informerSet, replaced on config reload while read by syncers, mirrors,
+	// publishers and schedulers
+	informers atomic.Value
 
-	dsInformer     kubecache.SharedIndexInformer
-	deployInformer kubecache.SharedIndexInformer
-	stsInformer    kubecache.SharedIndexInformer
-	podInformer    kubecache.SharedIndexInformer
+	// reloadDelay is a time.Duration, accessed atomically since it can be reloaded
+	reloadDelay int64
 
-	cmInformer     kubecache.SharedIndexInformer
-	secretInformer kubecache.SharedIndexInformer
+	// syncPolicy is a *conf.SyncConfig, can be reloaded
+	syncPolicy atomic.Value
 
-	reloadDelay time.Duration
-	reloadRec   *reconcile.Core
-	syncRec     *reconcile.Core
+	// mirrorPolicy is a *conf.MirrorConfig, can be reloaded
+	mirrorPolicy atomic.Value
+
+	reloadRec *reconcile.Core
+	syncRec   *reconcile.Core
 
 	// reload related
 	reloadTriggerIndex      map[configRef]map[reloadObjectKey]struct{}
@@ -357,10 +471,90 @@ type Controller struct {
 
 	syncerTriggerIndex map[configRef]*syncerSpec
 	syncerMu           *sync.RWMutex
+
+	// sources of mirrored configs
+	mirrorSources map[configRef]struct{}
+	mirrorMu      *sync.RWMutex
+
+	// publishers of configs and hashes of data keys written by syncers (for loop protection)
+	publishers   map[configRef]*publisherSpec
+	syncedHashes map[configRef]string
+	publishMu    *sync.RWMutex
+
+	// jobs scheduled but not finished
+	pendingReloads       map[reloadObjectKey]*reloadSpec
+	pendingSyncerUpdates map[configRef]*syncerSpec
+	schedMu              *sync.RWMutex
 }
 
 func (c *Controller) Start() error {
-	c.informerFactory.Start(c.ctx.Done())
+	for _, startScheduler := range []func() error{
+		c.reloadRec.Start,
+		c.syncRec.Start,
+	} {
+		if err := startScheduler(); err != nil {
+			return fmt.Errorf("failed to start scheduler: %w", err)
+		}
+	}
+
+	for {
+		if err := c.startInformers(); err != nil {
+			return err
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case config := <-c.configCh:
+			c.applyConfig(config)
+		}
+	}
+}
+
+// Reload the controller with new config, changes are applied asynchronously
+// and all triggers and syncers are recreated
+func (c *Controller) Reload(config *conf.KsyncConfig) {
+	// apply live changes immediately
+	atomic.StoreInt64(&c.reloadDelay, int64(config.Ksync.ReloadDelay))
+	c.syncPolicy.Store(&config.Ksync.Sync)
+	c.mirrorPolicy.Store(&config.Ksync.Mirror)
+
+	for {
+		select {
+		case c.configCh <- config:
+			return
+		default:
+			// discard outdated config
+			select {
+			case <-c.configCh:
+			default:
+			}
+		}
+	}
+}
+
+func (c *Controller) getLogger() log.Interface {
+	return c.logger.Load().(log.Interface)
+}
+
+func (c *Controller) getInformers() *informerSet {
+	return c.informers.Load().(*informerSet)
+}
+
+func (c *Controller) getSyncPolicy() *conf.SyncConfig {
+	return c.syncPolicy.Load().(*conf.SyncConfig)
+}
+
+func (c *Controller) getMirrorPolicy() *conf.MirrorConfig {
+	return c.mirrorPolicy.Load().(*conf.MirrorConfig)
+}
+
+func (c *Controller) getReloadDelay() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.reloadDelay))
+}
+
+func (c *Controller) startInformers() error {
+	c.informerFactory.Start(c.informerCtx.Done())
 
 	for _, startReconcile := range c.reconcilesStart {
 		if err := startReconcile(); err != nil {
@@ -374,15 +568,53 @@ func (c *Controller) Start() error {
 		}
 	}
 
-	if !kubecache.WaitForCacheSync(c.ctx.Done(), c.informersSyncWait...) {
+	if !kubecache.WaitForCacheSync(c.informerCtx.Done(), c.informersSyncWait...) {
 		return fmt.Errorf("informer cache not synced")
 	}
 
 	for _, reconcileUntil := range c.reconcileUntil {
-		go reconcileUntil(c.ctx.Done())
-	}
+		c.reconcilesWG.Add(1)
+		go func(reconcileUntil func(<-chan struct{})) {
+			defer c.reconcilesWG.Done()
 
-	<-c.ctx.Done()
+			reconcileUntil(c.informerCtx.Done())
+		}(reconcileUntil)
+	}
 
 	return nil
 }
+
+func (c *Controller) applyConfig(config *conf.KsyncConfig) {
+	scope, err := c.resolveWatchScope(config)
+	if err != nil {
+		c.getLogger().I("failed to resolve watch scope, keep using the old one", log.Error(err))
+		scope = c.scope
+	}
+
+	c.getLogger().I("stopping informers and reconcilers for full resync")
+	c.stopInformers()
+	c.reconcilesWG.Wait()
+
+	// stop syncers and publishers before informers replaced, new ones are created with
+	// new informers
+	c.removeAllSyncers()
+	c.removeAllPublishers()
+
+	// default logger may have been reloaded
+	c.logger.Store(log.Log.WithName("controller"))
+
+	func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.reloadTriggerIndex = make(map[configRef]map[reloadObjectKey]struct{})
+		if *scope != *c.scope {
+			// objects out of scope will never be updated
+			c.reloadTriggerSourceHash = make(map[configRef]string)
+		}
+	}()
+
+	// informers are always recreated, so all existing objects will be added
+	// again and all triggers and syncers are recreated
+	c.setupInformers(scope)
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"arhat.dev/ksync/pkg/conf"
+)
+
+func TestReload(t *testing.T) {
+	c := newTestController(t, &conf.SyncConfig{})
+	c.configCh = make(chan *conf.KsyncConfig, 1)
+
+	newConfig := func(delay time.Duration, allowed ...string) *conf.KsyncConfig {
+		config := new(conf.KsyncConfig)
+		config.Ksync.ReloadDelay = delay
+		config.Ksync.Sync.AllowedTargetNamespaces = allowed
+		config.Ksync.Mirror.AllowedSourceNamespaces = allowed
+		return config
+	}
+
+	outdated := newConfig(time.Second, "foo")
+	c.Reload(outdated)
+
+	latest := newConfig(2*time.Second, "bar")
+	c.Reload(latest)
+
+	if d := c.getReloadDelay(); d != 2*time.Second {
+		t.Errorf("reload delay not applied: %v", d)
+	}
+
+	if !c.getSyncPolicy().TargetNamespaceAllowed("default", "bar") ||
+		c.getSyncPolicy().TargetNamespaceAllowed("default", "foo") {
+		t.Errorf("sync policy not applied: %v", c.getSyncPolicy())
+	}
+
+	if !c.getMirrorPolicy().SourceNamespaceAllowed("bar") {
+		t.Errorf("mirror policy not applied: %v", c.getMirrorPolicy())
+	}
+
+	select {
+	case config := <-c.configCh:
+		if config != latest {
+			t.Errorf("outdated config not discarded")
+		}
+	default:
+		t.Fatal("config not queued for full resync")
+	}
+}
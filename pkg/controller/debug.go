@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"arhat.dev/ksync/pkg/syncer"
+)
+
+type (
+	debugState struct {
+		// Triggers maps config to workloads reloaded on its change
+		Triggers map[string][]string `json:"triggers"`
+
+		// Hashes of configs triggering reload
+		Hashes map[string]string `json:"hashes"`
+
+		PendingReloads       []debugPendingReload `json:"pendingReloads"`
+		PendingSyncerUpdates []debugSyncer        `json:"pendingSyncerUpdates"`
+
+		Syncers []debugSyncer `json:"syncers"`
+	}
+
+	debugPendingReload struct {
+		Workload   string    `json:"workload"`
+		Triggers   []string  `json:"triggers"`
+		NotifiedAt time.Time `json:"notifiedAt"`
+	}
+
+	debugSyncer struct {
+		// Targets are objects referencing the syncer config
+		Targets []string       `json:"targets"`
+		Config  string         `json:"config"`
+		Status  *syncer.Status `json:"status,omitempty"`
+	}
+)
+
+// DebugHandler serves controller state in json, filtered by the `namespace` query parameter
+// if provided
+func (c *Controller) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		state := c.collectDebugState(r.URL.Query().Get("namespace"))
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(state)
+	})
+}
+
+func (c *Controller) collectDebugState(namespace string) *debugState {
+	state := &debugState{
+		Triggers:             make(map[string][]string),
+		Hashes:               make(map[string]string),
+		PendingReloads:       []debugPendingReload{},
+		PendingSyncerUpdates: []debugSyncer{},
+		Syncers:              []debugSyncer{},
+	}
+
+	match := func(ns string) bool {
+		return namespace == "" || namespace == ns
+	}
+
+	// syncerTargets returns objects referencing the syncer matching namespace, syncerMu must be held
+	syncerTargets := func(spec *syncerSpec) ([]string, bool) {
+		var (
+			targets []string
+			matched bool
+		)
+		for ref := range spec.refs {
+			targets = append(targets, ref.String())
+			matched = matched || match(ref.namespace)
+		}
+		sort.Strings(targets)
+
+		return targets, matched
+	}
+
+	func() {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+
+		for t, reloads := range c.reloadTriggerIndex {
+			var workloads []string
+			for r := range reloads {
+				if match(r.namespace) {
+					workloads = append(workloads, r.String())
+				}
+			}
+
+			if len(workloads) == 0 && !match(t.namespace) {
+				continue
+			}
+
+			sort.Strings(workloads)
+			state.Triggers[t.String()] = workloads
+		}
+
+		for t, hash := range c.reloadTriggerSourceHash {
+			if match(t.namespace) {
+				state.Hashes[t.String()] = hash
+			}
+		}
+	}()
+
+	var pendingSyncers []*syncerSpec
+	func() {
+		c.schedMu.RLock()
+		defer c.schedMu.RUnlock()
+
+		for key, spec := range c.pendingReloads {
+			if !match(key.namespace) {
+				continue
+			}
+
+			state.PendingReloads = append(state.PendingReloads, debugPendingReload{
+				Workload:   key.String(),
+				Triggers:   configRefStrings(spec.triggers),
+				NotifiedAt: spec.notifiedAt,
+			})
+		}
+
+		for _, spec := range c.pendingSyncerUpdates {
+			pendingSyncers = append(pendingSyncers, spec)
+		}
+	}()
+
+	// syncer status is collected after syncerMu released since syncers hold their own locks
+	// when calling back into the controller
+	var syncers []*syncer.Syncer
+	func() {
+		c.syncerMu.RLock()
+		defer c.syncerMu.RUnlock()
+
+		for _, spec := range pendingSyncers {
+			targets, matched := syncerTargets(spec)
+			if !matched {
+				continue
+			}
+
+			state.PendingSyncerUpdates = append(state.PendingSyncerUpdates, debugSyncer{
+				Targets: targets,
+				Config:  spec.syncerConfig.String(),
+			})
+		}
+
+		for t, spec := range c.syncerTriggerIndex {
+			targets, matched := syncerTargets(spec)
+			if !matched {
+				continue
+			}
+
+			state.Syncers = append(state.Syncers, debugSyncer{
+				Targets: targets,
+				Config:  t.String(),
+			})
+			syncers = append(syncers, spec.syncer)
+		}
+	}()
+
+	for i, s := range syncers {
+		if s != nil {
+			status := s.Status()
+			state.Syncers[i].Status = &status
+		}
+	}
+
+	sort.Slice(state.PendingReloads, func(i, j int) bool {
+		return state.PendingReloads[i].Workload < state.PendingReloads[j].Workload
+	})
+	sort.Slice(state.PendingSyncerUpdates, func(i, j int) bool {
+		return state.PendingSyncerUpdates[i].Config < state.PendingSyncerUpdates[j].Config
+	})
+	sort.Slice(state.Syncers, func(i, j int) bool {
+		return state.Syncers[i].Config < state.Syncers[j].Config
+	})
+
+	return state
+}
+
+func configRefStrings(refs map[configRef]struct{}) []string {
+	ret := make([]string, 0, len(refs))
+	for t := range refs {
+		ret = append(ret, t.String())
+	}
+	sort.Strings(ret)
+
+	return ret
+}
@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/reference"
+
+	"arhat.dev/ksync/pkg/fetcher"
+)
+
+// reasons of events emitted by controller
+const (
+	eventReasonReloaded            = "Reloaded"
+	eventReasonReloadFailed        = "ReloadFailed"
+	eventReasonSynced              = "Synced"
+	eventReasonSyncFailed          = "SyncFailed"
+	eventReasonDataRejected        = "DataRejected"
+	eventReasonDataStalled         = "DataStalled"
+	eventReasonMirrored            = "Mirrored"
+	eventReasonMirrorFailed        = "MirrorFailed"
+	eventReasonPublished           = "Published"
+	eventReasonPublishFailed       = "PublishFailed"
+	eventReasonFetcherDisconnected = "FetcherDisconnected"
+	eventReasonFetcherRecovered    = "FetcherRecovered"
+)
+
+// recordReloadEvent emits event on the reloaded workload with triggers in the message
+func (c *Controller) recordReloadEvent(obj runtime.Object, spec *reloadSpec, err error) {
+	if c.recorder == nil || obj == nil {
+		return
+	}
+
+	triggers := formatTriggers(spec.namespace, spec.triggers)
+	if err != nil {
+		c.recorder.Eventf(obj, corev1.EventTypeWarning, eventReasonReloadFailed,
+			"failed to reload for config change in %s: %v", triggers, err)
+		return
+	}
+
+	c.recorder.Eventf(obj, corev1.EventTypeNormal, eventReasonReloaded,
+		"reloaded for config change in %s", triggers)
+}
+
+// recordSyncEvent emits event on the sync target for applied (or failed) update
+func (c *Controller) recordSyncEvent(target, syncerConfig configRef, data map[string][]byte, err error) {
+	if c.recorder == nil {
+		return
+	}
+
+	var keys []string
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	source := formatConfigRef(target.namespace, syncerConfig)
+	if err != nil {
+		c.recorder.Eventf(c.configObjectReference(target), corev1.EventTypeWarning, eventReasonSyncFailed,
+			"failed to apply update of keys [%s] from syncer %s: %v", strings.Join(keys, ", "), source, err)
+		return
+	}
+
+	c.recorder.Eventf(c.configObjectReference(target), corev1.EventTypeNormal, eventReasonSynced,
+		"applied update of keys [%s] from syncer %s", strings.Join(keys, ", "), source)
+}
+
+// recordRejectionEvent emits event on the sync target for data rejected by validator
+func (c *Controller) recordRejectionEvent(target configRef, validatorMethod, key string, err error) {
+	if c.recorder == nil {
+		return
+	}
+
+	c.recorder.Eventf(c.configObjectReference(target), corev1.EventTypeWarning, eventReasonDataRejected,
+		"data for key %q rejected by %s validator: %v", key, validatorMethod, err)
+}
+
+// recordStallEvent emits event on the sync target for required data keys not synced in time
+func (c *Controller) recordStallEvent(target configRef, missing []string, action string) {
+	if c.recorder == nil {
+		return
+	}
+
+	c.recorder.Eventf(c.configObjectReference(target), corev1.EventTypeWarning, eventReasonDataStalled,
+		"required data keys [%s] not synced in time, action taken: %s", strings.Join(missing, ", "), action)
+}
+
+// recordFetcherHealthEvent emits event on the sync target for fetcher disconnected longer than
+// maxDisconnected (or recovered)
+func (c *Controller) recordFetcherHealthEvent(target, syncerConfig configRef, status fetcher.Status, healthy bool) {
+	if c.recorder == nil {
+		return
+	}
+
+	source := formatConfigRef(target.namespace, syncerConfig)
+	if healthy {
+		c.recorder.Eventf(c.configObjectReference(target), corev1.EventTypeNormal, eventReasonFetcherRecovered,
+			"%s fetcher of syncer %s recovered", status.Method, source)
+		return
+	}
+
+	c.recorder.Eventf(c.configObjectReference(target), corev1.EventTypeWarning, eventReasonFetcherDisconnected,
+		"%s fetcher of syncer %s disconnected for too long (reconnects: %d, last error: %s)",
+		status.Method, source, status.Reconnects, status.LastError)
+}
+
+// recordMirrorEvent emits event on the mirror source for mirrored (or failed) namespaces
+func (c *Controller) recordMirrorEvent(src configRef, namespaces string, err error) {
+	if c.recorder == nil {
+		return
+	}
+
+	if err != nil {
+		c.recorder.Eventf(c.configObjectReference(src), corev1.EventTypeWarning, eventReasonMirrorFailed,
+			"failed to mirror to namespace %s: %v", namespaces, err)
+		return
+	}
+
+	c.recorder.Eventf(c.configObjectReference(src), corev1.EventTypeNormal, eventReasonMirrored,
+		"mirrored to namespaces [%s]", namespaces)
+}
+
+// recordPublishEvent emits event on the published config for published (or failed) keys
+func (c *Controller) recordPublishEvent(ref, publishConfig configRef, keys []string, err error) {
+	if c.recorder == nil {
+		return
+	}
+
+	sort.Strings(keys)
+
+	dest := formatConfigRef(ref.namespace, publishConfig)
+	if err != nil {
+		c.recorder.Eventf(c.configObjectReference(ref), corev1.EventTypeWarning, eventReasonPublishFailed,
+			"failed to publish keys [%s] with publisher %s: %v", strings.Join(keys, ", "), dest, err)
+		return
+	}
+
+	c.recorder.Eventf(c.configObjectReference(ref), corev1.EventTypeNormal, eventReasonPublished,
+		"published keys [%s] with publisher %s", strings.Join(keys, ", "), dest)
+}
+
+// configObjectReference returns reference to the config object with uid, which is required
+// to list events in `kubectl describe`, the object is looked up in cache and then from the
+// api server since objects just created by syncers may not be in cache yet
+func (c *Controller) configObjectReference(ref configRef) *corev1.ObjectReference {
+	var (
+		obj runtime.Object
+		err error
+	)
+	switch ref.kind {
+	case configKindCM:
+		cm, found, _ := c.configGetter().getConfigMap(ref.namespace, ref.name)
+		if !found {
+			cm, err = c.kubeClient.CoreV1().ConfigMaps(ref.namespace).Get(c.ctx, ref.name, metav1.GetOptions{})
+		}
+		if err == nil && cm != nil {
+			obj = cm
+		}
+	case configKindSecret:
+		secret, found, _ := c.configGetter().getSecret(ref.namespace, ref.name)
+		if !found {
+			secret, err = c.kubeClient.CoreV1().Secrets(ref.namespace).Get(c.ctx, ref.name, metav1.GetOptions{})
+		}
+		if err == nil && secret != nil {
+			obj = secret
+		}
+	}
+
+	if obj != nil {
+		objRef, err2 := reference.GetReference(scheme.Scheme, obj)
+		if err2 == nil {
+			return objRef
+		}
+	}
+
+	// object is gone, event is still recorded but not shown by `kubectl describe`
+	kind := "ConfigMap"
+	if ref.kind == configKindSecret {
+		kind = "Secret"
+	}
+
+	return &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       kind,
+		Namespace:  ref.namespace,
+		Name:       ref.name,
+	}
+}
+
+// formatConfigRef formats config ref in human readable form, namespace is omitted
+// when it's the same as ns
+func formatConfigRef(ns string, ref configRef) string {
+	kind := "configmap"
+	if ref.kind == configKindSecret {
+		kind = "secret"
+	}
+
+	name := ref.name
+	if ref.namespace != ns {
+		name = ref.namespace + "/" + ref.name
+	}
+
+	if ref.key == "" {
+		return kind + " " + name
+	}
+
+	return fmt.Sprintf("%s %s (key %q)", kind, name, ref.key)
+}
+
+func formatTriggers(ns string, triggers map[configRef]struct{}) string {
+	var ret []string
+	for t := range triggers {
+		ret = append(ret, formatConfigRef(ns, t))
+	}
+	sort.Strings(ret)
+
+	return strings.Join(ret, ", ")
+}
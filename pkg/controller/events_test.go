@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"arhat.dev/ksync/pkg/conf"
+)
+
+func TestConfigObjectReference(t *testing.T) {
+	c := newTestController(t, &conf.SyncConfig{},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cached", UID: "cm-uid"}},
+	)
+
+	// created by syncer but not cached yet
+	_, err := c.kubeClient.CoreV1().Secrets("default").Create(c.ctx,
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "created", UID: "secret-uid"}},
+		metav1.CreateOptions{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		ref        configRef
+		expectKind string
+		expectUID  types.UID
+	}{
+		{name: "Cached", ref: createConfigRef(configKindCM, "default", "cached", ""), expectKind: "ConfigMap", expectUID: "cm-uid"},
+		{name: "Not Cached", ref: createConfigRef(configKindSecret, "default", "created", ""), expectKind: "Secret", expectUID: "secret-uid"},
+		{name: "Not Found", ref: createConfigRef(configKindSecret, "default", "missing", ""), expectKind: "Secret"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ref := c.configObjectReference(test.ref)
+			if ref.Kind != test.expectKind || ref.APIVersion != "v1" {
+				t.Errorf("unexpected type %s/%s", ref.APIVersion, ref.Kind)
+			}
+
+			if ref.Namespace != test.ref.namespace || ref.Name != test.ref.name {
+				t.Errorf("unexpected object %s/%s", ref.Namespace, ref.Name)
+			}
+
+			if ref.UID != test.expectUID {
+				t.Errorf("unexpected uid %q", ref.UID)
+			}
+		})
+	}
+}
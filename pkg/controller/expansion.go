@@ -6,14 +6,66 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	utilvalidation "k8s.io/apimachinery/pkg/util/validation"
+	kubecache "k8s.io/client-go/tools/cache"
 	podshelper "k8s.io/kubernetes/pkg/apis/core/pods"
 	"k8s.io/kubernetes/pkg/fieldpath"
 	kubecontainer "k8s.io/kubernetes/pkg/kubelet/container"
 	"k8s.io/kubernetes/third_party/forked/golang/expansion"
 )
 
+// configGetter gets configmaps and secrets for env expansion
+type configGetter interface {
+	// getConfigMap returns false if not found
+	getConfigMap(namespace, name string) (*v1.ConfigMap, bool, error)
+
+	// getSecret returns false if not found
+	getSecret(namespace, name string) (*v1.Secret, bool, error)
+}
+
+// informerConfigGetter gets configmaps and secrets from informer cache
+type informerConfigGetter struct {
+	cmIndexer     kubecache.Indexer
+	secretIndexer kubecache.Indexer
+}
+
+func (g *informerConfigGetter) getConfigMap(namespace, name string) (*v1.ConfigMap, bool, error) {
+	obj, found, err := g.cmIndexer.GetByKey(namespace + "/" + name)
+	if err != nil || !found {
+		return nil, found, err
+	}
+
+	cm, ok := obj.(*v1.ConfigMap)
+	if !ok {
+		return nil, false, fmt.Errorf("failed to convert to configmap type")
+	}
+
+	return cm, true, nil
+}
+
+func (g *informerConfigGetter) getSecret(namespace, name string) (*v1.Secret, bool, error) {
+	obj, found, err := g.secretIndexer.GetByKey(namespace + "/" + name)
+	if err != nil || !found {
+		return nil, found, err
+	}
+
+	secret, ok := obj.(*v1.Secret)
+	if !ok {
+		return nil, false, fmt.Errorf("failed to convert to secret type")
+	}
+
+	return secret, true, nil
+}
+
+func (c *Controller) configGetter() configGetter {
+	informers := c.getInformers()
+	return &informerConfigGetter{
+		cmIndexer:     informers.cm.GetIndexer(),
+		secretIndexer: informers.secret.GetIndexer(),
+	}
+}
+
 // nolint:gocyclo
-func (c *Controller) expandContainersEnvs(pod *v1.Pod, container *v1.Container) ([]kubecontainer.EnvVar, error) {
+func expandContainersEnvs(getter configGetter, pod *v1.Pod, container *v1.Container) ([]kubecontainer.EnvVar, error) {
 	var (
 		configMaps = make(map[string]*v1.ConfigMap)
 		secrets    = make(map[string]*v1.Secret)
@@ -33,21 +85,17 @@ func (c *Controller) expandContainersEnvs(pod *v1.Pod, container *v1.Container)
 			if !ok {
 				optional := cm.Optional != nil && *cm.Optional
 
-				var (
-					cmObj     interface{}
-					found, ok bool
-				)
-				cmObj, found, err = c.cmInformer.GetIndexer().GetByKey(pod.Namespace + "/" + name)
+				var found bool
+				configMap, found, err = getter.getConfigMap(pod.Namespace, name)
 				if err != nil {
 					return result, err
 				}
-				if !found && optional {
-					// ignore error when marked optional
-					continue
-				}
-				configMap, ok = cmObj.(*v1.ConfigMap)
-				if !ok {
-					return result, fmt.Errorf("failed to convert to configmap type")
+				if !found {
+					if optional {
+						// ignore error when marked optional
+						continue
+					}
+					return result, fmt.Errorf("couldn't find ConfigMap %v/%v", pod.Namespace, name)
 				}
 
 				configMaps[name] = configMap
@@ -69,21 +117,17 @@ func (c *Controller) expandContainersEnvs(pod *v1.Pod, container *v1.Container)
 			if !ok {
 				optional := s.Optional != nil && *s.Optional
 
-				var (
-					secretObj interface{}
-					found, ok bool
-				)
-				secretObj, found, err = c.secretInformer.GetIndexer().GetByKey(pod.Namespace + "/" + name)
+				var found bool
+				secret, found, err = getter.getSecret(pod.Namespace, name)
 				if err != nil {
 					return result, err
 				}
-				if !found && optional {
-					// ignore error when marked optional
-					continue
-				}
-				secret, ok = secretObj.(*v1.Secret)
-				if !ok {
-					return result, fmt.Errorf("failed to convert to secret type")
+				if !found {
+					if optional {
+						// ignore error when marked optional
+						continue
+					}
+					return result, fmt.Errorf("couldn't find Secret %v/%v", pod.Namespace, name)
 				}
 				secrets[name] = secret
 			}
@@ -146,21 +190,17 @@ func (c *Controller) expandContainersEnvs(pod *v1.Pod, container *v1.Container)
 				optional := cm.Optional != nil && *cm.Optional
 				configMap, ok := configMaps[name]
 				if !ok {
-					var (
-						cmObj interface{}
-						found bool
-					)
-					cmObj, found, err = c.cmInformer.GetIndexer().GetByKey(pod.Namespace + "/" + name)
+					var found bool
+					configMap, found, err = getter.getConfigMap(pod.Namespace, name)
 					if err != nil {
 						return result, err
 					}
-					if !found && optional {
-						// ignore error when marked optional
-						continue
-					}
-					configMap, ok = cmObj.(*v1.ConfigMap)
-					if !ok {
-						return result, fmt.Errorf("failed to convert to configmap type")
+					if !found {
+						if optional {
+							// ignore error when marked optional
+							continue
+						}
+						return result, fmt.Errorf("couldn't find ConfigMap %v/%v", pod.Namespace, name)
 					}
 					configMaps[name] = configMap
 				}
@@ -178,21 +218,17 @@ func (c *Controller) expandContainersEnvs(pod *v1.Pod, container *v1.Container)
 				optional := s.Optional != nil && *s.Optional
 				secret, ok := secrets[name]
 				if !ok {
-					var (
-						secretObj interface{}
-						found     bool
-					)
-					secretObj, found, err = c.secretInformer.GetIndexer().GetByKey(pod.Namespace + "/" + name)
+					var found bool
+					secret, found, err = getter.getSecret(pod.Namespace, name)
 					if err != nil {
 						return result, err
 					}
-					if !found && optional {
-						// ignore error when marked optional
-						continue
-					}
-					secret, ok = secretObj.(*v1.Secret)
-					if !ok {
-						return result, fmt.Errorf("failed to convert to secret type")
+					if !found {
+						if optional {
+							// ignore error when marked optional
+							continue
+						}
+						return result, fmt.Errorf("couldn't find Secret %v/%v", pod.Namespace, name)
 					}
 					secrets[name] = secret
 				}
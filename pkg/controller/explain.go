@@ -0,0 +1,578 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"arhat.dev/pkg/log"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"arhat.dev/ksync/pkg/constant"
+)
+
+type (
+	// Explanation of reload triggers of a workload
+	Explanation struct {
+		Kind      string `json:"kind"`
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+
+		// Triggers to reload the workload by patching its pod template, not set for pod
+		Triggers []ExplainedTrigger `json:"triggers,omitempty"`
+
+		// Pods of the workload, they are deleted when pod specific triggers fired
+		Pods []ExplainedPod `json:"pods,omitempty"`
+
+		// Notes about things can not be explained
+		Notes []string `json:"notes,omitempty"`
+	}
+
+	// ExplainedPod is the pod with pod specific triggers
+	ExplainedPod struct {
+		Name  string `json:"name"`
+		Owner string `json:"owner,omitempty"`
+
+		Triggers []ExplainedTrigger `json:"triggers,omitempty"`
+
+		// Error happened when resolving pod specific triggers
+		Error string `json:"error,omitempty"`
+	}
+
+	// ExplainedTrigger is a configmap/secret (key) can trigger reload
+	ExplainedTrigger struct {
+		// Kind is either configmap or secret
+		Kind      string `json:"kind"`
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+		Key       string `json:"key,omitempty"`
+
+		// Sources contributed this trigger, pod spec or annotations
+		Sources []string `json:"sources"`
+
+		// CurrentHash of the config data, empty if config not found
+		CurrentHash string `json:"currentHash,omitempty"`
+
+		// StampedHash is the hash stamped on the pod template, empty if not stamped
+		StampedHash string `json:"stampedHash,omitempty"`
+	}
+)
+
+// Explain resolves reload triggers of target in the form of `<kind>/<namespace>/<name>`
+// (kind is one of pod, deploy, ds, sts) as the controller does
+//
+// objects are used before looking up in the cluster, kubeClient can be nil to explain objects only
+func Explain(
+	ctx context.Context,
+	kubeClient kubeclient.Interface,
+	objects []runtime.Object,
+	target string,
+) (*Explanation, error) {
+	kind, namespace, name, err := parseExplainTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	src := newExplainSource(ctx, kubeClient, objects, namespace)
+	logger := log.Log.WithName("explain")
+
+	ret := &Explanation{
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+	}
+
+	obj, found, err := src.getObject(kind, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%s not found", target)
+	}
+
+	if pod, ok := obj.(*corev1.Pod); ok {
+		owner, ownerName, err2 := src.getPodOwner(pod)
+		if err2 != nil {
+			return nil, fmt.Errorf("failed to resolve pod owner: %w", err2)
+		}
+
+		if owner == nil {
+			ret.Notes = append(ret.Notes, "pod has no managed controller, will never be reloaded")
+			return ret, nil
+		}
+
+		ret.Pods = append(ret.Pods, explainPod(logger, src, owner, ownerName, pod))
+		return ret, nil
+	}
+
+	md, spec := getReloadResourceSpec(obj)
+	if md == nil {
+		return nil, fmt.Errorf("unsupported object %s", target)
+	}
+	md.Namespace = namespace
+
+	ret.Triggers = explainTriggers(src, createReloadTriggers(md, spec),
+		explainWorkloadTriggerSources(md, spec),
+		getPodTemplateAnnotations(obj),
+	)
+
+	var selector *metav1.LabelSelector
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		selector = o.Spec.Selector
+	case *appsv1.DaemonSet:
+		selector = o.Spec.Selector
+	case *appsv1.StatefulSet:
+		selector = o.Spec.Selector
+	}
+
+	pods, err := src.listPods(namespace, selector)
+	if err != nil {
+		ret.Notes = append(ret.Notes, fmt.Sprintf("failed to list pods: %v", err))
+	}
+
+	for _, pod := range pods {
+		ret.Pods = append(ret.Pods, explainPod(logger, src, md, kind+"/"+name, pod))
+	}
+
+	if len(ret.Triggers) == 0 && len(ret.Pods) == 0 {
+		ret.Notes = append(ret.Notes, "no reload trigger found")
+	}
+
+	return ret, nil
+}
+
+func parseExplainTarget(target string) (kind, namespace, name string, err error) {
+	parts := strings.Split(target, "/")
+	switch len(parts) {
+	case 2:
+		kind, namespace, name = parts[0], metav1.NamespaceDefault, parts[1]
+	case 3:
+		kind, namespace, name = parts[0], parts[1], parts[2]
+	default:
+		return "", "", "", fmt.Errorf("invalid target %q, expecting <kind>/<namespace>/<name>", target)
+	}
+
+	switch strings.ToLower(kind) {
+	case "pod", "pods", "po":
+		kind = "pod"
+	case "deploy", "deployment", "deployments":
+		kind = "deploy"
+	case "ds", "daemonset", "daemonsets":
+		kind = "ds"
+	case "sts", "statefulset", "statefulsets":
+		kind = "sts"
+	default:
+		return "", "", "", fmt.Errorf("unsupported kind %q, expecting one of pod, deploy, ds, sts", kind)
+	}
+
+	if name == "" || namespace == "" {
+		return "", "", "", fmt.Errorf("invalid target %q, empty namespace or name", target)
+	}
+
+	return kind, namespace, name, nil
+}
+
+func explainPod(
+	logger log.Interface,
+	src *explainSource,
+	owner metav1.Object,
+	ownerName string,
+	pod *corev1.Pod,
+) ExplainedPod {
+	ret := ExplainedPod{
+		Name:  pod.Name,
+		Owner: ownerName,
+	}
+
+	triggers, err := createPodSpecificTriggers(logger, src, owner, pod)
+	if err != nil {
+		ret.Error = err.Error()
+		return ret
+	}
+
+	// find out where these triggers come from by evaluating owner annotations separately
+	sources := make(map[configRef][]string)
+	ownerWithAnnotations := func(annotations map[string]string) metav1.Object {
+		return &metav1.ObjectMeta{
+			Name:        owner.GetName(),
+			Namespace:   owner.GetNamespace(),
+			Annotations: annotations,
+		}
+	}
+
+	specTriggers, _ := createPodSpecificTriggers(logger, src, ownerWithAnnotations(nil), pod)
+	for t := range specTriggers {
+		sources[t] = append(sources[t], "volume mount subPathExpr")
+	}
+
+	for _, k := range triggerAnnotations {
+		v, ok := owner.GetAnnotations()[k]
+		if !ok || !strings.Contains(v, "$") {
+			continue
+		}
+
+		annoTriggers, _ := createPodSpecificTriggers(logger, src, ownerWithAnnotations(map[string]string{k: v}), pod)
+		for t := range annoTriggers {
+			sources[t] = append(sources[t], "annotation "+k)
+		}
+	}
+
+	// pods are deleted on reload, no hash stamped
+	ret.Triggers = explainTriggers(src, triggers, sources, nil)
+
+	return ret
+}
+
+// triggerAnnotations are annotations can add reload triggers
+var triggerAnnotations = []string{
+	constant.AnnotationConfigMaps,
+	constant.AnnotationSecrets,
+	constant.AnnotationForceConfigMaps,
+	constant.AnnotationForceSecrets,
+}
+
+// explainWorkloadTriggerSources finds out where triggers come from by evaluating pod spec and
+// annotations separately
+func explainWorkloadTriggerSources(md *metav1.ObjectMeta, spec *corev1.PodSpec) map[configRef][]string {
+	sources := make(map[configRef][]string)
+
+	mdWithAnnotations := func(annotations map[string]string) *metav1.ObjectMeta {
+		return &metav1.ObjectMeta{
+			Name:        md.Name,
+			Namespace:   md.Namespace,
+			Annotations: annotations,
+		}
+	}
+
+	for t := range createReloadTriggers(mdWithAnnotations(nil), spec) {
+		sources[t] = append(sources[t], "pod spec volumes")
+	}
+
+	for _, k := range triggerAnnotations {
+		v, ok := md.Annotations[k]
+		if !ok || v == "" {
+			continue
+		}
+
+		for t := range createReloadTriggers(mdWithAnnotations(map[string]string{k: v}), &corev1.PodSpec{}) {
+			sources[t] = append(sources[t], "annotation "+k)
+		}
+	}
+
+	return sources
+}
+
+func explainTriggers(
+	src *explainSource,
+	triggers map[configRef]struct{},
+	sources map[configRef][]string,
+	stamped map[string]string,
+) []ExplainedTrigger {
+	var ret []ExplainedTrigger
+	for t := range triggers {
+		et := ExplainedTrigger{
+			Kind:        "configmap",
+			Namespace:   t.namespace,
+			Name:        t.name,
+			Key:         t.key,
+			Sources:     sources[t],
+			StampedHash: strings.TrimPrefix(stamped[triggerHashAnnotationKey(t)], "sha256:"),
+		}
+
+		if len(et.Sources) == 0 {
+			et.Sources = []string{"unknown"}
+		}
+
+		var (
+			stringData map[string]string
+			binaryData map[string][]byte
+			found      bool
+		)
+		switch t.kind {
+		case configKindCM:
+			var cm *corev1.ConfigMap
+			cm, found, _ = src.getConfigMap(t.namespace, t.name)
+			if found {
+				stringData, binaryData = cm.Data, cm.BinaryData
+			}
+		case configKindSecret:
+			et.Kind = "secret"
+
+			var secret *corev1.Secret
+			secret, found, _ = src.getSecret(t.namespace, t.name)
+			if found {
+				stringData, binaryData = secret.StringData, secret.Data
+			}
+		}
+
+		if found {
+			et.CurrentHash = buildTriggerSourceHash(t.kind, t.namespace, t.name, stringData, binaryData)[t]
+		}
+
+		ret = append(ret, et)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		a, b := ret[i], ret[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+
+		return a.Key < b.Key
+	})
+
+	return ret
+}
+
+func getPodTemplateAnnotations(obj runtime.Object) map[string]string {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return o.Spec.Template.Annotations
+	case *appsv1.DaemonSet:
+		return o.Spec.Template.Annotations
+	case *appsv1.StatefulSet:
+		return o.Spec.Template.Annotations
+	}
+
+	return nil
+}
+
+type explainObjectKey struct {
+	kind, namespace, name string
+}
+
+func newExplainSource(
+	ctx context.Context,
+	kubeClient kubeclient.Interface,
+	objects []runtime.Object,
+	defaultNamespace string,
+) *explainSource {
+	src := &explainSource{
+		ctx:        ctx,
+		kubeClient: kubeClient,
+		objects:    make(map[explainObjectKey]runtime.Object),
+	}
+
+	for _, obj := range objects {
+		md, ok := obj.(metav1.ObjectMetaAccessor)
+		if !ok {
+			continue
+		}
+
+		var kind string
+		switch o := obj.(type) {
+		case *corev1.ConfigMap:
+			kind = "cm"
+		case *corev1.Secret:
+			kind = "secret"
+		case *corev1.Pod:
+			kind = "pod"
+			defaultFieldRefAPIVersion(o)
+		case *appsv1.Deployment:
+			kind = "deploy"
+		case *appsv1.DaemonSet:
+			kind = "ds"
+		case *appsv1.StatefulSet:
+			kind = "sts"
+		case *appsv1.ReplicaSet:
+			kind = "rs"
+		default:
+			continue
+		}
+
+		// objects in manifests may not have namespace set
+		if md.GetObjectMeta().GetNamespace() == "" {
+			md.GetObjectMeta().SetNamespace(defaultNamespace)
+		}
+
+		src.objects[explainObjectKey{
+			kind:      kind,
+			namespace: md.GetObjectMeta().GetNamespace(),
+			name:      md.GetObjectMeta().GetName(),
+		}] = obj
+	}
+
+	return src
+}
+
+// explainSource gets objects from manifests and then from the cluster (if kubeClient provided)
+type explainSource struct {
+	ctx        context.Context
+	kubeClient kubeclient.Interface
+	objects    map[explainObjectKey]runtime.Object
+}
+
+// nolint:gocyclo
+func (s *explainSource) getObject(kind, namespace, name string) (runtime.Object, bool, error) {
+	if obj, ok := s.objects[explainObjectKey{kind: kind, namespace: namespace, name: name}]; ok {
+		return obj, true, nil
+	}
+
+	if s.kubeClient == nil {
+		return nil, false, nil
+	}
+
+	var (
+		obj runtime.Object
+		err error
+	)
+	switch kind {
+	case "cm":
+		obj, err = s.kubeClient.CoreV1().ConfigMaps(namespace).Get(s.ctx, name, metav1.GetOptions{})
+	case "secret":
+		obj, err = s.kubeClient.CoreV1().Secrets(namespace).Get(s.ctx, name, metav1.GetOptions{})
+	case "pod":
+		obj, err = s.kubeClient.CoreV1().Pods(namespace).Get(s.ctx, name, metav1.GetOptions{})
+	case "deploy":
+		obj, err = s.kubeClient.AppsV1().Deployments(namespace).Get(s.ctx, name, metav1.GetOptions{})
+	case "ds":
+		obj, err = s.kubeClient.AppsV1().DaemonSets(namespace).Get(s.ctx, name, metav1.GetOptions{})
+	case "sts":
+		obj, err = s.kubeClient.AppsV1().StatefulSets(namespace).Get(s.ctx, name, metav1.GetOptions{})
+	case "rs":
+		obj, err = s.kubeClient.AppsV1().ReplicaSets(namespace).Get(s.ctx, name, metav1.GetOptions{})
+	default:
+		return nil, false, fmt.Errorf("unsupported kind %q", kind)
+	}
+
+	if err != nil {
+		if kubeerrors.IsNotFound(err) {
+			return nil, false, nil
+		}
+
+		return nil, false, err
+	}
+
+	return obj, true, nil
+}
+
+func (s *explainSource) getConfigMap(namespace, name string) (*corev1.ConfigMap, bool, error) {
+	obj, found, err := s.getObject("cm", namespace, name)
+	if err != nil || !found {
+		return nil, found, err
+	}
+
+	return obj.(*corev1.ConfigMap), true, nil
+}
+
+func (s *explainSource) getSecret(namespace, name string) (*corev1.Secret, bool, error) {
+	obj, found, err := s.getObject("secret", namespace, name)
+	if err != nil || !found {
+		return nil, found, err
+	}
+
+	return obj.(*corev1.Secret), true, nil
+}
+
+func (s *explainSource) listPods(namespace string, labelSelector *metav1.LabelSelector) ([]*corev1.Pod, error) {
+	if labelSelector == nil {
+		return nil, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector: %w", err)
+	}
+
+	var pods []*corev1.Pod
+	for k, obj := range s.objects {
+		pod, ok := obj.(*corev1.Pod)
+		if k.kind != "pod" || k.namespace != namespace || !ok {
+			continue
+		}
+
+		if selector.Matches(labels.Set(pod.Labels)) {
+			pods = append(pods, pod)
+		}
+	}
+
+	if s.kubeClient != nil {
+		podList, err := s.kubeClient.CoreV1().Pods(namespace).List(s.ctx, metav1.ListOptions{
+			LabelSelector: selector.String(),
+		})
+		if err != nil {
+			return pods, err
+		}
+
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			if _, ok := s.objects[explainObjectKey{kind: "pod", namespace: namespace, name: pod.Name}]; ok {
+				continue
+			}
+
+			pods = append(pods, pod)
+		}
+	}
+
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+
+	return pods, nil
+}
+
+// getPodOwner resolves the workload owning the pod in the same way as the controller
+func (s *explainSource) getPodOwner(pod *corev1.Pod) (metav1.Object, string, error) {
+	ownerRef := metav1.GetControllerOf(pod)
+	if ownerRef == nil {
+		return nil, "", nil
+	}
+
+	var kind string
+	switch ownerRef.Kind {
+	case "DaemonSet":
+		kind = "ds"
+	case "StatefulSet":
+		kind = "sts"
+	case "ReplicaSet":
+		rs, found, err := s.getObject("rs", pod.Namespace, ownerRef.Name)
+		if err != nil || !found {
+			return nil, "", err
+		}
+
+		ownerRef = metav1.GetControllerOf(rs.(*appsv1.ReplicaSet))
+		if ownerRef == nil || ownerRef.Kind != "Deployment" {
+			return nil, "", nil
+		}
+
+		kind = "deploy"
+	default:
+		return nil, "", nil
+	}
+
+	obj, found, err := s.getObject(kind, pod.Namespace, ownerRef.Name)
+	if err != nil || !found {
+		return nil, "", err
+	}
+
+	md, _ := getReloadResourceSpec(obj)
+	return md, kind + "/" + ownerRef.Name, nil
+}
+
+// defaultFieldRefAPIVersion sets default api version of env field refs, which is done by the api
+// server for objects in the cluster
+func defaultFieldRefAPIVersion(pod *corev1.Pod) {
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for i := range containers {
+			for j := range containers[i].Env {
+				vf := containers[i].Env[j].ValueFrom
+				if vf != nil && vf.FieldRef != nil && vf.FieldRef.APIVersion == "" {
+					vf.FieldRef.APIVersion = "v1"
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"arhat.dev/pkg/log"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"arhat.dev/ksync/pkg/constant"
+	"arhat.dev/ksync/pkg/fetcher"
+)
+
+// syncStatus is written to sync targets as annotation when health of fetchers changed
+type syncStatus struct {
+	Healthy   bool             `json:"healthy"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+	Fetchers  []fetcher.Status `json:"fetchers"`
+}
+
+// handleFetcherHealthChanged emits event on the sync target and writes status of the syncer to
+// all its sync targets
+func (c *Controller) handleFetcherHealthChanged(
+	logger log.Interface,
+	spec *syncerSpec,
+	writeTarget configRef,
+	status fetcher.Status,
+	healthy bool,
+) {
+	c.recordFetcherHealthEvent(writeTarget, spec.syncerConfig, status, healthy)
+
+	s := spec.syncer.Status()
+	value, err := json.Marshal(&syncStatus{
+		Healthy:   s.Healthy,
+		UpdatedAt: time.Now().UTC(),
+		Fetchers:  s.Fetchers,
+	})
+	if err != nil {
+		logger.I("failed to marshal sync status", log.Error(err))
+		return
+	}
+
+	for target := range c.resolveSyncTargets(logger, spec) {
+		err = c.annotateSyncStatus(target, string(value))
+		if err != nil {
+			logger.I("failed to update sync status", log.String("target", target.String()), log.Error(err))
+		}
+	}
+}
+
+// annotateSyncStatus sets sync status annotation of the target, missing target is ignored
+func (c *Controller) annotateSyncStatus(target configRef, value string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				constant.AnnotationSyncStatus: value,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	switch target.kind {
+	case configKindCM:
+		_, err = c.kubeClient.CoreV1().ConfigMaps(target.namespace).
+			Patch(c.ctx, target.name, types.MergePatchType, patch, metav1.PatchOptions{})
+	case configKindSecret:
+		_, err = c.kubeClient.CoreV1().Secrets(target.namespace).
+			Patch(c.ctx, target.name, types.MergePatchType, patch, metav1.PatchOptions{})
+	default:
+		return nil
+	}
+
+	if err != nil && !kubeerrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// HealthHandler serves liveness of ksync, fails while any syncer with `failLiveness` enabled
+// has unhealthy fetchers
+func (c *Controller) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		unhealthy := c.collectUnhealthySyncers()
+		if len(unhealthy) == 0 {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("ok"))
+			return
+		}
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = fmt.Fprintf(w, "unhealthy syncers: %s", strings.Join(unhealthy, ", "))
+	})
+}
+
+// collectUnhealthySyncers returns configs of unhealthy syncers failing liveness
+func (c *Controller) collectUnhealthySyncers() []string {
+	c.syncerMu.RLock()
+	defer c.syncerMu.RUnlock()
+
+	var ret []string
+	for t, spec := range c.syncerTriggerIndex {
+		if spec.syncer == nil || !spec.config.Health.FailLiveness {
+			continue
+		}
+
+		if !spec.syncer.Healthy() {
+			ret = append(ret, t.String())
+		}
+	}
+	sort.Strings(ret)
+
+	return ret
+}
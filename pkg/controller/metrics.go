@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/metric"
+	"go.opentelemetry.io/otel/label"
+)
+
+var (
+	meter = metric.Must(global.Meter("arhat.dev/ksync/pkg/controller"))
+
+	reloadScheduledCounter = meter.NewInt64Counter("ksync_reload_scheduled_total",
+		metric.WithDescription("count of workload reloads scheduled"))
+	reloadSucceededCounter = meter.NewInt64Counter("ksync_reload_succeeded_total",
+		metric.WithDescription("count of workload reloads succeeded"))
+	reloadFailedCounter = meter.NewInt64Counter("ksync_reload_failed_total",
+		metric.WithDescription("count of workload reload attempts failed"))
+	reloadLatencyRecorder = meter.NewFloat64ValueRecorder("ksync_reload_latency_seconds",
+		metric.WithDescription("time elapsed from config change to workload reloaded"))
+
+	syncAppliedCounter = meter.NewInt64Counter("ksync_sync_applied_total",
+		metric.WithDescription("count of syncer updates applied to sync targets"))
+	syncFailedCounter = meter.NewInt64Counter("ksync_sync_failed_total",
+		metric.WithDescription("count of syncer updates failed to be applied to sync targets"))
+)
+
+func reloadKindLabel(kind reloadKind) label.KeyValue {
+	return label.String("kind", string(kind))
+}
+
+func configKindLabel(kind configKind) label.KeyValue {
+	return label.String("kind", string(kind))
+}
+
+// registerObservers registers metrics observing controller state, must be called only once
+func (c *Controller) registerObservers() {
+	meter.NewInt64ValueObserver("ksync_reload_triggers",
+		func(ctx context.Context, result metric.Int64ObserverResult) {
+			c.mu.RLock()
+			defer c.mu.RUnlock()
+
+			result.Observe(int64(len(c.reloadTriggerIndex)))
+		},
+		metric.WithDescription("count of configs can trigger workload reload"),
+	)
+
+	meter.NewInt64ValueObserver("ksync_syncers",
+		func(ctx context.Context, result metric.Int64ObserverResult) {
+			c.syncerMu.RLock()
+			defer c.syncerMu.RUnlock()
+
+			result.Observe(int64(len(c.syncerTriggerIndex)))
+		},
+		metric.WithDescription("count of running syncers"),
+	)
+
+	meter.NewInt64ValueObserver("ksync_scheduler_queue_depth",
+		func(ctx context.Context, result metric.Int64ObserverResult) {
+			c.schedMu.RLock()
+			defer c.schedMu.RUnlock()
+
+			result.Observe(int64(len(c.pendingReloads)), label.String("scheduler", "reload"))
+			result.Observe(int64(len(c.pendingSyncerUpdates)), label.String("scheduler", "sync"))
+		},
+		metric.WithDescription("count of scheduled jobs not finished"),
+	)
+}
+
+func (c *Controller) recordReloadSucceeded(spec *reloadSpec) {
+	reloadSucceededCounter.Add(c.ctx, 1, reloadKindLabel(spec.kind))
+
+	if !spec.notifiedAt.IsZero() {
+		reloadLatencyRecorder.Record(c.ctx, time.Since(spec.notifiedAt).Seconds(), reloadKindLabel(spec.kind))
+	}
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"testing"
+
+	"arhat.dev/pkg/log"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/metric/metrictest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"arhat.dev/ksync/pkg/conf"
+	"arhat.dev/ksync/pkg/syncer"
+)
+
+func TestSyncMetrics(t *testing.T) {
+	meterImpl, provider := metrictest.NewMeterProvider()
+	global.SetMeterProvider(provider)
+
+	annotated := createConfigRef(configKindCM, "default", "foo", "")
+	c := newTestController(t, &conf.SyncConfig{},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}},
+		// existing but not managed by ksync, sync must fail
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "bar"}},
+	)
+
+	config := &syncer.Config{
+		Targets: []*syncer.TargetConfig{nil, {Name: "bar"}},
+	}
+	s, err := syncer.NewSyncer(c.ctx, log.Log.WithName("test"), config, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec := &syncerSpec{
+		syncerConfig: createConfigRef(configKindCM, "default", "syncer", "config.yaml"),
+		syncer:       s,
+		config:       config,
+		refs:         map[configRef]struct{}{annotated: {}},
+	}
+	c.writeSyncTargets(c.getLogger(), spec, map[string][]byte{"a": []byte("b")})
+
+	values := make(map[string]int64)
+	for _, batch := range meterImpl.MeasurementBatches {
+		for _, m := range batch.Measurements {
+			name := m.Instrument.Descriptor().Name()
+			for _, kv := range batch.Labels {
+				if kv.Key == "kind" && kv.Value.AsString() != string(configKindCM) {
+					t.Errorf("unexpected kind label %v for %q", kv.Value.AsString(), name)
+				}
+			}
+
+			values[name] += m.Number.AsInt64()
+		}
+	}
+
+	if v := values["ksync_sync_applied_total"]; v != 1 {
+		t.Errorf("unexpected sync applied count %d", v)
+	}
+
+	if v := values["ksync_sync_failed_total"]; v != 1 {
+		t.Errorf("unexpected sync failed count %d", v)
+	}
+}
@@ -0,0 +1,461 @@
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"arhat.dev/pkg/log"
+	"arhat.dev/pkg/reconcile"
+	"go.uber.org/multierr"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"arhat.dev/ksync/pkg/constant"
+)
+
+// errMirrorUnmanaged is returned when an object not managed by ksync mirror exists in the
+// target namespace
+var errMirrorUnmanaged = fmt.Errorf("object exists and is not managed by ksync mirror")
+
+// OnNamespaceUpdated ensures mirrors of all mirror sources since namespaces selected may change
+func (c *Controller) OnNamespaceUpdated(oldObj, newObj interface{}) *reconcile.Result {
+	oldNS, ok1 := oldObj.(*corev1.Namespace)
+	newNS, ok2 := newObj.(*corev1.Namespace)
+	if ok1 && ok2 && reflect.DeepEqual(oldNS.Labels, newNS.Labels) && oldNS.Status.Phase == newNS.Status.Phase {
+		return nil
+	}
+
+	return c.OnNamespaceAdded(newObj)
+}
+
+// OnNamespaceAdded ensures mirrors of all mirror sources in the namespace
+func (c *Controller) OnNamespaceAdded(obj interface{}) *reconcile.Result {
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return nil
+	}
+
+	var err error
+	for _, src := range c.mirrorSourceRefs() {
+		err = multierr.Append(err, c.ensureMirrorIn(src, ns))
+	}
+
+	if err != nil {
+		c.getLogger().I("failed to ensure mirrors for namespace change", log.Error(err))
+		return &reconcile.Result{Err: err}
+	}
+
+	return nil
+}
+
+func (c *Controller) mirrorSourceRefs() []configRef {
+	c.mirrorMu.RLock()
+	defer c.mirrorMu.RUnlock()
+
+	refs := make([]configRef, 0, len(c.mirrorSources))
+	for ref := range c.mirrorSources {
+		refs = append(refs, ref)
+	}
+
+	return refs
+}
+
+// getMirrorSourceObject gets the mirror source from cache
+func (c *Controller) getMirrorSourceObject(src configRef) (metav1.ObjectMetaAccessor, bool, error) {
+	var (
+		obj   metav1.ObjectMetaAccessor
+		found bool
+		err   error
+	)
+
+	switch src.kind {
+	case configKindCM:
+		var cm *corev1.ConfigMap
+		cm, found, err = c.configGetter().getConfigMap(src.namespace, src.name)
+		obj = cm
+	case configKindSecret:
+		var secret *corev1.Secret
+		secret, found, err = c.configGetter().getSecret(src.namespace, src.name)
+		obj = secret
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get mirror source %s: %w", src.String(), err)
+	}
+
+	return obj, found, nil
+}
+
+// ensureMirrorsOf ensures mirrors of the source in cache, mirrors are removed if the source
+// is not found or not labeled for mirroring
+func (c *Controller) ensureMirrorsOf(src configRef) error {
+	obj, found, err := c.getMirrorSourceObject(src)
+	if err != nil {
+		return err
+	}
+
+	if !found || !isConfigRequireMirrored(obj) {
+		return c.removeMirrors(src)
+	}
+
+	return c.ensureMirrors(src, obj)
+}
+
+// ensureMirrorIn creates, updates or deletes copy of the source in the namespace
+func (c *Controller) ensureMirrorIn(src configRef, ns *corev1.Namespace) error {
+	obj, found, err := c.getMirrorSourceObject(src)
+	if err != nil {
+		return err
+	}
+
+	if !found || !isConfigRequireMirrored(obj) {
+		return c.removeMirrors(src)
+	}
+
+	selector, err := c.checkMirrorSource(src, obj)
+	if err != nil {
+		return err
+	}
+
+	if !c.isMirrorNamespaceSelected(src, ns, selector) {
+		return c.deleteMirrorIn(src, ns.Name)
+	}
+
+	changed, err := c.mirrorTo(src, obj, ns.Name)
+	switch {
+	case err != nil:
+		c.recordMirrorEvent(src, ns.Name, err)
+		if err != errMirrorUnmanaged {
+			return fmt.Errorf("failed to mirror to namespace %q: %w", ns.Name, err)
+		}
+	case changed:
+		c.recordMirrorEvent(src, ns.Name, nil)
+	}
+
+	return nil
+}
+
+// checkMirrorSource returns the namespace selector of the source, existing copies are deleted
+// if the source is not allowed to be mirrored
+func (c *Controller) checkMirrorSource(src configRef, obj metav1.ObjectMetaAccessor) (labels.Selector, error) {
+	if !c.getMirrorPolicy().SourceNamespaceAllowed(src.namespace) {
+		return nil, multierr.Append(
+			fmt.Errorf("mirroring from namespace %q is not allowed", src.namespace),
+			c.removeMirrors(src),
+		)
+	}
+
+	value := strings.TrimSpace(obj.GetObjectMeta().GetAnnotations()[constant.AnnotationMirrorNamespaces])
+	if value == "" {
+		return nil, multierr.Append(
+			fmt.Errorf("mirror namespace selector is empty"),
+			c.removeMirrors(src),
+		)
+	}
+
+	selector, err := labels.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mirror namespace selector: %w", err)
+	}
+
+	return selector, nil
+}
+
+// isMirrorNamespaceSelected checks whether the namespace should have a copy of the source
+func (c *Controller) isMirrorNamespaceSelected(src configRef, ns *corev1.Namespace, selector labels.Selector) bool {
+	switch {
+	case ns.Name == src.namespace, ns.Status.Phase == corev1.NamespaceTerminating:
+		return false
+	case !c.getMirrorPolicy().TargetNamespaceAllowed(ns.Name):
+		return false
+	default:
+		return selector.Matches(labels.Set(ns.Labels))
+	}
+}
+
+// ensureMirrors creates or updates copies of the source in selected namespaces, and removes
+// copies in namespaces not selected
+func (c *Controller) ensureMirrors(src configRef, obj metav1.ObjectMetaAccessor) error {
+	nsInformer := c.getInformers().ns
+	if nsInformer == nil {
+		return fmt.Errorf("mirroring is not supported in namespaced mode")
+	}
+
+	selector, err := c.checkMirrorSource(src, obj)
+	if err != nil {
+		return err
+	}
+
+	func() {
+		c.mirrorMu.Lock()
+		defer c.mirrorMu.Unlock()
+
+		c.mirrorSources[src] = struct{}{}
+	}()
+
+	var (
+		namespaces = make(map[string]struct{})
+		updated    []string
+	)
+	for _, item := range nsInformer.GetIndexer().List() {
+		ns, ok := item.(*corev1.Namespace)
+		if !ok || !c.isMirrorNamespaceSelected(src, ns, selector) {
+			continue
+		}
+
+		namespaces[ns.Name] = struct{}{}
+
+		changed, err2 := c.mirrorTo(src, obj, ns.Name)
+		if err2 != nil {
+			c.recordMirrorEvent(src, ns.Name, err2)
+			if err2 != errMirrorUnmanaged {
+				err = multierr.Append(err, fmt.Errorf("failed to mirror to namespace %q: %w", ns.Name, err2))
+			}
+			continue
+		}
+
+		if changed {
+			updated = append(updated, ns.Name)
+		}
+	}
+
+	if len(updated) != 0 {
+		sort.Strings(updated)
+		c.recordMirrorEvent(src, strings.Join(updated, ", "), nil)
+	}
+
+	return multierr.Append(err, c.deleteMirrors(src, namespaces))
+}
+
+// removeMirrors deletes all copies of the source
+func (c *Controller) removeMirrors(src configRef) error {
+	tracked := func() bool {
+		c.mirrorMu.Lock()
+		defer c.mirrorMu.Unlock()
+
+		_, ok := c.mirrorSources[src]
+		delete(c.mirrorSources, src)
+		return ok
+	}()
+
+	if !tracked {
+		return nil
+	}
+
+	return c.deleteMirrors(src, nil)
+}
+
+// deleteMirrors deletes copies of the source except those in namespaces to keep
+func (c *Controller) deleteMirrors(src configRef, keep map[string]struct{}) error {
+	opts := metav1.ListOptions{
+		LabelSelector: labels.FormatLabels(map[string]string{
+			constant.LabelManagedBy: constant.LabelManagedByValueMirror,
+		}),
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", src.name).String(),
+	}
+
+	var copies []metav1.Object
+	switch src.kind {
+	case configKindCM:
+		list, err := c.kubeClient.CoreV1().ConfigMaps(corev1.NamespaceAll).List(c.ctx, opts)
+		if err != nil {
+			return fmt.Errorf("failed to list mirrored configmaps: %w", err)
+		}
+
+		for i := range list.Items {
+			copies = append(copies, &list.Items[i])
+		}
+	case configKindSecret:
+		list, err := c.kubeClient.CoreV1().Secrets(corev1.NamespaceAll).List(c.ctx, opts)
+		if err != nil {
+			return fmt.Errorf("failed to list mirrored secrets: %w", err)
+		}
+
+		for i := range list.Items {
+			copies = append(copies, &list.Items[i])
+		}
+	}
+
+	source := src.namespace + "/" + src.name
+
+	var err error
+	for _, o := range copies {
+		if o.GetAnnotations()[constant.AnnotationMirrorSource] != source {
+			continue
+		}
+
+		if _, ok := keep[o.GetNamespace()]; ok {
+			continue
+		}
+
+		err = multierr.Append(err, c.deleteMirrorCopy(src, o.GetNamespace()))
+	}
+
+	return err
+}
+
+// deleteMirrorIn deletes copy of the source in the namespace if any
+func (c *Controller) deleteMirrorIn(src configRef, namespace string) error {
+	var (
+		obj   metav1.ObjectMetaAccessor
+		found bool
+		err   error
+	)
+
+	switch src.kind {
+	case configKindCM:
+		var cm *corev1.ConfigMap
+		cm, found, err = c.configGetter().getConfigMap(namespace, src.name)
+		obj = cm
+	case configKindSecret:
+		var secret *corev1.Secret
+		secret, found, err = c.configGetter().getSecret(namespace, src.name)
+		obj = secret
+	}
+
+	if err != nil || !found {
+		return err
+	}
+
+	if srcNS, srcName, ok := getMirrorSource(obj); !ok || srcNS != src.namespace || srcName != src.name {
+		return nil
+	}
+
+	return c.deleteMirrorCopy(src, namespace)
+}
+
+// deleteMirrorCopy deletes copy of the source in the namespace, missing copy is ignored
+func (c *Controller) deleteMirrorCopy(src configRef, namespace string) error {
+	var err error
+	switch src.kind {
+	case configKindCM:
+		err = c.kubeClient.CoreV1().ConfigMaps(namespace).Delete(c.ctx, src.name, metav1.DeleteOptions{})
+	case configKindSecret:
+		err = c.kubeClient.CoreV1().Secrets(namespace).Delete(c.ctx, src.name, metav1.DeleteOptions{})
+	}
+
+	if err != nil && !kubeerrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete mirror in namespace %q: %w", namespace, err)
+	}
+
+	return nil
+}
+
+// mirrorTo creates or updates copy of the source in namespace, returns true if changed
+func (c *Controller) mirrorTo(
+	src configRef,
+	obj metav1.ObjectMetaAccessor,
+	namespace string,
+) (bool, error) {
+	meta := metav1.ObjectMeta{
+		Namespace: namespace,
+		Name:      src.name,
+		Labels: map[string]string{
+			constant.LabelManagedBy: constant.LabelManagedByValueMirror,
+		},
+		Annotations: map[string]string{
+			constant.AnnotationMirrorSource: src.namespace + "/" + src.name,
+		},
+	}
+
+	isManaged := func(md metav1.ObjectMetaAccessor) bool {
+		ns, name, ok := getMirrorSource(md)
+		return ok && ns == src.namespace && name == src.name
+	}
+
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		current, found, err := c.configGetter().getConfigMap(namespace, src.name)
+		if err == nil && !found {
+			// may be out of the watch scope
+			current, err = c.kubeClient.CoreV1().ConfigMaps(namespace).Get(c.ctx, src.name, metav1.GetOptions{})
+			if kubeerrors.IsNotFound(err) {
+				_, err = c.kubeClient.CoreV1().ConfigMaps(namespace).Create(c.ctx, &corev1.ConfigMap{
+					ObjectMeta: meta,
+					Data:       o.Data,
+					BinaryData: o.BinaryData,
+				}, metav1.CreateOptions{})
+				return err == nil, err
+			}
+		}
+
+		switch {
+		case err != nil:
+			return false, err
+		case !isManaged(current):
+			return false, errMirrorUnmanaged
+		case equalStringMap(current.Data, o.Data) && equalBytesMap(current.BinaryData, o.BinaryData):
+			return false, nil
+		}
+
+		current = current.DeepCopy()
+		current.Data, current.BinaryData = o.Data, o.BinaryData
+		_, err = c.kubeClient.CoreV1().ConfigMaps(namespace).Update(c.ctx, current, metav1.UpdateOptions{})
+		return err == nil, err
+	case *corev1.Secret:
+		current, found, err := c.configGetter().getSecret(namespace, src.name)
+		if err == nil && !found {
+			// may be out of the watch scope
+			current, err = c.kubeClient.CoreV1().Secrets(namespace).Get(c.ctx, src.name, metav1.GetOptions{})
+			if kubeerrors.IsNotFound(err) {
+				_, err = c.kubeClient.CoreV1().Secrets(namespace).Create(c.ctx, &corev1.Secret{
+					ObjectMeta: meta,
+					Type:       o.Type,
+					Data:       o.Data,
+				}, metav1.CreateOptions{})
+				return err == nil, err
+			}
+		}
+
+		switch {
+		case err != nil:
+			return false, err
+		case !isManaged(current):
+			return false, errMirrorUnmanaged
+		case current.Type != o.Type:
+			return false, fmt.Errorf("secret type %q differs from source type %q", current.Type, o.Type)
+		case equalBytesMap(current.Data, o.Data):
+			return false, nil
+		}
+
+		current = current.DeepCopy()
+		current.Data = o.Data
+		_, err = c.kubeClient.CoreV1().Secrets(namespace).Update(c.ctx, current, metav1.UpdateOptions{})
+		return err == nil, err
+	default:
+		return false, fmt.Errorf("unsupported mirror source type %T", obj)
+	}
+}
+
+func equalStringMap(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalBytesMap(a, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || string(bv) != string(v) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"arhat.dev/ksync/pkg/conf"
+	"arhat.dev/ksync/pkg/constant"
+)
+
+func TestEnsureMirrors(t *testing.T) {
+	newNS := func(name string) *corev1.Namespace {
+		return &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"team": "foo"}},
+			Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+		}
+	}
+
+	src := createConfigRef(configKindCM, "default", "foo", "")
+	newSource := func(selector string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   src.namespace,
+				Name:        src.name,
+				Labels:      map[string]string{constant.LabelAction: constant.LabelActionValueMirror},
+				Annotations: map[string]string{constant.AnnotationMirrorNamespaces: selector},
+			},
+			Data: map[string]string{"a": "1"},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		policy    *conf.MirrorConfig
+		selector  string
+		expectErr bool
+		mirrored  []string
+	}{
+		{
+			name:      "Source Not Allowed",
+			policy:    &conf.MirrorConfig{},
+			selector:  "team=foo",
+			expectErr: true,
+		},
+		{
+			name:      "Empty Selector",
+			policy:    &conf.MirrorConfig{AllowedSourceNamespaces: []string{"*"}},
+			selector:  " ",
+			expectErr: true,
+		},
+		{
+			name:     "System Namespaces Excluded",
+			policy:   &conf.MirrorConfig{AllowedSourceNamespaces: []string{"default"}},
+			selector: "team=foo",
+			mirrored: []string{"a", "b"},
+		},
+		{
+			name: "Namespaces Excluded",
+			policy: &conf.MirrorConfig{
+				AllowedSourceNamespaces: []string{"default"},
+				ExcludedNamespaces:      []string{"b"},
+				IncludeSystemNamespaces: true,
+			},
+			selector: "team=foo",
+			mirrored: []string{"a", "kube-system"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := newSource(test.selector)
+			c := newTestController(t, &conf.SyncConfig{},
+				source, newNS("default"), newNS("a"), newNS("b"), newNS("kube-system"),
+			)
+			c.mirrorPolicy.Store(test.policy)
+
+			err := c.ensureMirrors(src, source)
+			if test.expectErr != (err != nil) {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			list, err := c.kubeClient.CoreV1().ConfigMaps(corev1.NamespaceAll).List(c.ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var mirrored []string
+			for i := range list.Items {
+				if _, _, ok := getMirrorSource(&list.Items[i]); ok {
+					mirrored = append(mirrored, list.Items[i].Namespace)
+				}
+			}
+			sort.Strings(mirrored)
+
+			if len(mirrored) != len(test.mirrored) {
+				t.Fatalf("unexpected mirrored namespaces %v", mirrored)
+			}
+
+			for i := range mirrored {
+				if mirrored[i] != test.mirrored[i] {
+					t.Errorf("unexpected mirrored namespaces %v", mirrored)
+				}
+			}
+		})
+	}
+}
+
+func TestEnsureMirrorIn(t *testing.T) {
+	src := createConfigRef(configKindCM, "default", "foo", "")
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   src.namespace,
+			Name:        src.name,
+			Labels:      map[string]string{constant.LabelAction: constant.LabelActionValueMirror},
+			Annotations: map[string]string{constant.AnnotationMirrorNamespaces: "team=foo"},
+		},
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "a", Labels: map[string]string{"team": "foo"}}}
+
+	c := newTestController(t, &conf.SyncConfig{}, source)
+	c.mirrorPolicy.Store(&conf.MirrorConfig{AllowedSourceNamespaces: []string{"default"}})
+
+	if err := c.ensureMirrorIn(src, ns); err != nil {
+		t.Fatal(err)
+	}
+
+	cm, err := c.kubeClient.CoreV1().ConfigMaps("a").Get(c.ctx, src.name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// namespace not selected any more
+	if err = c.getInformers().cm.GetIndexer().Add(cm); err != nil {
+		t.Fatal(err)
+	}
+	ns.Labels = nil
+
+	if err = c.ensureMirrorIn(src, ns); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.kubeClient.CoreV1().ConfigMaps("a").Get(c.ctx, src.name, metav1.GetOptions{})
+	if err == nil {
+		t.Error("mirror not deleted")
+	}
+}
@@ -0,0 +1,293 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"arhat.dev/pkg/hashhelper"
+	"arhat.dev/pkg/log"
+	"go.uber.org/multierr"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"arhat.dev/ksync/pkg/constant"
+	"arhat.dev/ksync/pkg/fetcher"
+)
+
+// handlePublish publishes data keys of the config changed since last publish, the publisher
+// is created if not found (current data is published), and removed if the config is not
+// labeled for publishing any more
+func (c *Controller) handlePublish(
+	logger log.Interface,
+	ref configRef,
+	md metav1.ObjectMetaAccessor,
+	stringData map[string]string,
+	binaryData map[string][]byte,
+) error {
+	if !isConfigRequirePublished(md) {
+		c.removePublisher(ref)
+		return nil
+	}
+
+	publishConfig, err := createConfigRefFromAnnotation(md, constant.AnnotationPublishConfig, "publish")
+	if err != nil {
+		return fmt.Errorf("failed to create ref for the publish config: %w", err)
+	}
+
+	configData, err := c.dataGetter().Get(c.ctx, configRefToDataRef(*publishConfig))
+	if err != nil {
+		return fmt.Errorf("failed to get publish config: %w", err)
+	}
+
+	hashes := buildTriggerSourceHash(ref.kind, ref.namespace, ref.name, stringData, binaryData)
+
+	configHash := hashhelper.Sha256SumHex(configData)
+	spec, toPublish := c.checkPublish(logger, ref, *publishConfig, configHash, hashes)
+	if spec == nil {
+		logger.D("creating publisher for this config")
+		err = c.createPublisher(logger, ref, *publishConfig, configData)
+		if err != nil {
+			return err
+		}
+
+		// nothing published by the new publisher, all data keys except those written by
+		// syncers are published
+		spec, toPublish = c.checkPublish(logger, ref, *publishConfig, configHash, hashes)
+		if spec == nil {
+			return nil
+		}
+	}
+
+	if len(toPublish) == 0 {
+		logger.V("no data to publish")
+		return nil
+	}
+
+	data := make(map[string][]byte, len(toPublish))
+	keys := make([]string, 0, len(toPublish))
+	for k := range toPublish {
+		keys = append(keys, k)
+		if d, ok := binaryData[k]; ok {
+			data[k] = d
+		}
+		if d, ok := stringData[k]; ok {
+			data[k] = []byte(d)
+		}
+	}
+
+	err = spec.publisher.Publish(data)
+	c.recordPublishEvent(ref, spec.publishConfig, keys, err)
+	if err != nil {
+		return fmt.Errorf("failed to publish data: %w", err)
+	}
+
+	func() {
+		c.publishMu.Lock()
+		defer c.publishMu.Unlock()
+
+		for k, h := range toPublish {
+			spec.hashes[k] = h
+		}
+	}()
+
+	logger.I("published", log.Strings("keys", keys))
+	return nil
+}
+
+// checkPublish returns the publisher of the config and data keys to be published with their
+// hashes, returns nil publisher if not found or the publish config has changed
+func (c *Controller) checkPublish(
+	logger log.Interface,
+	ref, publishConfig configRef,
+	configHash string,
+	hashes map[configRef]string,
+) (*publisherSpec, map[string]string) {
+	c.publishMu.Lock()
+	defer c.publishMu.Unlock()
+
+	spec, ok := c.publishers[ref]
+	if !ok {
+		return nil, nil
+	}
+
+	if spec.publishConfig != publishConfig || spec.configHash != configHash {
+		logger.D("removing old publisher due to publish config changed")
+		_ = spec.publisher.Stop()
+		delete(c.publishers, ref)
+		return nil, nil
+	}
+
+	toPublish := make(map[string]string)
+	for _, k := range spec.publisher.DataKeys() {
+		keyRef := createConfigRef(ref.kind, ref.namespace, ref.name, k)
+		h, ok := hashes[keyRef]
+		if !ok || spec.hashes[k] == h {
+			continue
+		}
+
+		if c.syncedHashes[keyRef] == h {
+			// the data was written by syncer, publishing it back may cause loops
+			logger.V("skipped publishing data written by syncer", log.String("key", k))
+			spec.hashes[k] = h
+			continue
+		}
+
+		toPublish[k] = h
+	}
+
+	return spec, toPublish
+}
+
+// createPublisher creates and starts publisher for the config
+func (c *Controller) createPublisher(
+	logger log.Interface,
+	ref, publishConfig configRef,
+	configData []byte,
+) error {
+	config := new(fetcher.PublisherConfig)
+	err := yaml.Unmarshal(configData, config)
+	if err != nil {
+		err1 := json.Unmarshal(configData, config)
+		if err1 != nil {
+			return fmt.Errorf("failed to unmarshal publisher config: %w", multierr.Combine(err, err1))
+		}
+	}
+
+	p, err := fetcher.NewPublisher(c.ctx, c.getLogger().WithName("publisher").
+		WithFields(log.String("for", ref.String()), log.String("config", publishConfig.String())), config)
+	if err != nil {
+		return fmt.Errorf("failed to create publisher: %w", err)
+	}
+
+	err = p.Start(c.ctx.Done())
+	if err != nil {
+		_ = p.Stop()
+		return fmt.Errorf("failed to start publisher: %w", err)
+	}
+
+	spec := &publisherSpec{
+		publishConfig: publishConfig,
+		publisher:     p,
+		configHash:    hashhelper.Sha256SumHex(configData),
+		hashes:        make(map[string]string),
+	}
+
+	func() {
+		c.publishMu.Lock()
+		defer c.publishMu.Unlock()
+
+		if old, ok := c.publishers[ref]; ok {
+			_ = old.publisher.Stop()
+		}
+
+		c.publishers[ref] = spec
+	}()
+
+	logger.I("publisher created")
+	return nil
+}
+
+// handlePublishConfigUpdate checks publishers using the updated config object, they are
+// recreated if their publish config changed
+func (c *Controller) handlePublishConfigUpdate(logger log.Interface, src configRef) error {
+	var refs []configRef
+	func() {
+		c.publishMu.RLock()
+		defer c.publishMu.RUnlock()
+
+		for ref, spec := range c.publishers {
+			cfg := spec.publishConfig
+			if cfg.kind == src.kind && cfg.namespace == src.namespace && cfg.name == src.name {
+				refs = append(refs, ref)
+			}
+		}
+	}()
+
+	var err error
+	for _, ref := range refs {
+		var (
+			md         metav1.ObjectMetaAccessor
+			stringData map[string]string
+			binaryData map[string][]byte
+			found      bool
+			err2       error
+		)
+
+		switch ref.kind {
+		case configKindCM:
+			var cm *corev1.ConfigMap
+			cm, found, err2 = c.configGetter().getConfigMap(ref.namespace, ref.name)
+			if found {
+				md, stringData, binaryData = cm, cm.Data, cm.BinaryData
+			}
+		case configKindSecret:
+			var secret *corev1.Secret
+			secret, found, err2 = c.configGetter().getSecret(ref.namespace, ref.name)
+			if found {
+				md, stringData, binaryData = secret, secret.StringData, secret.Data
+			}
+		}
+
+		switch {
+		case err2 != nil:
+			err = multierr.Append(err, err2)
+		case !found:
+			c.removePublisher(ref)
+		default:
+			publishLogger := logger.WithFields(log.String("publish", ref.String()))
+			err = multierr.Append(err, c.handlePublish(publishLogger, ref, md, stringData, binaryData))
+		}
+	}
+
+	return err
+}
+
+// removePublisher stops publisher of the config if any
+func (c *Controller) removePublisher(ref configRef) {
+	c.publishMu.Lock()
+	defer c.publishMu.Unlock()
+
+	if spec, ok := c.publishers[ref]; ok {
+		_ = spec.publisher.Stop()
+		delete(c.publishers, ref)
+	}
+}
+
+// forgetSyncedData removes hashes of data keys written by syncers to the config
+func (c *Controller) forgetSyncedData(ref configRef) {
+	c.publishMu.Lock()
+	defer c.publishMu.Unlock()
+
+	for k := range c.syncedHashes {
+		if k.kind == ref.kind && k.namespace == ref.namespace && k.name == ref.name {
+			delete(c.syncedHashes, k)
+		}
+	}
+}
+
+func (c *Controller) removeAllPublishers() {
+	c.publishMu.Lock()
+	defer c.publishMu.Unlock()
+
+	for ref, spec := range c.publishers {
+		_ = spec.publisher.Stop()
+		delete(c.publishers, ref)
+	}
+}
+
+// recordSyncedData records hashes of data keys written by syncer to the target, data is the
+// synced data and newData is the data written after merge
+func (c *Controller) recordSyncedData(target configRef, data, newData map[string][]byte) {
+	c.publishMu.Lock()
+	defer c.publishMu.Unlock()
+
+	for k := range data {
+		d, ok := newData[k]
+		if !ok {
+			continue
+		}
+
+		c.syncedHashes[createConfigRef(target.kind, target.namespace, target.name, k)] = hashhelper.Sha256SumHex(d)
+	}
+}
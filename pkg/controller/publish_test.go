@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"arhat.dev/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"arhat.dev/ksync/pkg/conf"
+	"arhat.dev/ksync/pkg/constant"
+	"arhat.dev/ksync/pkg/fetcher"
+)
+
+const testPublisherMethod = "test-recorder"
+
+// testPublisher records published data keys
+type testPublisher struct {
+	published [][]string
+	mu        sync.Mutex
+}
+
+func (p *testPublisher) Start(stop <-chan struct{}) error { return nil }
+func (p *testPublisher) DataKeys() []string               { return []string{"a", "b"} }
+func (p *testPublisher) Stop() error                      { return nil }
+
+func (p *testPublisher) Publish(data map[string][]byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var keys []string
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	p.published = append(p.published, keys)
+	return nil
+}
+
+// takePublished returns data keys published since last call
+func (p *testPublisher) takePublished() [][]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ret := p.published
+	p.published = nil
+	return ret
+}
+
+func TestHandlePublish(t *testing.T) {
+	pub := new(testPublisher)
+	fetcher.RegisterPublisher(testPublisherMethod,
+		func(context.Context, log.Interface, *fetcher.PublisherConfig) (fetcher.Publisher, error) {
+			return pub, nil
+		},
+	)
+
+	c := newTestController(t, &conf.SyncConfig{}, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pub"},
+		Data:       map[string]string{"config.yaml": "method: " + testPublisherMethod},
+	})
+
+	ref := createConfigRef(configKindCM, "default", "src", "")
+	src := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "src",
+			Labels:      map[string]string{constant.LabelPublish: constant.LabelPublishValueEnabled},
+			Annotations: map[string]string{constant.AnnotationPublishConfig: "configmap://pub/config.yaml"},
+		},
+	}
+
+	publish := func(data map[string]string) [][]string {
+		err := c.handlePublish(c.getLogger(), ref, src, data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return pub.takePublished()
+	}
+
+	check := func(name string, actual [][]string, expected ...string) {
+		if len(expected) == 0 {
+			if len(actual) != 0 {
+				t.Errorf("%s: expected nothing published, got %v", name, actual)
+			}
+			return
+		}
+
+		if len(actual) != 1 || len(actual[0]) != len(expected) {
+			t.Fatalf("%s: expected %v published, got %v", name, expected, actual)
+		}
+
+		for i, k := range expected {
+			if actual[0][i] != k {
+				t.Errorf("%s: expected %v published, got %v", name, expected, actual)
+			}
+		}
+	}
+
+	data := map[string]string{"a": "1", "b": "1", "c": "1"}
+	check("created", publish(data), "a", "b")
+	check("unchanged", publish(data))
+
+	data = map[string]string{"a": "2", "b": "1", "c": "2"}
+	check("changed", publish(data), "a")
+
+	// written by syncer, must not be published back
+	c.recordSyncedData(ref, map[string][]byte{"b": []byte("2")}, map[string][]byte{"a": []byte("2"), "b": []byte("2")})
+	data = map[string]string{"a": "2", "b": "2", "c": "2"}
+	check("synced", publish(data))
+
+	data = map[string]string{"a": "2", "b": "3", "c": "2"}
+	check("changed after synced", publish(data), "b")
+
+	// publisher removed when not labeled any more
+	src.Labels = nil
+	check("unlabeled", publish(data))
+	if _, ok := c.publishers[ref]; ok {
+		t.Errorf("publisher not removed")
+	}
+}
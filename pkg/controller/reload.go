@@ -14,6 +14,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	kubecontainer "k8s.io/kubernetes/pkg/kubelet/container"
 	"k8s.io/kubernetes/third_party/forked/golang/expansion"
@@ -21,14 +22,24 @@ import (
 	"arhat.dev/ksync/pkg/constant"
 )
 
-func (c *Controller) handleWorkloadReload(obj interface{}) *reconcile.Result {
-	logger := c.logger.WithFields(log.String("action", "reload"))
+func (c *Controller) handleWorkloadReload(obj interface{}) (result *reconcile.Result) {
+	logger := c.getLogger().WithFields(log.String("action", "reload"))
 	spec, ok := obj.(*reloadSpec)
 	if !ok {
 		logger.I("invalid reload job key, not a reloadSpec", log.String("keyType", reflect.TypeOf(obj).String()))
 		return nil
 	}
 
+	defer func() {
+		if result != nil && result.Err != nil {
+			// will retry
+			reloadFailedCounter.Add(c.ctx, 1, reloadKindLabel(spec.kind))
+			return
+		}
+
+		c.removePendingReload(spec)
+	}()
+
 	targetKey := spec.namespace + "/" + spec.name
 	logger = logger.WithFields(log.String("target", targetKey))
 
@@ -36,7 +47,7 @@ func (c *Controller) handleWorkloadReload(obj interface{}) *reconcile.Result {
 		podTemplate *corev1.PodTemplateSpec
 
 		// to generalize patch action
-		target                      interface{}
+		target                      runtime.Object
 		kubeResourceKind            interface{}
 		doPatch                     func(data []byte) error
 		getObjectWithNewPodTemplate func(*corev1.PodTemplateSpec) interface{}
@@ -45,7 +56,7 @@ func (c *Controller) handleWorkloadReload(obj interface{}) *reconcile.Result {
 	case reloadKindPod:
 		logger = logger.WithFields(log.String("kind", "pod"))
 
-		obj, ok, err := c.podInformer.GetIndexer().GetByKey(targetKey)
+		obj, ok, err := c.getInformers().pod.GetIndexer().GetByKey(targetKey)
 		if err != nil {
 			logger.I("failed to get pod from informer cache by key", log.Error(err))
 			return &reconcile.Result{Err: err}
@@ -65,14 +76,17 @@ func (c *Controller) handleWorkloadReload(obj interface{}) *reconcile.Result {
 			Delete(c.ctx, spec.name, metav1.DeleteOptions{GracePeriodSeconds: pod.Spec.TerminationGracePeriodSeconds})
 		if err != nil && !kubeerrors.IsNotFound(err) {
 			logger.I("failed to kill pod", log.Error(err))
+			c.recordReloadEvent(pod, spec, err)
 			return &reconcile.Result{Err: err}
 		}
 
+		c.recordReloadSucceeded(spec)
+		c.recordReloadEvent(pod, spec, nil)
 		return nil
 	case reloadKindDaemonSet:
 		logger = logger.WithFields(log.String("kind", "ds"))
 
-		obj, ok, err := c.dsInformer.GetIndexer().GetByKey(targetKey)
+		obj, ok, err := c.getInformers().ds.GetIndexer().GetByKey(targetKey)
 		if err != nil {
 			logger.I("failed to get daemonset from informer cache", log.Error(err))
 			return &reconcile.Result{Err: err}
@@ -104,7 +118,7 @@ func (c *Controller) handleWorkloadReload(obj interface{}) *reconcile.Result {
 	case reloadKindDeployment:
 		logger = logger.WithFields(log.String("kind", "deploy"))
 
-		obj, ok, err := c.deployInformer.GetIndexer().GetByKey(targetKey)
+		obj, ok, err := c.getInformers().deploy.GetIndexer().GetByKey(targetKey)
 		if err != nil {
 			logger.I("failed to get deployment from informer cache", log.Error(err))
 			return &reconcile.Result{Err: err}
@@ -136,7 +150,7 @@ func (c *Controller) handleWorkloadReload(obj interface{}) *reconcile.Result {
 	case reloadKindStatefulSet:
 		logger = logger.WithFields(log.String("kind", "sts"))
 
-		obj, ok, err := c.stsInformer.GetIndexer().GetByKey(targetKey)
+		obj, ok, err := c.getInformers().sts.GetIndexer().GetByKey(targetKey)
 		if err != nil {
 			logger.I("failed to get statefulset from informer cache", log.Error(err))
 			return &reconcile.Result{Err: err}
@@ -182,10 +196,7 @@ func (c *Controller) handleWorkloadReload(obj interface{}) *reconcile.Result {
 				continue
 			}
 
-			hashKey := constant.AnnotationHashPrefix + "/" + hashhelper.MD5SumHex([]byte(
-				filepath.Clean(filepath.Join(string(t.kind), t.namespace, t.name, t.key)),
-			))
-			hashes[hashKey] = fmt.Sprintf("sha256:%s", hash)
+			hashes[triggerHashAnnotationKey(t)] = fmt.Sprintf("sha256:%s", hash)
 		}
 	}()
 
@@ -205,12 +216,22 @@ func (c *Controller) handleWorkloadReload(obj interface{}) *reconcile.Result {
 			return nil
 		}
 
+		c.recordReloadEvent(target, spec, err)
 		return &reconcile.Result{Err: err}
 	}
 
+	c.recordReloadSucceeded(spec)
+	c.recordReloadEvent(target, spec, nil)
 	return nil
 }
 
+// triggerHashAnnotationKey is the pod template annotation key to store hash of the trigger
+func triggerHashAnnotationKey(t configRef) string {
+	return constant.AnnotationHashPrefix + "/" + hashhelper.MD5SumHex([]byte(
+		filepath.Clean(filepath.Join(string(t.kind), t.namespace, t.name, t.key)),
+	))
+}
+
 func (c *Controller) ensureReloadObject(
 	logger log.Interface,
 	key reloadObjectKey,
@@ -538,8 +559,9 @@ func createReloadTriggers(md *metav1.ObjectMeta, spec *corev1.PodSpec) map[confi
 }
 
 // nolint:gocyclo
-func (c *Controller) createPodSpecificTriggers(
+func createPodSpecificTriggers(
 	logger log.Interface,
+	getter configGetter,
 	podOwnerMetadata metav1.Object,
 	pod *corev1.Pod,
 ) (map[configRef]struct{}, error) {
@@ -609,7 +631,7 @@ loopAnnotations:
 		logger.V("resolving init container envs")
 		// loop all init containers just to get env vars
 		for i := range pod.Spec.InitContainers {
-			envs, err := c.expandContainersEnvs(pod, &pod.Spec.InitContainers[i])
+			envs, err := expandContainersEnvs(getter, pod, &pod.Spec.InitContainers[i])
 			if err != nil {
 				return nil, fmt.Errorf("failed to expand container envs: %w", err)
 			}
@@ -621,7 +643,7 @@ loopAnnotations:
 	if evalAnnotation || evalPod {
 		logger.V("resolving work container envs")
 		for i, ctr := range pod.Spec.Containers {
-			envs, err := c.expandContainersEnvs(pod, &pod.Spec.Containers[i])
+			envs, err := expandContainersEnvs(getter, pod, &pod.Spec.Containers[i])
 			if err != nil {
 				return nil, fmt.Errorf("failed to expand container envs: %w", err)
 			}
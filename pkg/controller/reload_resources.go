@@ -12,7 +12,7 @@ import (
 func (c *Controller) OnPodUpdated(oldObj, newObj interface{}) *reconcile.Result {
 	var (
 		pod    = newObj.(*corev1.Pod).DeepCopy()
-		logger = c.logger.WithFields(
+		logger = c.getLogger().WithFields(
 			log.String("name", pod.Name),
 			log.String("namespace", pod.Namespace),
 			log.String("type", "reload:pod"),
@@ -39,7 +39,7 @@ func (c *Controller) OnPodUpdated(oldObj, newObj interface{}) *reconcile.Result
 	)
 	switch ownerRef.Kind {
 	case "DaemonSet":
-		dsObj, found, err := c.dsInformer.GetIndexer().GetByKey(ownerKey)
+		dsObj, found, err := c.getInformers().ds.GetIndexer().GetByKey(ownerKey)
 		if err != nil {
 			logger.I("failed to get daemonset", log.String("ds", ownerKey), log.Error(err))
 			return &reconcile.Result{Err: err}
@@ -66,7 +66,7 @@ func (c *Controller) OnPodUpdated(oldObj, newObj interface{}) *reconcile.Result
 		ownerKey = pod.Namespace + "/" + ownerRef.Name
 
 		logger = logger.WithFields(log.String("ownerRef", "dp/"+ownerKey))
-		dpObj, found, err := c.deployInformer.GetIndexer().GetByKey(ownerKey)
+		dpObj, found, err := c.getInformers().deploy.GetIndexer().GetByKey(ownerKey)
 		if err != nil {
 			logger.I("failed to get deployment", log.String("dp", ownerKey), log.Error(err))
 			return &reconcile.Result{Err: err}
@@ -79,7 +79,7 @@ func (c *Controller) OnPodUpdated(oldObj, newObj interface{}) *reconcile.Result
 		owner, ok = dpObj.(metav1.ObjectMetaAccessor)
 	case "StatefulSet":
 		logger = logger.WithFields(log.String("ownerRef", "sts/"+ownerKey))
-		stsObj, found, err := c.stsInformer.GetIndexer().GetByKey(ownerKey)
+		stsObj, found, err := c.getInformers().sts.GetIndexer().GetByKey(ownerKey)
 		if err != nil {
 			logger.I("failed to get statefulset", log.String("sts", ownerKey), log.Error(err))
 			return &reconcile.Result{Err: err}
@@ -102,7 +102,7 @@ func (c *Controller) OnPodUpdated(oldObj, newObj interface{}) *reconcile.Result
 
 	logger.D("creating pod specific triggers")
 	// found the pod controller, get pod specific triggers for this pod
-	triggers, err := c.createPodSpecificTriggers(logger, owner.GetObjectMeta(), pod)
+	triggers, err := createPodSpecificTriggers(logger, c.configGetter(), owner.GetObjectMeta(), pod)
 	if err != nil {
 		logger.I("failed to create pod specific triggers", log.Error(err))
 		return &reconcile.Result{Err: err}
@@ -141,12 +141,12 @@ func getReloadResourceSpec(obj interface{}) (*metav1.ObjectMeta, *corev1.PodSpec
 }
 
 func (c *Controller) OnReloadResourceUpdated(oldObj, newObj interface{}) *reconcile.Result {
-	return c.ensureReloadObject(c.logger,
+	return c.ensureReloadObject(c.getLogger(),
 		createReloadKey(getReloadResourceMeta(newObj)),
 		createReloadTriggers(getReloadResourceSpec(newObj)),
 	)
 }
 
 func (c *Controller) OnReloadResourceDeleting(obj interface{}) *reconcile.Result {
-	return c.ensureReloadObject(c.logger, createReloadKey(getReloadResourceMeta(obj)), nil)
+	return c.ensureReloadObject(c.getLogger(), createReloadKey(getReloadResourceMeta(obj)), nil)
 }
@@ -1,12 +1,11 @@
 package controller
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/url"
-	"path/filepath"
 	"reflect"
-	"strings"
 
 	"arhat.dev/pkg/log"
 	"arhat.dev/pkg/reconcile"
@@ -15,19 +14,30 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeclient "k8s.io/client-go/kubernetes"
 
 	"arhat.dev/ksync/pkg/constant"
+	"arhat.dev/ksync/pkg/dataref"
+	"arhat.dev/ksync/pkg/fetcher"
 	"arhat.dev/ksync/pkg/syncer"
+	"arhat.dev/ksync/pkg/validator"
 )
 
-func (c *Controller) handleSyncerConfigUpdate(obj interface{}) *reconcile.Result {
-	logger := c.logger.WithFields(log.String("action", "syncer-update"))
+func (c *Controller) handleSyncerConfigUpdate(obj interface{}) (result *reconcile.Result) {
+	logger := c.getLogger().WithFields(log.String("action", "syncer-update"))
 	spec, ok := obj.(*syncerSpec)
 	if !ok {
 		logger.I("invalid key, not a syncerSpec", log.Any("keyType", reflect.TypeOf(obj).String()))
 		return nil
 	}
 
+	defer func() {
+		if result == nil || result.Err == nil {
+			c.removePendingSyncerUpdate(spec.syncerConfig, spec)
+		}
+	}()
+
 	// stop it
 	if spec.syncer != nil {
 		logger.V("stopping old syncer")
@@ -41,33 +51,42 @@ func (c *Controller) handleSyncerConfigUpdate(obj interface{}) *reconcile.Result
 
 	// remove it (should not fail)
 	logger.V("removing old syncer")
-	err := c.removeSyncer(nil, &spec.syncerConfig)
+	refs, err := c.removeSyncer(nil, nil, &spec.syncerConfig)
 	if err != nil {
 		logger.I("failed to remove syncer for update", log.Error(err))
 		return &reconcile.Result{Err: err}
 	}
 
-	// create it
+	// create it for all objects referencing it
 	logger.V("ensuring syncer")
-	_, err = c.ensureSyncer(spec.targetConfig, nil, &spec.syncerConfig)
-	if err != nil {
-		logger.I("failed to ensure new syncer", log.Error(err))
-		return &reconcile.Result{Err: err}
+	for _, ref := range refs {
+		_, err = c.ensureSyncer(ref, nil, &spec.syncerConfig)
+		if err != nil {
+			logger.I("failed to ensure new syncer", log.Error(err))
+			result = &reconcile.Result{Err: err}
+		}
 	}
 
-	return nil
+	return result
 }
 
-func (c *Controller) removeSyncer(md metav1.ObjectMetaAccessor, trigger *configRef) error {
+// removeSyncer removes syncTarget from objects referencing the syncer, and stops the syncer
+// if no object references it, the syncer is removed anyway if syncTarget is nil,
+// returns objects referencing the syncer if it's removed
+func (c *Controller) removeSyncer(
+	syncTarget *configRef,
+	md metav1.ObjectMetaAccessor,
+	trigger *configRef,
+) ([]configRef, error) {
 	if md == nil && trigger == nil {
-		return fmt.Errorf("unable to create key for syncer, none of metadata and trigger provided")
+		return nil, fmt.Errorf("unable to create key for syncer, none of metadata and trigger provided")
 	}
 
 	if trigger == nil {
 		var err error
 		trigger, err = createTriggerForSyncerConfigResourceFromMetadata(md)
 		if err != nil {
-			return fmt.Errorf("failed to create trigger for the syncer config: %w", err)
+			return nil, fmt.Errorf("failed to create trigger for the syncer config: %w", err)
 		}
 	}
 
@@ -76,7 +95,15 @@ func (c *Controller) removeSyncer(md metav1.ObjectMetaAccessor, trigger *configR
 
 	spec, ok := c.syncerTriggerIndex[*trigger]
 	if !ok {
-		return nil
+		return nil, nil
+	}
+
+	if syncTarget != nil {
+		delete(spec.refs, *syncTarget)
+		if len(spec.refs) != 0 {
+			// still referenced by other objects
+			return nil, nil
+		}
 	}
 
 	if spec.syncer != nil {
@@ -85,58 +112,61 @@ func (c *Controller) removeSyncer(md metav1.ObjectMetaAccessor, trigger *configR
 
 	delete(c.syncerTriggerIndex, *trigger)
 
-	return nil
+	refs := make([]configRef, 0, len(spec.refs))
+	for ref := range spec.refs {
+		refs = append(refs, ref)
+	}
+
+	return refs, nil
+}
+
+func (c *Controller) removeAllSyncers() {
+	c.syncerMu.Lock()
+	defer c.syncerMu.Unlock()
+
+	for t, spec := range c.syncerTriggerIndex {
+		if spec.syncer != nil {
+			_ = spec.syncer.Stop()
+		}
+
+		delete(c.syncerTriggerIndex, t)
+	}
 }
 
 func createTriggerForSyncerConfigResourceFromMetadata(md metav1.ObjectMetaAccessor) (*configRef, error) {
+	return createConfigRefFromAnnotation(md, constant.AnnotationSyncConfig, "sync")
+}
+
+// createConfigRefFromAnnotation parses the config link in annotation, what is the kind of the
+// config used in error messages
+func createConfigRefFromAnnotation(md metav1.ObjectMetaAccessor, annotation, what string) (*configRef, error) {
 	annotations := md.GetObjectMeta().GetAnnotations()
 
 	if len(annotations) == 0 {
 		return nil, fmt.Errorf("no annotation found")
 	}
 
-	link, ok := annotations[constant.AnnotationSyncConfig]
+	link, ok := annotations[annotation]
 	if !ok || link == "" {
-		return nil, fmt.Errorf("no sync config annotation found")
+		return nil, fmt.Errorf("no %s config annotation found", what)
 	}
 
-	u, err := url.Parse(link)
+	// <name>/<key> -> namespace is the namespace we found this config
+	ref, err := dataref.Parse(link, md.GetObjectMeta().GetNamespace())
 	if err != nil {
-		return nil, fmt.Errorf("invalid sync config link: %w", err)
-	}
-
-	trigger := new(configRef)
-
-	target := filepath.Clean(filepath.Join(u.Host, u.Path))
-	parts := strings.SplitN(target, "/", 3)
-
-	switch len(parts) {
-	case 2:
-		// <name>/<key> -> namespace is the namespace we found this config
-		trigger.namespace = md.GetObjectMeta().GetNamespace()
-		trigger.name = parts[0]
-		trigger.key = parts[1]
-	case 3:
-		// <namespace>/<name>/<key>
-		trigger.namespace = parts[0]
-		trigger.name = parts[1]
-		trigger.key = parts[2]
-	default:
-		return nil, fmt.Errorf("invalid sync config reference %q", target)
+		return nil, fmt.Errorf("invalid %s config link: %w", what, err)
 	}
 
-	switch u.Scheme {
-	case "configmap":
-		trigger.kind = configKindCM
-	case "secret":
+	trigger := createConfigRef(configKindCM, ref.Namespace, ref.Name, ref.Key)
+	if ref.Kind == dataref.KindSecret {
 		trigger.kind = configKindSecret
-	default:
-		return nil, fmt.Errorf("unsupported sync config link scheme %q", u.Scheme)
 	}
 
-	return trigger, nil
+	return &trigger, nil
 }
 
+// ensureSyncer creates the syncer for the sync config if not created, and adds syncTarget to
+// objects referencing it, returns true if syncTarget is newly added
 func (c *Controller) ensureSyncer(
 	syncTarget configRef,
 	md metav1.ObjectMetaAccessor,
@@ -154,22 +184,12 @@ func (c *Controller) ensureSyncer(
 		}
 	}
 
-	exists := func() bool {
-		c.syncerMu.RLock()
-		defer c.syncerMu.RUnlock()
-
-		if _, ok := c.syncerTriggerIndex[*trigger]; ok {
-			// already created this syncer
-			return true
-		}
-		return false
-	}()
-
+	exists, added := c.addSyncerRef(*trigger, syncTarget)
 	if exists {
-		return false, nil
+		return added, nil
 	}
 
-	logger := c.logger.WithName("syncer").
+	logger := c.getLogger().WithName("syncer").
 		WithFields(log.String("for", syncTarget.String()), log.String("config", trigger.String()))
 
 	// create, start and register syncer
@@ -199,11 +219,63 @@ func (c *Controller) ensureSyncer(
 		return false, fmt.Errorf("failed to get syncer config: %w", err)
 	}
 
-	s, err := syncer.NewSyncer(c.ctx, logger, config)
+	syncerConfig := *trigger
+
+	policy := c.getSyncPolicy()
+	for _, tc := range config.TargetConfigs() {
+		if tc != nil && tc.NamespaceSelector != nil {
+			if !policy.AllowNamespaceSelector {
+				return false, fmt.Errorf("target namespace selector is not allowed")
+			}
+
+			if c.getInformers().ns == nil {
+				return false, fmt.Errorf("target namespace selector is not supported in namespaced mode")
+			}
+
+			// selected namespaces are checked when resolving targets
+			continue
+		}
+
+		target := resolveWriteTarget(syncTarget, tc)
+		if !policy.TargetNamespaceAllowed(syncTarget.namespace, target.namespace) {
+			return false, fmt.Errorf("sync target %q is not allowed: namespace %q not in allowed target namespaces",
+				target.String(), target.namespace)
+		}
+	}
+
+	// validators not depending on the sync target see the first target of the object creating
+	// the syncer, target dependent validators are evaluated for each write target
+	writeTarget := resolveWriteTarget(syncTarget, config.TargetConfigs()[0])
+
+	// data refs in the sync config are resolved in its own namespace
+	syncerCtx := dataref.WithNamespace(dataref.WithGetter(c.ctx, c.dataGetter()), syncerConfig.namespace)
+	syncerCtx = validator.WithSyncTarget(syncerCtx, newValidatorSyncTarget(writeTarget, syncerConfig))
+	spec := &syncerSpec{
+		syncerConfig: syncerConfig,
+		config:       config,
+		refs:         map[configRef]struct{}{syncTarget: {}},
+	}
+
+	s, err := syncer.NewSyncer(syncerCtx, logger, config, func() []validator.SyncTarget {
+		var targets []validator.SyncTarget
+		for target := range c.resolveSyncTargets(logger, spec) {
+			targets = append(targets, newValidatorSyncTarget(target, syncerConfig))
+		}
+		return targets
+	}, func(target validator.SyncTarget, validatorMethod, key string, err error) {
+		c.recordRejectionEvent(configRefFromValidatorSyncTarget(target), validatorMethod, key, err)
+	}, func(missing []string, action string) {
+		c.recordStallEvent(writeTarget, missing, action)
+	}, func(status fetcher.Status, healthy bool) {
+		c.handleFetcherHealthChanged(logger, spec, writeTarget, status, healthy)
+	})
 	if err != nil {
 		return false, fmt.Errorf("failed to create syncer: %w", err)
 	}
 
+	// set before start, health of fetchers is reported with status of the syncer
+	spec.syncer = s
+
 	err = s.Start(c.ctx.Done())
 	if err != nil {
 		_ = s.Stop()
@@ -211,107 +283,428 @@ func (c *Controller) ensureSyncer(
 		return false, fmt.Errorf("failed to start syncer: %w", err)
 	}
 
-	func() {
+	registered := func() bool {
 		c.syncerMu.Lock()
 		defer c.syncerMu.Unlock()
 
-		c.syncerTriggerIndex[*trigger] = &syncerSpec{
-			targetConfig: syncTarget,
-			syncerConfig: *trigger,
-			syncer:       s,
+		if existing, ok := c.syncerTriggerIndex[*trigger]; ok {
+			// created by others in the meantime
+			existing.refs[syncTarget] = struct{}{}
+			return false
 		}
+
+		c.syncerTriggerIndex[*trigger] = spec
+		return true
 	}()
 
-	go func(target configRef) {
-		logger.I("starting config syncing routing")
-		for update := range s.Retrieve() {
-			var err error
-			switch target.kind {
-			case configKindCM:
-				logger.V("got an update")
-				err = c.updateConfigMapWithNewData(target.namespace, target.name, update)
-			case configKindSecret:
-				logger.V("got an update")
-				err = c.updateSecretWithNewData(target.namespace, target.name, update)
-			default:
-				logger.V("unknown target kind")
+	if !registered {
+		_ = s.Stop()
+		return true, nil
+	}
+
+	go c.runSyncer(logger, spec)
+
+	return true, nil
+}
+
+// addSyncerRef adds syncTarget to objects referencing the syncer if the syncer exists,
+// returns whether the syncer exists and whether syncTarget is newly added
+func (c *Controller) addSyncerRef(trigger, syncTarget configRef) (exists, added bool) {
+	c.syncerMu.Lock()
+	defer c.syncerMu.Unlock()
+
+	spec, ok := c.syncerTriggerIndex[trigger]
+	if !ok {
+		return false, false
+	}
+
+	if _, ok = spec.refs[syncTarget]; ok {
+		return true, false
+	}
+
+	spec.refs[syncTarget] = struct{}{}
+	return true, true
+}
+
+// runSyncer writes updates from syncer to all targets until the syncer stopped
+func (c *Controller) runSyncer(logger log.Interface, spec *syncerSpec) {
+	logger.I("starting config syncing routing")
+	for update := range spec.syncer.Retrieve() {
+		logger.V("got an update")
+
+		c.writeSyncTargets(logger, spec, update)
+	}
+}
+
+// writeSyncTargets writes update from syncer to all resolved sync targets
+func (c *Controller) writeSyncTargets(logger log.Interface, spec *syncerSpec, update map[string][]byte) {
+	for target, ts := range c.resolveSyncTargets(logger, spec) {
+		var createOpts *syncer.TargetConfig
+		if ts.config != nil && ts.config.Create {
+			createOpts = ts.config
+		}
+
+		// validated before key mapping, as other validators
+		data := spec.syncer.ValidateTarget(newValidatorSyncTarget(target, spec.syncerConfig), update)
+		data = ts.config.MapKeys(data)
+		if len(data) == 0 {
+			continue
+		}
+
+		// annotated objects are opted in, other existing objects must be managed by ksync
+		requireManaged := !ts.annotated
+
+		var err error
+		switch target.kind {
+		case configKindCM:
+			err = c.updateConfigMapWithNewData(
+				target.namespace, target.name, data, spec.syncer.Merge, createOpts, requireManaged)
+		case configKindSecret:
+			err = c.updateSecretWithNewData(
+				target.namespace, target.name, data, spec.syncer.Merge, createOpts, requireManaged)
+		default:
+			logger.V("unknown target kind")
+			continue
+		}
+
+		c.recordSyncEvent(target, spec.syncerConfig, data, err)
+		if err != nil {
+			syncFailedCounter.Add(c.ctx, 1, configKindLabel(target.kind))
+			logger.I("failed to update target config", log.String("target", target.String()), log.Error(err))
+			continue
+		}
+
+		syncAppliedCounter.Add(c.ctx, 1, configKindLabel(target.kind))
+		logger.I("synced", log.String("target", target.String()))
+	}
+}
+
+// syncTargetSpec is an object to write synced data
+type syncTargetSpec struct {
+	config *syncer.TargetConfig
+
+	// annotated is true if the target is an object annotated with the sync config
+	annotated bool
+}
+
+// resolveSyncTargets returns all objects to write synced data with their target configs,
+// targets in namespaces not allowed are ignored
+func (c *Controller) resolveSyncTargets(
+	logger log.Interface,
+	spec *syncerSpec,
+) map[configRef]*syncTargetSpec {
+	refs := func() map[configRef]struct{} {
+		c.syncerMu.RLock()
+		defer c.syncerMu.RUnlock()
+
+		ret := make(map[configRef]struct{}, len(spec.refs))
+		for ref := range spec.refs {
+			ret[ref] = struct{}{}
+		}
+		return ret
+	}()
+
+	policy := c.getSyncPolicy()
+	targets := make(map[configRef]*syncTargetSpec)
+	for _, tc := range spec.config.TargetConfigs() {
+		selector, _ := tc.Selector()
+
+		var namespaces []string
+		if selector != nil {
+			nsInformer := c.getInformers().ns
+			if !policy.AllowNamespaceSelector || nsInformer == nil {
+				logger.I("target namespace selector not allowed", log.String("selector", selector.String()))
 				continue
 			}
 
-			logger.I("synced")
-			if err != nil {
-				logger.I("failed to update target config", log.Error(err))
+			for _, item := range nsInformer.GetIndexer().List() {
+				ns, ok := item.(*corev1.Namespace)
+				if !ok || ns.Status.Phase == corev1.NamespaceTerminating {
+					continue
+				}
+
+				if selector.Matches(labels.Set(ns.Labels)) {
+					namespaces = append(namespaces, ns.Name)
+				}
 			}
 		}
-	}(syncTarget)
 
-	return true, nil
+		for ref := range refs {
+			target := resolveWriteTarget(ref, tc)
+			if selector == nil {
+				namespaces = []string{target.namespace}
+			}
+
+			for _, ns := range namespaces {
+				target.namespace = ns
+				if !policy.TargetNamespaceAllowed(ref.namespace, ns) {
+					logger.I("sync target namespace not allowed",
+						log.String("target", target.String()), log.String("for", ref.String()))
+					continue
+				}
+
+				_, annotated := refs[target]
+				targets[target] = &syncTargetSpec{config: tc, annotated: annotated}
+			}
+		}
+	}
+
+	return targets
+}
+
+// resolveWriteTarget returns the object to write synced data, defaults to the annotated object
+func resolveWriteTarget(annotated configRef, config *syncer.TargetConfig) configRef {
+	if config == nil {
+		return annotated
+	}
+
+	target := annotated
+	switch config.Kind {
+	case syncer.TargetKindConfigMap:
+		target.kind = configKindCM
+	case syncer.TargetKindSecret:
+		target.kind = configKindSecret
+	}
+
+	if config.Namespace != "" {
+		target.namespace = config.Namespace
+	}
+
+	if config.Name != "" {
+		target.name = config.Name
+	}
+
+	return target
 }
 
-func (c *Controller) updateConfigMapWithNewData(namespace, name string, data map[string][]byte) error {
+// dataMergeFunc merges synced data into current data
+type dataMergeFunc func(current, update map[string][]byte) (map[string][]byte, error)
+
+// updateConfigMapWithNewData merges data into the configmap, the configmap is created with
+// createOpts if not found (returns error if createOpts is nil)
+func (c *Controller) updateConfigMapWithNewData(
+	namespace, name string,
+	data map[string][]byte,
+	merge dataMergeFunc,
+	createOpts *syncer.TargetConfig,
+	requireManaged bool,
+) error {
 	key := namespace + "/" + name
-	item, found, err := c.cmInformer.GetIndexer().GetByKey(key)
+	cm, found, err := c.configGetter().getConfigMap(namespace, name)
 	if err != nil {
 		return fmt.Errorf("failed to find configmpa %q: %w", key, err)
 	}
+
 	if !found {
-		return nil
+		// may be out of the watch scope
+		cm, err = c.kubeClient.CoreV1().ConfigMaps(namespace).Get(c.ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if !kubeerrors.IsNotFound(err) {
+				return fmt.Errorf("failed to get configmap %q: %w", key, err)
+			}
+
+			return c.createConfigMapWithNewData(namespace, name, data, merge, createOpts)
+		}
 	}
 
-	cm, ok := item.(*corev1.ConfigMap)
-	if !ok {
-		return fmt.Errorf("invalid cache item, not a configmap: %t", item)
+	if requireManaged && !isSyncManaged(cm) {
+		return fmt.Errorf("configmap %q: %w", key, errSyncTargetUnmanaged)
 	}
 
 	cm = cm.DeepCopy()
-	if cm.Data == nil {
-		cm.Data = make(map[string]string)
+
+	current := make(map[string][]byte, len(cm.Data)+len(cm.BinaryData))
+	for k, d := range cm.BinaryData {
+		current[k] = d
 	}
+	for k, d := range cm.Data {
+		current[k] = []byte(d)
+	}
+
+	newData, err := merge(current, data)
+	if err != nil {
+		return fmt.Errorf("failed to merge data into configmap %q: %w", key, err)
+	}
+
+	binaryData := cm.BinaryData
+	cm.Data, cm.BinaryData = make(map[string]string), nil
+	for k, d := range newData {
+		// keep unchanged binary data as is
+		if old, ok := binaryData[k]; ok && bytes.Equal(old, d) {
+			if cm.BinaryData == nil {
+				cm.BinaryData = make(map[string][]byte)
+			}
+
+			cm.BinaryData[k] = d
+			continue
+		}
 
-	for k, d := range data {
 		cm.Data[k] = string(d)
 	}
 
+	// guard override is for one update only
+	delete(cm.Annotations, constant.AnnotationGuardOverride)
+
 	_, err = c.kubeClient.CoreV1().ConfigMaps(namespace).Update(c.ctx, cm, metav1.UpdateOptions{})
-	if err != nil && !kubeerrors.IsNotFound(err) {
+	if err != nil {
+		if kubeerrors.IsNotFound(err) {
+			return c.createConfigMapWithNewData(namespace, name, data, merge, createOpts)
+		}
+
 		return fmt.Errorf("failed to update configmap %q with new data: %w", key, err)
 	}
 
+	c.recordSyncedData(createConfigRef(configKindCM, namespace, name, ""), data, newData)
+
 	return nil
 }
 
-func (c *Controller) updateSecretWithNewData(namespace, name string, data map[string][]byte) error {
+func (c *Controller) createConfigMapWithNewData(
+	namespace, name string,
+	data map[string][]byte,
+	merge dataMergeFunc,
+	createOpts *syncer.TargetConfig,
+) error {
 	key := namespace + "/" + name
-	item, found, err := c.secretInformer.GetIndexer().GetByKey(key)
+	if createOpts == nil {
+		return fmt.Errorf("configmap %q not found", key)
+	}
+
+	newData, err := merge(nil, data)
+	if err != nil {
+		return fmt.Errorf("failed to merge data into new configmap %q: %w", key, err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: newTargetObjectMeta(namespace, name, createOpts),
+		Data:       make(map[string]string, len(newData)),
+	}
+	for k, d := range newData {
+		cm.Data[k] = string(d)
+	}
+
+	_, err = c.kubeClient.CoreV1().ConfigMaps(namespace).Create(c.ctx, cm, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create configmap %q with new data: %w", key, err)
+	}
+
+	c.recordSyncedData(createConfigRef(configKindCM, namespace, name, ""), data, newData)
+
+	return nil
+}
+
+// updateSecretWithNewData merges data into the secret, the secret is created with
+// createOpts if not found (returns error if createOpts is nil)
+func (c *Controller) updateSecretWithNewData(
+	namespace, name string,
+	data map[string][]byte,
+	merge dataMergeFunc,
+	createOpts *syncer.TargetConfig,
+	requireManaged bool,
+) error {
+	key := namespace + "/" + name
+	secret, found, err := c.configGetter().getSecret(namespace, name)
 	if err != nil {
 		return fmt.Errorf("failed to find secret %q: %w", key, err)
 	}
+
 	if !found {
-		return nil
+		// may be out of the watch scope
+		secret, err = c.kubeClient.CoreV1().Secrets(namespace).Get(c.ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if !kubeerrors.IsNotFound(err) {
+				return fmt.Errorf("failed to get secret %q: %w", key, err)
+			}
+
+			return c.createSecretWithNewData(namespace, name, data, merge, createOpts)
+		}
 	}
 
-	secret, ok := item.(*corev1.Secret)
-	if !ok {
-		return fmt.Errorf("invalid cache item, not a secret: %t", item)
+	if requireManaged && !isSyncManaged(secret) {
+		return fmt.Errorf("secret %q: %w", key, errSyncTargetUnmanaged)
 	}
 
 	secret = secret.DeepCopy()
-	if secret.Data == nil {
-		secret.Data = make(map[string][]byte)
-	}
 
-	for k, d := range data {
-		secret.Data[k] = d
+	secret.Data, err = merge(secret.Data, data)
+	if err != nil {
+		return fmt.Errorf("failed to merge data into secret %q: %w", key, err)
 	}
 
+	// guard override is for one update only
+	delete(secret.Annotations, constant.AnnotationGuardOverride)
+
 	_, err = c.kubeClient.CoreV1().Secrets(namespace).Update(c.ctx, secret, metav1.UpdateOptions{})
-	if err != nil && !kubeerrors.IsNotFound(err) {
+	if err != nil {
+		if kubeerrors.IsNotFound(err) {
+			return c.createSecretWithNewData(namespace, name, data, merge, createOpts)
+		}
+
 		return fmt.Errorf("failed to update secret %q with new data: %w", key, err)
 	}
 
+	c.recordSyncedData(createConfigRef(configKindSecret, namespace, name, ""), data, secret.Data)
+
+	return nil
+}
+
+func (c *Controller) createSecretWithNewData(
+	namespace, name string,
+	data map[string][]byte,
+	merge dataMergeFunc,
+	createOpts *syncer.TargetConfig,
+) error {
+	key := namespace + "/" + name
+	if createOpts == nil {
+		return fmt.Errorf("secret %q not found", key)
+	}
+
+	newData, err := merge(nil, data)
+	if err != nil {
+		return fmt.Errorf("failed to merge data into new secret %q: %w", key, err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: newTargetObjectMeta(namespace, name, createOpts),
+		Type:       corev1.SecretType(createOpts.Type),
+		Data:       newData,
+	}
+
+	_, err = c.kubeClient.CoreV1().Secrets(namespace).Create(c.ctx, secret, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create secret %q with new data: %w", key, err)
+	}
+
+	c.recordSyncedData(createConfigRef(configKindSecret, namespace, name, ""), data, newData)
+
 	return nil
 }
 
+// newTargetObjectMeta returns metadata of the created sync target, labeled as managed by ksync sync
+func newTargetObjectMeta(namespace, name string, createOpts *syncer.TargetConfig) metav1.ObjectMeta {
+	labels := make(map[string]string, len(createOpts.Labels)+1)
+	for k, v := range createOpts.Labels {
+		labels[k] = v
+	}
+	labels[constant.LabelManagedBy] = constant.LabelManagedByValueSync
+
+	return metav1.ObjectMeta{
+		Namespace:       namespace,
+		Name:            name,
+		Labels:          labels,
+		Annotations:     createOpts.Annotations,
+		OwnerReferences: createOpts.OwnerReferences,
+	}
+}
+
+// errSyncTargetUnmanaged is returned when writing to an existing object neither annotated with
+// the sync config nor managed by ksync sync
+var errSyncTargetUnmanaged = fmt.Errorf("object exists and is not managed by ksync sync")
+
+func isSyncManaged(obj metav1.Object) bool {
+	return obj.GetLabels()[constant.LabelManagedBy] == constant.LabelManagedByValueSync
+}
+
 func getSyncerConfig(key string, stringData map[string]string, binaryData map[string][]byte) (*syncer.Config, error) {
 	d, ok := binaryData[key]
 	if !ok {
@@ -335,3 +728,112 @@ func getSyncerConfig(key string, stringData map[string]string, binaryData map[st
 
 	return config, nil
 }
+
+func (c *Controller) dataGetter() dataref.Getter {
+	return &kubeDataGetter{kubeClient: c.kubeClient, cache: c.configGetter}
+}
+
+// kubeDataGetter gets data referenced by validators from informer cache, or kubernetes
+// if not cached (e.g. out of the watch scope)
+type kubeDataGetter struct {
+	kubeClient kubeclient.Interface
+	cache      func() configGetter
+}
+
+func (g *kubeDataGetter) Get(ctx context.Context, ref *dataref.Ref) ([]byte, error) {
+	obj, err := g.GetObject(ctx, ref.Kind, ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if d, ok := obj.Data[ref.Key]; ok {
+		return d, nil
+	}
+
+	return nil, fmt.Errorf("%w: key %q", dataref.ErrNotFound, ref.Key)
+}
+
+func (g *kubeDataGetter) GetObject(
+	ctx context.Context, kind dataref.Kind, namespace, name string,
+) (*dataref.Object, error) {
+	var (
+		obj        = new(dataref.Object)
+		stringData map[string]string
+		binaryData map[string][]byte
+		err        error
+	)
+	switch kind {
+	case dataref.KindConfigMap:
+		cm, found, _ := g.cache().getConfigMap(namespace, name)
+		if !found {
+			cm, err = g.kubeClient.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		}
+		if err == nil {
+			obj.Annotations, stringData, binaryData = cm.Annotations, cm.Data, cm.BinaryData
+		}
+	case dataref.KindSecret:
+		secret, found, _ := g.cache().getSecret(namespace, name)
+		if !found {
+			secret, err = g.kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		}
+		if err == nil {
+			obj.Annotations, stringData, binaryData = secret.Annotations, secret.StringData, secret.Data
+		}
+	default:
+		return nil, fmt.Errorf("unsupported data reference kind %q", kind)
+	}
+
+	if err != nil {
+		if kubeerrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %v", dataref.ErrNotFound, err)
+		}
+		return nil, err
+	}
+
+	obj.Data = make(map[string][]byte, len(stringData)+len(binaryData))
+	for k, v := range stringData {
+		obj.Data[k] = []byte(v)
+	}
+	for k, v := range binaryData {
+		obj.Data[k] = v
+	}
+
+	return obj, nil
+}
+
+func configRefDataKind(ref configRef) dataref.Kind {
+	if ref.kind == configKindSecret {
+		return dataref.KindSecret
+	}
+
+	return dataref.KindConfigMap
+}
+
+// newValidatorSyncTarget creates sync target of syncerConfig for validators
+func newValidatorSyncTarget(target, syncerConfig configRef) validator.SyncTarget {
+	return validator.SyncTarget{
+		Kind:      string(configRefDataKind(target)),
+		Namespace: target.namespace,
+		Name:      target.name,
+		Syncer:    configRefToDataRef(syncerConfig).String(),
+	}
+}
+
+// configRefFromValidatorSyncTarget is the reverse of newValidatorSyncTarget
+func configRefFromValidatorSyncTarget(target validator.SyncTarget) configRef {
+	kind := configKindCM
+	if dataref.Kind(target.Kind) == dataref.KindSecret {
+		kind = configKindSecret
+	}
+
+	return createConfigRef(kind, target.Namespace, target.Name, "")
+}
+
+func configRefToDataRef(ref configRef) *dataref.Ref {
+	return &dataref.Ref{
+		Kind:      configRefDataKind(ref),
+		Namespace: ref.namespace,
+		Name:      ref.name,
+		Key:       ref.key,
+	}
+}
@@ -0,0 +1,315 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"arhat.dev/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"arhat.dev/ksync/pkg/conf"
+	"arhat.dev/ksync/pkg/constant"
+	"arhat.dev/ksync/pkg/dataref"
+	"arhat.dev/ksync/pkg/syncer"
+	"arhat.dev/ksync/pkg/validator"
+)
+
+// newTestController creates a controller with objs in both the fake clientset and informer cache
+func newTestController(t *testing.T, policy *conf.SyncConfig, objs ...runtime.Object) *Controller {
+	client := fake.NewSimpleClientset(objs...)
+	factory := informers.NewSharedInformerFactory(client, 0)
+
+	set := &informerSet{
+		cm:     factory.Core().V1().ConfigMaps().Informer(),
+		secret: factory.Core().V1().Secrets().Informer(),
+		ns:     factory.Core().V1().Namespaces().Informer(),
+	}
+	for _, obj := range objs {
+		var err error
+		switch obj.(type) {
+		case *corev1.ConfigMap:
+			err = set.cm.GetIndexer().Add(obj)
+		case *corev1.Secret:
+			err = set.secret.GetIndexer().Add(obj)
+		case *corev1.Namespace:
+			err = set.ns.GetIndexer().Add(obj)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := &Controller{
+		ctx:                context.TODO(),
+		kubeClient:         client,
+		syncerTriggerIndex: make(map[configRef]*syncerSpec),
+		syncerMu:           new(sync.RWMutex),
+		publishers:         make(map[configRef]*publisherSpec),
+		syncedHashes:       make(map[configRef]string),
+		publishMu:          new(sync.RWMutex),
+		mirrorSources:      make(map[configRef]struct{}),
+		mirrorMu:           new(sync.RWMutex),
+	}
+	c.logger.Store(log.Log.WithName("test"))
+	c.informers.Store(set)
+	c.syncPolicy.Store(policy)
+	c.mirrorPolicy.Store(&conf.MirrorConfig{})
+
+	return c
+}
+
+func replaceMerge(current, update map[string][]byte) (map[string][]byte, error) {
+	return update, nil
+}
+
+func TestUpdateConfigMapWithNewData(t *testing.T) {
+	newCM := func(name string, labels map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: labels},
+			Data:       map[string]string{"a": "old"},
+		}
+	}
+
+	c := newTestController(t, &conf.SyncConfig{},
+		newCM("unmanaged", nil),
+		newCM("managed", map[string]string{constant.LabelManagedBy: constant.LabelManagedByValueSync}),
+	)
+	data := map[string][]byte{"a": []byte("new")}
+
+	tests := []struct {
+		name           string
+		requireManaged bool
+		createOpts     *syncer.TargetConfig
+		expectErr      error
+		expectData     string
+	}{
+		{name: "unmanaged", requireManaged: false, expectData: "new"},
+		{name: "unmanaged", requireManaged: true, expectErr: errSyncTargetUnmanaged},
+		{name: "managed", requireManaged: true, expectData: "new"},
+		{name: "created", requireManaged: true, createOpts: &syncer.TargetConfig{Create: true}, expectData: "new"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := c.updateConfigMapWithNewData(
+				"default", test.name, data, replaceMerge, test.createOpts, test.requireManaged)
+			if test.expectErr != nil {
+				if !errors.Is(err, test.expectErr) {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cm, err := c.kubeClient.CoreV1().ConfigMaps("default").Get(c.ctx, test.name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cm.Data["a"] != test.expectData {
+				t.Errorf("unexpected data %v", cm.Data)
+			}
+
+			if test.createOpts != nil && !isSyncManaged(cm) {
+				t.Errorf("created target not labeled as managed: %v", cm.Labels)
+			}
+		})
+	}
+}
+
+func TestWriteSyncTargetsValidatePerTarget(t *testing.T) {
+	newCM := func(name, data string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Data:       map[string]string{"app.conf": data},
+		}
+	}
+
+	foo, bar := createConfigRef(configKindCM, "default", "foo", ""), createConfigRef(configKindCM, "default", "bar", "")
+	c := newTestController(t, &conf.SyncConfig{},
+		newCM("foo", strings.Repeat("x", 100)),
+		newCM("bar", "x"),
+	)
+
+	config := &syncer.Config{
+		Validators: []*validator.Config{{
+			Method:   validator.MethodGuard,
+			DataKeys: []string{"app.conf"},
+			Guard:    &validator.GuardConfig{MaxShrinkPercent: 50},
+		}},
+	}
+
+	ctx := dataref.WithGetter(c.ctx, c.dataGetter())
+	s, err := syncer.NewSyncer(ctx, log.Log.WithName("test"), config, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec := &syncerSpec{
+		syncerConfig: createConfigRef(configKindCM, "default", "syncer", "config.yaml"),
+		syncer:       s,
+		config:       config,
+		refs:         map[configRef]struct{}{foo: {}, bar: {}},
+	}
+	c.writeSyncTargets(c.getLogger(), spec, map[string][]byte{"app.conf": []byte("yyyy")})
+
+	// shrinks too much for foo only
+	for name, expected := range map[string]string{"foo": strings.Repeat("x", 100), "bar": "yyyy"} {
+		cm, err := c.kubeClient.CoreV1().ConfigMaps("default").Get(c.ctx, name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cm.Data["app.conf"] != expected {
+			t.Errorf("unexpected data of %q: %v", name, cm.Data)
+		}
+	}
+}
+
+func TestResolveSyncTargetsNamespaceAllowed(t *testing.T) {
+	annotated := createConfigRef(configKindCM, "default", "foo", "")
+	spec := &syncerSpec{
+		config: &syncer.Config{
+			Targets: []*syncer.TargetConfig{
+				nil,
+				{Namespace: "shared", Name: "bar"},
+				{Namespace: "kube-system", Name: "bar"},
+			},
+		},
+		refs: map[configRef]struct{}{annotated: {}},
+	}
+
+	c := newTestController(t, &conf.SyncConfig{AllowedTargetNamespaces: []string{"shared"}})
+	targets := c.resolveSyncTargets(c.getLogger(), spec)
+	if len(targets) != 2 {
+		t.Fatalf("unexpected targets %v", targets)
+	}
+
+	if ts, ok := targets[annotated]; !ok || !ts.annotated {
+		t.Errorf("annotated object not resolved as annotated target")
+	}
+
+	if ts, ok := targets[createConfigRef(configKindCM, "shared", "bar", "")]; !ok || ts.annotated {
+		t.Errorf("allowed target not resolved as unannotated target")
+	}
+}
+
+func TestResolveSyncTargetsNamespaceSelector(t *testing.T) {
+	newNS := func(name string, phase corev1.NamespacePhase) *corev1.Namespace {
+		return &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"team": "foo"}},
+			Status:     corev1.NamespaceStatus{Phase: phase},
+		}
+	}
+
+	annotated := createConfigRef(configKindCM, "default", "foo", "")
+	spec := &syncerSpec{
+		config: &syncer.Config{
+			Target: &syncer.TargetConfig{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "foo"}},
+			},
+		},
+		refs: map[configRef]struct{}{annotated: {}},
+	}
+	objs := []runtime.Object{
+		newNS("a", corev1.NamespaceActive),
+		newNS("b", corev1.NamespaceActive),
+		newNS("c", corev1.NamespaceTerminating),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "d"}},
+	}
+
+	tests := []struct {
+		name    string
+		policy  *conf.SyncConfig
+		targets []string
+	}{
+		{
+			name:   "Selector Not Allowed",
+			policy: &conf.SyncConfig{AllowedTargetNamespaces: []string{"*"}},
+		},
+		{
+			name:    "All Namespaces Allowed",
+			policy:  &conf.SyncConfig{AllowNamespaceSelector: true, AllowedTargetNamespaces: []string{"*"}},
+			targets: []string{"a", "b"},
+		},
+		{
+			name:    "Some Namespaces Allowed",
+			policy:  &conf.SyncConfig{AllowNamespaceSelector: true, AllowedTargetNamespaces: []string{"b", "d"}},
+			targets: []string{"b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newTestController(t, test.policy, objs...)
+			targets := c.resolveSyncTargets(c.getLogger(), spec)
+			if len(targets) != len(test.targets) {
+				t.Fatalf("unexpected targets %v", targets)
+			}
+
+			for _, ns := range test.targets {
+				if _, ok := targets[createConfigRef(configKindCM, ns, "foo", "")]; !ok {
+					t.Errorf("target in namespace %q not resolved", ns)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncerRefs(t *testing.T) {
+	c := newTestController(t, &conf.SyncConfig{})
+
+	trigger := createConfigRef(configKindCM, "default", "syncer", "config.yaml")
+	foo := createConfigRef(configKindCM, "default", "foo", "")
+	bar := createConfigRef(configKindSecret, "default", "bar", "")
+
+	if exists, _ := c.addSyncerRef(trigger, foo); exists {
+		t.Fatal("syncer exists before created")
+	}
+
+	c.syncerTriggerIndex[trigger] = &syncerSpec{
+		syncerConfig: trigger,
+		refs:         map[configRef]struct{}{foo: {}},
+	}
+
+	if exists, added := c.addSyncerRef(trigger, foo); !exists || added {
+		t.Errorf("existing ref added again")
+	}
+
+	if exists, added := c.addSyncerRef(trigger, bar); !exists || !added {
+		t.Errorf("new ref not added")
+	}
+
+	refs, err := c.removeSyncer(&foo, nil, &trigger)
+	if err != nil || len(refs) != 0 {
+		t.Fatalf("unexpected result of removing ref: %v, %v", refs, err)
+	}
+
+	if _, ok := c.syncerTriggerIndex[trigger]; !ok {
+		t.Fatal("syncer removed while still referenced")
+	}
+
+	_, err = c.removeSyncer(&bar, nil, &trigger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.syncerTriggerIndex[trigger]; ok {
+		t.Error("syncer not removed after all refs removed")
+	}
+
+	_, err = c.removeSyncer(nil, nil, nil)
+	if err == nil {
+		t.Error("removed syncer without key")
+	}
+}
@@ -1,6 +1,11 @@
 package controller
 
-import "arhat.dev/ksync/pkg/syncer"
+import (
+	"time"
+
+	"arhat.dev/ksync/pkg/fetcher"
+	"arhat.dev/ksync/pkg/syncer"
+)
 
 // kinds of objects to be reloaded
 type reloadKind string
@@ -52,12 +57,30 @@ type (
 	reloadSpec struct {
 		reloadObjectKey
 		triggers map[configRef]struct{}
+
+		// notifiedAt is the time when the earliest config change of this reload was noticed
+		notifiedAt time.Time
 	}
 
 	syncerSpec struct {
-		targetConfig configRef
 		syncerConfig configRef
 		syncer       *syncer.Syncer
+		config       *syncer.Config
+
+		// refs are objects annotated with the syncer config (guarded by syncerMu),
+		// the syncer is stopped when all of them removed
+		refs map[configRef]struct{}
+	}
+
+	publisherSpec struct {
+		publishConfig configRef
+		publisher     fetcher.Publisher
+
+		// configHash is the hash of publisher config, publisher is recreated when changed
+		configHash string
+
+		// hashes of data keys published (or skipped)
+		hashes map[string]string
 	}
 )
 
@@ -39,3 +39,26 @@ func isConfigRequireSynced(md metav1.ObjectMetaAccessor) bool {
 
 	return labels[constant.LabelAction] == constant.LabelActionValueSync
 }
+
+func isConfigRequireMirrored(md metav1.ObjectMetaAccessor) bool {
+	return md.GetObjectMeta().GetLabels()[constant.LabelAction] == constant.LabelActionValueMirror
+}
+
+func isConfigRequirePublished(md metav1.ObjectMetaAccessor) bool {
+	return md.GetObjectMeta().GetLabels()[constant.LabelPublish] == constant.LabelPublishValueEnabled
+}
+
+// getMirrorSource returns the source of the mirrored copy, returns false if not a copy
+func getMirrorSource(md metav1.ObjectMetaAccessor) (namespace, name string, ok bool) {
+	meta := md.GetObjectMeta()
+	if meta.GetLabels()[constant.LabelManagedBy] != constant.LabelManagedByValueMirror {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(meta.GetAnnotations()[constant.AnnotationMirrorSource], "/", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
@@ -1,6 +1,9 @@
 package controller
 
 import (
+	"sort"
+	"time"
+
 	"arhat.dev/pkg/hashhelper"
 	"arhat.dev/pkg/log"
 	"arhat.dev/pkg/queue"
@@ -40,9 +43,17 @@ func buildTriggerSourceHash(
 		data[k] = []byte(stringData[k])
 	}
 
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	// sort keys to get stable hash of all data
+	sort.Strings(keys)
+
 	var allData []byte
 	result := make(map[configRef]string)
-	for k, v := range data {
+	for _, k := range keys {
+		v := data[k]
 		result[createConfigRef(kind, namespace, name, k)] = hashhelper.Sha256SumHex(v)
 
 		allData = append(allData, v...)
@@ -54,6 +65,7 @@ func buildTriggerSourceHash(
 }
 
 func (c *Controller) notifyUpdate(baseLogger log.Interface, triggerSourceHashUpdate map[configRef]string) {
+	notifiedAt := time.Now()
 	canBeReloadedBy := make(map[configRef]struct{})
 
 	func() {
@@ -105,13 +117,14 @@ func (c *Controller) notifyUpdate(baseLogger log.Interface, triggerSourceHashUpd
 			logger := baseLogger.WithFields(log.String("target", r.String()))
 			logger.V("scheduling reloading")
 
-			c.reloadRec.Update(r, nil, &reloadSpec{reloadObjectKey: r, triggers: canBeReloadedBy})
-			err := c.reloadRec.Schedule(queue.Job{Action: queue.ActionAdd, Key: r}, c.reloadDelay)
+			c.reloadRec.Update(r, nil, c.addPendingReload(r, canBeReloadedBy, notifiedAt))
+			err := c.reloadRec.Schedule(queue.Job{Action: queue.ActionAdd, Key: r}, c.getReloadDelay())
 			if err != nil {
 				logger.E("failed to schedule reload", log.Error(err))
 				continue
 			}
 
+			reloadScheduledCounter.Add(c.ctx, 1, reloadKindLabel(r.kind))
 			logger.V("reload scheduled")
 		}
 	}()
@@ -129,6 +142,7 @@ func (c *Controller) notifyUpdate(baseLogger log.Interface, triggerSourceHashUpd
 			logger := baseLogger.WithFields(log.String("target", t.String()))
 			logger.V("scheduling syncer config update")
 
+			c.addPendingSyncerUpdate(t, spec)
 			c.syncRec.Update(t, nil, spec)
 			err := c.syncRec.Schedule(queue.Job{Action: queue.ActionAdd, Key: t}, 0)
 			if err != nil {
@@ -140,3 +154,64 @@ func (c *Controller) notifyUpdate(baseLogger log.Interface, triggerSourceHashUpd
 		}
 	}()
 }
+
+// addPendingReload records a scheduled reload, triggers of the pending reload (if any) are merged
+// into the new one
+func (c *Controller) addPendingReload(
+	key reloadObjectKey,
+	triggers map[configRef]struct{},
+	notifiedAt time.Time,
+) *reloadSpec {
+	c.schedMu.Lock()
+	defer c.schedMu.Unlock()
+
+	spec := &reloadSpec{
+		reloadObjectKey: key,
+		triggers:        make(map[configRef]struct{}),
+		notifiedAt:      notifiedAt,
+	}
+
+	if prev, ok := c.pendingReloads[key]; ok {
+		for t := range prev.triggers {
+			spec.triggers[t] = struct{}{}
+		}
+
+		if prev.notifiedAt.Before(notifiedAt) {
+			spec.notifiedAt = prev.notifiedAt
+		}
+	}
+
+	for t := range triggers {
+		spec.triggers[t] = struct{}{}
+	}
+
+	c.pendingReloads[key] = spec
+
+	return spec
+}
+
+func (c *Controller) removePendingReload(spec *reloadSpec) {
+	c.schedMu.Lock()
+	defer c.schedMu.Unlock()
+
+	// do not remove newly scheduled one
+	if c.pendingReloads[spec.reloadObjectKey] == spec {
+		delete(c.pendingReloads, spec.reloadObjectKey)
+	}
+}
+
+func (c *Controller) addPendingSyncerUpdate(key configRef, spec *syncerSpec) {
+	c.schedMu.Lock()
+	defer c.schedMu.Unlock()
+
+	c.pendingSyncerUpdates[key] = spec
+}
+
+func (c *Controller) removePendingSyncerUpdate(key configRef, spec *syncerSpec) {
+	c.schedMu.Lock()
+	defer c.schedMu.Unlock()
+
+	if c.pendingSyncerUpdates[key] == spec {
+		delete(c.pendingSyncerUpdates, key)
+	}
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"testing"
+
+	"arhat.dev/pkg/hashhelper"
+)
+
+func TestBuildTriggerSourceHash(t *testing.T) {
+	stringData := map[string]string{
+		"a": "1",
+		"b": "2",
+		"c": "3",
+		"d": "4",
+	}
+	binaryData := map[string][]byte{
+		"e": []byte("5"),
+		"f": []byte("6"),
+	}
+
+	allDataRef := createConfigRef(configKindCM, "default", "foo", "")
+	expectedAllDataHash := hashhelper.Sha256SumHex([]byte("123456"))
+
+	// hash of all data must not depend on map iteration order, otherwise workloads
+	// triggered by the whole object are reloaded without any change
+	for i := 0; i < 20; i++ {
+		hashes := buildTriggerSourceHash(configKindCM, "default", "foo", stringData, binaryData)
+		if len(hashes) != 7 {
+			t.Fatalf("unexpected hashes %v", hashes)
+		}
+
+		if h := hashes[allDataRef]; h != expectedAllDataHash {
+			t.Fatalf("unexpected hash of all data %q", h)
+		}
+
+		if h := hashes[createConfigRef(configKindCM, "default", "foo", "e")]; h != hashhelper.Sha256SumHex([]byte("5")) {
+			t.Fatalf("unexpected hash of data key %q", h)
+		}
+	}
+}
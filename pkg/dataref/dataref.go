@@ -0,0 +1,146 @@
+package dataref
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"arhat.dev/ksync/pkg/constant"
+)
+
+// ErrNotFound is returned (wrapped) by getters when the object or the data key does not exist
+var ErrNotFound = errors.New("not found")
+
+type Kind string
+
+const (
+	KindConfigMap Kind = "configmap"
+	KindSecret    Kind = "secret"
+)
+
+// Ref references a data key in configmap or secret
+type Ref struct {
+	Kind      Kind
+	Namespace string
+	Name      string
+	Key       string
+}
+
+func (r *Ref) String() string {
+	return string(r.Kind) + "://" + r.Namespace + "/" + r.Name + "/" + r.Key
+}
+
+// Parse data reference in the form of `{configmap|secret}://{ | <namespace>/}<name>/<key>`,
+// defaultNamespace is used when namespace is not specified
+func Parse(link, defaultNamespace string) (*Ref, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data reference: %w", err)
+	}
+
+	ref := new(Ref)
+
+	target := filepath.Clean(filepath.Join(u.Host, u.Path))
+	parts := strings.SplitN(target, "/", 3)
+
+	switch len(parts) {
+	case 2:
+		// <name>/<key>
+		ref.Namespace = defaultNamespace
+		ref.Name = parts[0]
+		ref.Key = parts[1]
+	case 3:
+		// <namespace>/<name>/<key>
+		ref.Namespace = parts[0]
+		ref.Name = parts[1]
+		ref.Key = parts[2]
+	default:
+		return nil, fmt.Errorf("invalid data reference %q", target)
+	}
+
+	switch Kind(u.Scheme) {
+	case KindConfigMap, KindSecret:
+		ref.Kind = Kind(u.Scheme)
+	default:
+		return nil, fmt.Errorf("unsupported data reference scheme %q", u.Scheme)
+	}
+
+	return ref, nil
+}
+
+// Getter gets referenced data
+type Getter interface {
+	Get(ctx context.Context, ref *Ref) ([]byte, error)
+}
+
+// Object is a configmap or secret
+type Object struct {
+	Annotations map[string]string
+	Data        map[string][]byte
+}
+
+// ObjectGetter gets whole configmap or secret, getter in context may implement it
+type ObjectGetter interface {
+	GetObject(ctx context.Context, kind Kind, namespace, name string) (*Object, error)
+}
+
+// WithGetter returns a context with getter, which can be retrieved by GetterFromContext
+func WithGetter(ctx context.Context, getter Getter) context.Context {
+	return context.WithValue(ctx, constant.ContextKeyDataGetter, getter)
+}
+
+// GetterFromContext returns nil if no getter set
+func GetterFromContext(ctx context.Context) Getter {
+	g, _ := ctx.Value(constant.ContextKeyDataGetter).(Getter)
+	return g
+}
+
+// ObjectGetterFromContext returns nil if getter in context is not an ObjectGetter
+func ObjectGetterFromContext(ctx context.Context) ObjectGetter {
+	g, _ := ctx.Value(constant.ContextKeyDataGetter).(ObjectGetter)
+	return g
+}
+
+// WithNamespace returns a context with namespace used as the default namespace of data references
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, constant.ContextKeyNamespace, namespace)
+}
+
+// NamespaceFromContext returns empty string if not set
+func NamespaceFromContext(ctx context.Context) string {
+	ns, _ := ctx.Value(constant.ContextKeyNamespace).(string)
+	return ns
+}
+
+// Resolve parses link with namespace in ctx and gets data using getter in ctx, only data in
+// the namespace in ctx can be referenced
+func Resolve(ctx context.Context, link string) ([]byte, error) {
+	namespace := NamespaceFromContext(ctx)
+	if namespace == "" {
+		return nil, fmt.Errorf("unable to resolve %q: no namespace", link)
+	}
+
+	ref, err := Parse(link, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref.Namespace != namespace {
+		return nil, fmt.Errorf("referencing data in namespace %q is not allowed, only %q", ref.Namespace, namespace)
+	}
+
+	getter := GetterFromContext(ctx)
+	if getter == nil {
+		return nil, fmt.Errorf("unable to get %s: no data getter available", ref.String())
+	}
+
+	data, err := getter.Get(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", ref.String(), err)
+	}
+
+	return data, nil
+}
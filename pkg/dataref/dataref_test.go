@@ -0,0 +1,62 @@
+package dataref
+
+import (
+	"context"
+	"testing"
+)
+
+type testGetter map[string]string
+
+func (g testGetter) Get(_ context.Context, ref *Ref) ([]byte, error) {
+	d, ok := g[ref.String()]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return []byte(d), nil
+}
+
+func TestResolve(t *testing.T) {
+	getter := testGetter{
+		"secret://default/foo/bar":     "default",
+		"secret://kube-system/foo/bar": "kube-system",
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		link      string
+		expected  string
+	}{
+		{name: "Default Namespace", namespace: "default", link: "secret://foo/bar", expected: "default"},
+		{name: "Same Namespace", namespace: "default", link: "secret://default/foo/bar", expected: "default"},
+		{name: "Other Namespace", namespace: "default", link: "secret://kube-system/foo/bar"},
+		{name: "Escape Namespace", namespace: "default", link: "secret://../kube-system/foo/bar"},
+		{name: "No Namespace", link: "secret://kube-system/foo/bar"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := WithGetter(context.TODO(), getter)
+			if test.namespace != "" {
+				ctx = WithNamespace(ctx, test.namespace)
+			}
+
+			data, err := Resolve(ctx, test.link)
+			if test.expected == "" {
+				if err == nil {
+					t.Errorf("expected error, got %q", data)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package fetcher
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Actions taken when required data keys are not buffered in time
+const (
+	// BatchTimeoutActionReport reports the stalled condition and keeps waiting (default)
+	BatchTimeoutActionReport = "report"
+
+	// BatchTimeoutActionFlush sends data buffered without required data keys
+	BatchTimeoutActionFlush = "flush"
+
+	// BatchTimeoutActionDrop drops data buffered
+	BatchTimeoutActionDrop = "drop"
+)
+
+// BatchConfig controls when buffered data is sent
+type BatchConfig struct {
+	// MaxWait for required data keys since data buffered, 0 to wait forever
+	MaxWait time.Duration `json:"maxWait" yaml:"maxWait"`
+
+	// OnTimeout is the action taken after MaxWait, one of report (default), flush, drop
+	OnTimeout string `json:"onTimeout" yaml:"onTimeout"`
+
+	// Debounce coalesces data received within the window into one batch
+	Debounce time.Duration `json:"debounce" yaml:"debounce"`
+
+	// MaxDebounce limits delay of the batch since first data received in the debounce window,
+	// so data keeps being sent when received faster than Debounce, defaults to 10 * Debounce
+	MaxDebounce time.Duration `json:"maxDebounce" yaml:"maxDebounce"`
+}
+
+func (c *BatchConfig) Validate() error {
+	switch c.OnTimeout {
+	case "", BatchTimeoutActionReport, BatchTimeoutActionFlush, BatchTimeoutActionDrop:
+	default:
+		return fmt.Errorf("unknown batch timeout action %q", c.OnTimeout)
+	}
+
+	if c.MaxWait < 0 || c.Debounce < 0 || c.MaxDebounce < 0 {
+		return fmt.Errorf("negative batch maxWait, debounce or maxDebounce")
+	}
+
+	return nil
+}
+
+// BatchHandler operates on data buffered by the owner of the batcher
+type BatchHandler struct {
+	// BufferedKeys returns keys of data buffered
+	BufferedKeys func() []string
+
+	// Send data buffered, called when all required data keys buffered or timed out with flush action
+	Send func()
+
+	// Flush sends all data buffered including data held for missing dependencies, called when
+	// timed out with flush action, optional (defaults to Send)
+	Flush func()
+
+	// Drop data buffered, called when timed out with drop action
+	Drop func()
+
+	// MissingDependencies returns data keys not available but depended on by data held after
+	// Send, optional
+	MissingDependencies func() []string
+
+	// OnTimeout is called with missing data keys and the action to be taken, optional
+	OnTimeout func(missing []string, action string)
+}
+
+// NewBatcher creates a batcher to decide when data buffered is ready to be sent
+func NewBatcher(requiredKeys []string, config BatchConfig) *Batcher {
+	if config.OnTimeout == "" {
+		config.OnTimeout = BatchTimeoutActionReport
+	}
+
+	if config.MaxDebounce == 0 {
+		config.MaxDebounce = 10 * config.Debounce
+	}
+
+	return &Batcher{
+		requiredKeys: requiredKeys,
+		config:       config,
+		signal:       make(chan struct{}, 1),
+		mu:           new(sync.RWMutex),
+	}
+}
+
+// Batcher waits for required data keys and coalesces data updates
+type Batcher struct {
+	requiredKeys []string
+	config       BatchConfig
+	signal       chan struct{}
+
+	// stalled are missing required data keys (or dependencies) reported
+	stalled []string
+	mu      *sync.RWMutex
+}
+
+// Notify the batcher data buffered, never blocks
+func (b *Batcher) Notify() {
+	select {
+	case b.signal <- struct{}{}:
+	default:
+	}
+}
+
+// StalledKeys returns missing required data keys (or dependencies) if stalled condition reported
+func (b *Batcher) StalledKeys() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return append([]string(nil), b.stalled...)
+}
+
+// Run until stopped by signal
+func (b *Batcher) Run(stop <-chan struct{}, h BatchHandler) {
+	var (
+		waitTimer     = newStoppedTimer()
+		debounceTimer = newStoppedTimer()
+
+		waitCh, debounceCh <-chan time.Time
+
+		// debounceDeadline is the latest time to end the current debounce window
+		debounceDeadline time.Time
+	)
+
+	defer func() {
+		waitTimer.Stop()
+		debounceTimer.Stop()
+	}()
+
+	stopWaiting := func() {
+		if waitCh != nil && !waitTimer.Stop() {
+			<-waitTimer.C
+		}
+
+		waitCh = nil
+	}
+
+	missingDependencies := func() []string {
+		if h.MissingDependencies == nil {
+			return nil
+		}
+
+		return h.MissingDependencies()
+	}
+
+	flush := h.Flush
+	if flush == nil {
+		flush = h.Send
+	}
+
+	check := func() {
+		missing, empty := b.missingKeys(h.BufferedKeys())
+		switch {
+		case empty:
+			stopWaiting()
+			return
+		case len(missing) == 0:
+			stopWaiting()
+			h.Send()
+			b.setStalled(nil)
+
+			// data held for dependencies is waited like required data keys
+			if len(missingDependencies()) == 0 {
+				return
+			}
+		}
+
+		if b.config.MaxWait > 0 && waitCh == nil && len(b.StalledKeys()) == 0 {
+			waitTimer.Reset(b.config.MaxWait)
+			waitCh = waitTimer.C
+		}
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-b.signal:
+			if b.config.Debounce > 0 {
+				if debounceCh == nil {
+					debounceDeadline = time.Now().Add(b.config.MaxDebounce)
+				} else if !debounceTimer.Stop() {
+					<-debounceTimer.C
+				}
+
+				delay := b.config.Debounce
+				if untilDeadline := time.Until(debounceDeadline); untilDeadline < delay {
+					delay = untilDeadline
+				}
+
+				debounceTimer.Reset(delay)
+				debounceCh = debounceTimer.C
+				continue
+			}
+
+			check()
+		case <-debounceCh:
+			debounceCh = nil
+			check()
+		case <-waitCh:
+			waitCh = nil
+
+			missing, empty := b.missingKeys(h.BufferedKeys())
+			if !empty && len(missing) == 0 {
+				missing = missingDependencies()
+			}
+
+			if empty || len(missing) == 0 {
+				check()
+				continue
+			}
+
+			if h.OnTimeout != nil {
+				h.OnTimeout(missing, b.config.OnTimeout)
+			}
+
+			switch b.config.OnTimeout {
+			case BatchTimeoutActionFlush:
+				flush()
+			case BatchTimeoutActionDrop:
+				h.Drop()
+			default:
+				b.setStalled(missing)
+			}
+		}
+	}
+}
+
+// missingKeys returns required data keys not buffered, and whether no data buffered
+func (b *Batcher) missingKeys(buffered []string) (missing []string, empty bool) {
+	if len(buffered) == 0 {
+		return nil, true
+	}
+
+	keys := make(map[string]struct{}, len(buffered))
+	for _, k := range buffered {
+		keys[k] = struct{}{}
+	}
+
+	for _, k := range b.requiredKeys {
+		if _, ok := keys[k]; !ok {
+			missing = append(missing, k)
+		}
+	}
+	sort.Strings(missing)
+
+	return missing, false
+}
+
+func (b *Batcher) setStalled(missing []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stalled = missing
+}
+
+func newStoppedTimer() *time.Timer {
+	t := time.NewTimer(time.Hour)
+	if !t.Stop() {
+		<-t.C
+	}
+
+	return t
+}
@@ -0,0 +1,204 @@
+package fetcher
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testBuffer struct {
+	mu    sync.Mutex
+	keys  []string
+	sent  int
+	drops int
+}
+
+func (b *testBuffer) handler(onTimeout func(missing []string, action string)) BatchHandler {
+	return BatchHandler{
+		BufferedKeys: func() []string {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			return append([]string(nil), b.keys...)
+		},
+		Send: func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			b.keys = nil
+			b.sent++
+		},
+		Drop: func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			b.keys = nil
+			b.drops++
+		},
+		OnTimeout: onTimeout,
+	}
+}
+
+func (b *testBuffer) add(keys ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.keys = append(b.keys, keys...)
+}
+
+func (b *testBuffer) counts() (sent, drops int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sent, b.drops
+}
+
+func TestBatcher(t *testing.T) {
+	tests := []struct {
+		name          string
+		action        string
+		expectedSent  int
+		expectedDrops int
+		expectStalled bool
+	}{
+		{name: "Flush", action: BatchTimeoutActionFlush, expectedSent: 1},
+		{name: "Drop", action: BatchTimeoutActionDrop, expectedDrops: 1},
+		{name: "Report", action: "", expectStalled: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stop := make(chan struct{})
+			defer close(stop)
+
+			var missing []string
+			timedOut := make(chan struct{})
+			buf := &testBuffer{}
+			b := NewBatcher([]string{"a", "b"}, BatchConfig{MaxWait: 20 * time.Millisecond, OnTimeout: test.action})
+			go b.Run(stop, buf.handler(func(m []string, action string) {
+				missing = m
+				close(timedOut)
+			}))
+
+			buf.add("a")
+			b.Notify()
+
+			select {
+			case <-timedOut:
+			case <-time.After(time.Second):
+				t.Fatal("timeout not fired")
+			}
+
+			if !reflect.DeepEqual(missing, []string{"b"}) {
+				t.Errorf("unexpected missing keys %v", missing)
+			}
+
+			// wait for action taken
+			time.Sleep(10 * time.Millisecond)
+
+			sent, drops := buf.counts()
+			if sent != test.expectedSent || drops != test.expectedDrops {
+				t.Errorf("unexpected sent %d and drops %d", sent, drops)
+			}
+
+			if stalled := len(b.StalledKeys()) != 0; stalled != test.expectStalled {
+				t.Errorf("unexpected stalled condition %v", stalled)
+			}
+		})
+	}
+}
+
+func TestBatcherDebounce(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+
+	buf := &testBuffer{}
+	b := NewBatcher(nil, BatchConfig{Debounce: 100 * time.Millisecond})
+	go b.Run(stop, buf.handler(nil))
+
+	for i := 0; i < 5; i++ {
+		buf.add("a")
+		b.Notify()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if sent, _ := buf.counts(); sent != 0 {
+		t.Errorf("expected no data sent within debounce window, got %d", sent)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	if sent, _ := buf.counts(); sent != 1 {
+		t.Errorf("expected data sent once, got %d", sent)
+	}
+}
+
+func TestBatcherMissingDependencies(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+
+	var (
+		mu      sync.Mutex
+		held    = []string{"a"}
+		flushed = make(chan struct{})
+		missing []string
+	)
+
+	h := BatchHandler{
+		BufferedKeys: func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), held...)
+		},
+		// data key a is held for its dependency
+		Send: func() {},
+		Flush: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			held = nil
+			close(flushed)
+		},
+		Drop: func() {},
+		MissingDependencies: func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(held) == 0 {
+				return nil
+			}
+			return []string{"dep"}
+		},
+		OnTimeout: func(m []string, action string) {
+			missing = m
+		},
+	}
+
+	b := NewBatcher(nil, BatchConfig{MaxWait: 20 * time.Millisecond, OnTimeout: BatchTimeoutActionFlush})
+	go b.Run(stop, h)
+	b.Notify()
+
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Fatal("data held for dependencies not flushed")
+	}
+
+	if !reflect.DeepEqual(missing, []string{"dep"}) {
+		t.Errorf("unexpected missing keys %v", missing)
+	}
+}
+
+func TestBatcherMaxDebounce(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+
+	buf := &testBuffer{}
+	b := NewBatcher(nil, BatchConfig{Debounce: 50 * time.Millisecond, MaxDebounce: 100 * time.Millisecond})
+	go b.Run(stop, buf.handler(nil))
+
+	// data received faster than debounce is still sent
+	for i := 0; i < 60; i++ {
+		buf.add("a")
+		b.Notify()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if sent, _ := buf.counts(); sent == 0 {
+		t.Errorf("expected data sent after max debounce")
+	}
+}
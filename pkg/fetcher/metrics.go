@@ -0,0 +1,28 @@
+package fetcher
+
+import (
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/metric"
+	"go.opentelemetry.io/otel/label"
+)
+
+var (
+	meter = metric.Must(global.Meter("arhat.dev/ksync/pkg/fetcher"))
+
+	connectedCounter = meter.NewInt64UpDownCounter("ksync_fetcher_connected",
+		metric.WithDescription("count of fetchers connected to their data source"))
+	reconnectCounter = meter.NewInt64Counter("ksync_fetcher_reconnects_total",
+		metric.WithDescription("count of fetcher reconnect attempts"))
+	networkErrorCounter = meter.NewInt64Counter("ksync_fetcher_network_errors_total",
+		metric.WithDescription("count of network errors happened in fetchers"))
+	messageCounter = meter.NewInt64Counter("ksync_fetcher_messages_total",
+		metric.WithDescription("count of messages received by fetchers"))
+	stalledCounter = meter.NewInt64Counter("ksync_fetcher_stalled_total",
+		metric.WithDescription("count of timeouts waiting for required data keys"))
+	publishedCounter = meter.NewInt64Counter("ksync_publisher_messages_total",
+		metric.WithDescription("count of messages published by publishers"))
+)
+
+func methodLabel(method string) label.KeyValue {
+	return label.String("method", method)
+}
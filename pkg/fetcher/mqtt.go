@@ -3,6 +3,7 @@ package fetcher
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -33,6 +34,9 @@ type MQTTConfig struct {
 	TLS tlshelper.TLSConfig `json:"tls" yaml:"tls"`
 
 	Subscriptions []MQTTSubscriptionConfig `json:"subscriptions" yaml:"subscriptions"`
+
+	// Publications are used by mqtt publisher only
+	Publications []MQTTPublicationConfig `json:"publications" yaml:"publications"`
 }
 
 type MQTTSubscriptionConfig struct {
@@ -46,11 +50,21 @@ type MQTTSubscriptionConfig struct {
 	DataKey string `json:"dataKey" yaml:"dataKey"`
 }
 
-func NewMQTTFetcher(ctx context.Context, logger log.Interface, config *Config) (Interface, error) {
-	options := []libmqtt.Option{
-		libmqtt.WithBackoffStrategy(time.Second, 10*time.Second, 1.5),
-	}
+type MQTTPublicationConfig struct {
+	// Topic to publish
+	Topic string `json:"topic" yaml:"topic"`
+
+	// QoS of published messages
+	QoS int `json:"qos" yaml:"qos"`
+
+	// Retain published messages in broker
+	Retain bool `json:"retain" yaml:"retain"`
+
+	// DataKey is the configmap/secret data key to publish
+	DataKey string `json:"dataKey" yaml:"dataKey"`
+}
 
+func NewMQTTFetcher(ctx context.Context, logger log.Interface, config *Config) (Interface, error) {
 	dataTopics := make(map[string]string)
 	var topics []*libmqtt.Topic
 	for _, s := range config.MQTT.Subscriptions {
@@ -66,7 +80,37 @@ func NewMQTTFetcher(ctx context.Context, logger log.Interface, config *Config) (
 		})
 	}
 
-	switch config.MQTT.Version {
+	client, err := newMQTTClient(&config.MQTT)
+	if err != nil {
+		return nil, err
+	}
+
+	mu := new(sync.RWMutex)
+	return &MQTTFetcher{
+		log:    logger,
+		broker: config.MQTT.Broker,
+		topics: topics,
+		client: client,
+
+		dataTopics: dataTopics,
+		dataBuf:    make(map[string][]byte),
+		dataCh:     make(chan map[string][]byte, 1),
+		mu:         mu,
+		batcher:    NewBatcher(config.RequiredDataKeys, config.Batch),
+		once:       new(sync.Once),
+
+		connErrCh: make(chan error),
+		subErrCh:  make(chan error),
+	}, nil
+}
+
+// newMQTTClient creates a mqtt client with connection settings in config
+func newMQTTClient(config *MQTTConfig) (libmqtt.Client, error) {
+	options := []libmqtt.Option{
+		libmqtt.WithBackoffStrategy(time.Second, 10*time.Second, 1.5),
+	}
+
+	switch config.Version {
 	case "5":
 		options = append(options, libmqtt.WithVersion(libmqtt.V5, false))
 	case "3.1.1":
@@ -75,7 +119,7 @@ func NewMQTTFetcher(ctx context.Context, logger log.Interface, config *Config) (
 		options = append(options, libmqtt.WithVersion(libmqtt.V311, false))
 	}
 
-	switch config.MQTT.Transport {
+	switch config.Transport {
 	case "websocket":
 		options = append(options, libmqtt.WithWebSocketConnector(0, nil))
 	case "tcp":
@@ -84,7 +128,7 @@ func NewMQTTFetcher(ctx context.Context, logger log.Interface, config *Config) (
 		options = append(options, libmqtt.WithTCPConnector(0))
 	}
 
-	keepalive := config.MQTT.KeepaliveInterval
+	keepalive := config.KeepaliveInterval
 	if keepalive == 0 {
 		// default to 60s
 		keepalive = 60 * time.Second
@@ -92,44 +136,22 @@ func NewMQTTFetcher(ctx context.Context, logger log.Interface, config *Config) (
 
 	options = append(options, libmqtt.WithConnPacket(libmqtt.ConnPacket{
 		CleanSession: true,
-		Username:     config.MQTT.Username,
-		Password:     config.MQTT.Password,
-		ClientID:     config.MQTT.ClientID,
+		Username:     config.Username,
+		Password:     config.Password,
+		ClientID:     config.ClientID,
 		Keepalive:    uint16(keepalive),
 	}))
 	options = append(options, libmqtt.WithKeepalive(uint16(float64(keepalive)/float64(time.Second)), 1.2))
 
-	if config.MQTT.TLS.Enabled {
-		tlsConfig, err := config.MQTT.TLS.GetTLSConfig(false)
+	if config.TLS.Enabled {
+		tlsConfig, err := config.TLS.GetTLSConfig(false)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load tls config: %w", err)
 		}
 		options = append(options, libmqtt.WithCustomTLS(tlsConfig))
 	}
 
-	client, err := libmqtt.NewClient(options...)
-	if err != nil {
-		return nil, err
-	}
-
-	mu := new(sync.RWMutex)
-	return &MQTTFetcher{
-		log:    logger,
-		broker: config.MQTT.Broker,
-		topics: topics,
-		client: client,
-
-		dataKeys:   config.RequiredDataKeys,
-		dataTopics: dataTopics,
-		dataBuf:    make(map[string][]byte),
-		dataCh:     make(chan map[string][]byte, 1),
-		mu:         mu,
-		cond:       sync.NewCond(new(sync.Mutex)),
-		once:       new(sync.Once),
-
-		connErrCh: make(chan error),
-		subErrCh:  make(chan error),
-	}, nil
+	return libmqtt.NewClient(options...)
 }
 
 var errAlreadySubscribing = fmt.Errorf("already subscribing")
@@ -140,16 +162,22 @@ type MQTTFetcher struct {
 	topics []*libmqtt.Topic
 	client libmqtt.Client
 
-	dataKeys   []string
 	dataTopics map[string]string
 	dataBuf    map[string][]byte
 	dataCh     chan map[string][]byte
 	mu         *sync.RWMutex
-	cond       *sync.Cond
+	batcher    *Batcher
 	once       *sync.Once
 
 	subscribing int32
 	started     int32
+	connected   int32
+	lastMsgAt   int64
+
+	// health of the connection
+	reconnects     int64
+	disconnectedAt int64
+	lastErr        atomic.Value
 
 	stopSig   <-chan struct{}
 	connErrCh chan error
@@ -159,8 +187,18 @@ type MQTTFetcher struct {
 // Connect to MQTT broker with connect packet
 func (c *MQTTFetcher) Start(stop <-chan struct{}) (err error) {
 	c.stopSig = stop
-
-	go c.handleDataUpdated()
+	atomic.StoreInt64(&c.disconnectedAt, time.Now().UnixNano())
+
+	go c.batcher.Run(stop, BatchHandler{
+		BufferedKeys: c.bufferedKeys,
+		Send:         c.sendData,
+		Drop:         c.dropData,
+		OnTimeout: func(missing []string, action string) {
+			stalledCounter.Add(context.Background(), 1, methodLabel(MethodMQTT))
+			c.log.I("required data keys not received in time",
+				log.Strings("missing", missing), log.String("action", action))
+		},
+	})
 
 	err = c.client.ConnectServer(c.broker,
 		libmqtt.WithRouter(libmqtt.NewTextRouter()),
@@ -202,63 +240,87 @@ func (c *MQTTFetcher) Retrieve() <-chan map[string][]byte {
 // Stop mqtt client
 func (c *MQTTFetcher) Stop() error {
 	c.client.Destroy(false)
+	c.setConnected(false)
 	return nil
 }
 
-func (c *MQTTFetcher) handleDataUpdated() {
-	stopped := func() bool {
-		select {
-		case <-c.stopSig:
-			return true
-		default:
-			return false
-		}
+func (c *MQTTFetcher) Status() Status {
+	var lastMsgAt time.Time
+	if ts := atomic.LoadInt64(&c.lastMsgAt); ts != 0 {
+		lastMsgAt = time.Unix(0, ts)
 	}
 
-	for !stopped() {
-		c.log.V("waiting to be signaled")
-
-		c.cond.L.Lock()
-		c.cond.Wait()
+	var disconnectedAt time.Time
+	if ts := atomic.LoadInt64(&c.disconnectedAt); ts != 0 {
+		disconnectedAt = time.Unix(0, ts)
+	}
 
-		c.log.V("signaled")
+	lastErr, _ := c.lastErr.Load().(string)
+
+	return Status{
+		Method:         MethodMQTT,
+		Connected:      atomic.LoadInt32(&c.connected) == 1,
+		LastMessageAt:  lastMsgAt,
+		BufferedKeys:   c.bufferedKeys(),
+		StalledKeys:    c.batcher.StalledKeys(),
+		DisconnectedAt: disconnectedAt,
+		LastError:      lastErr,
+		Reconnects:     atomic.LoadInt64(&c.reconnects),
+	}
+}
 
-		send := func() bool {
-			c.mu.RLock()
-			defer c.mu.RUnlock()
+func (c *MQTTFetcher) bufferedKeys() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
-			for _, k := range c.dataKeys {
-				if _, ok := c.dataBuf[k]; !ok {
-					// not all data key updated
-					c.log.V("data update didn't meet requirement")
-					return false
-				}
-			}
+	keys := make([]string, 0, len(c.dataBuf))
+	for k := range c.dataBuf {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 
-			return true
-		}()
+	return keys
+}
 
-		if send {
-			c.log.V("sending data update")
-			func() {
-				c.mu.Lock()
-				defer c.mu.Unlock()
-
-				d := c.dataBuf
-				select {
-				case c.dataCh <- d:
-					c.dataBuf = make(map[string][]byte)
-				case <-c.stopSig:
-					c.log.V("data update not sent due to exited")
-					return
-				}
-			}()
+// setConnected updates connection state and the connected metric
+func (c *MQTTFetcher) setConnected(connected bool) {
+	if connected {
+		if atomic.CompareAndSwapInt32(&c.connected, 0, 1) {
+			atomic.StoreInt64(&c.disconnectedAt, 0)
+			connectedCounter.Add(context.Background(), 1, methodLabel(MethodMQTT))
 		}
+	} else if atomic.CompareAndSwapInt32(&c.connected, 1, 0) {
+		atomic.StoreInt64(&c.disconnectedAt, time.Now().UnixNano())
+		connectedCounter.Add(context.Background(), -1, methodLabel(MethodMQTT))
+	}
+}
+
+// setLastError records the last connection error for status
+func (c *MQTTFetcher) setLastError(err error) {
+	c.lastErr.Store(err.Error())
+}
+
+func (c *MQTTFetcher) sendData() {
+	c.log.V("sending data update")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-		c.cond.L.Unlock()
+	select {
+	case c.dataCh <- c.dataBuf:
+		c.dataBuf = make(map[string][]byte)
+	case <-c.stopSig:
+		c.log.V("data update not sent due to exited")
 	}
 }
 
+func (c *MQTTFetcher) dropData() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dataBuf = make(map[string][]byte)
+}
+
 func (c *MQTTFetcher) handleTopicMsg(client libmqtt.Client, topic string, qos libmqtt.QosLevel, msgBytes []byte) {
 	c.log.V("received message", log.String("topic", topic))
 
@@ -268,17 +330,25 @@ func (c *MQTTFetcher) handleTopicMsg(client libmqtt.Client, topic string, qos li
 		return
 	}
 
+	messageCounter.Add(context.Background(), 1, methodLabel(MethodMQTT))
+	atomic.StoreInt64(&c.lastMsgAt, time.Now().UnixNano())
+
 	func() {
 		c.mu.Lock()
 		defer func() {
 			c.mu.Unlock()
 
 			c.log.V("signaling update check")
-			c.cond.Signal()
+			c.batcher.Notify()
 		}()
 
 		c.log.V("updating data buffer", log.String("topic", topic), log.String("dataKey", dataKey))
 
+		if msgBytes == nil {
+			// nil is reserved for removed keys
+			msgBytes = []byte{}
+		}
+
 		c.dataBuf[dataKey] = msgBytes
 	}()
 }
@@ -315,6 +385,10 @@ func (c *MQTTFetcher) subscribe() error {
 
 func (c *MQTTFetcher) handleNet(client libmqtt.Client, server string, err error) {
 	if err != nil {
+		c.setConnected(false)
+		c.setLastError(err)
+		networkErrorCounter.Add(context.Background(), 1, methodLabel(MethodMQTT))
+
 		if atomic.LoadInt32(&c.subscribing) == 1 && atomic.LoadInt32(&c.started) == 0 {
 			select {
 			case <-c.stopSig:
@@ -342,8 +416,14 @@ func (c *MQTTFetcher) handleNet(client libmqtt.Client, server string, err error)
 }
 
 func (c *MQTTFetcher) handleConn(client libmqtt.Client, server string, code byte, err error) {
+	if atomic.LoadInt32(&c.started) == 1 {
+		atomic.AddInt64(&c.reconnects, 1)
+		reconnectCounter.Add(context.Background(), 1, methodLabel(MethodMQTT))
+	}
+
 	// nolint:gocritic
 	if err != nil {
+		c.setLastError(err)
 		if atomic.CompareAndSwapInt32(&c.started, 0, 1) {
 			select {
 			case <-c.stopSig:
@@ -356,6 +436,7 @@ func (c *MQTTFetcher) handleConn(client libmqtt.Client, server string, code byte
 
 		c.log.I("failed to connect to broker", log.Uint8("code", code), log.Error(err))
 	} else if code != libmqtt.CodeSuccess {
+		c.setLastError(fmt.Errorf("rejected by mqtt broker, code: %d", code))
 		if atomic.CompareAndSwapInt32(&c.started, 0, 1) {
 			select {
 			case <-c.stopSig:
@@ -369,6 +450,7 @@ func (c *MQTTFetcher) handleConn(client libmqtt.Client, server string, code byte
 		c.log.I("reconnect rejected by broker", log.Uint8("code", code))
 	} else {
 		// connection success
+		c.setConnected(true)
 		if atomic.LoadInt32(&c.started) == 0 {
 			// client still in initial stage
 			// close connErrCh to signal connection success
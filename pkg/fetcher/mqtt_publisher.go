@@ -0,0 +1,195 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"arhat.dev/pkg/log"
+
+	"github.com/goiiot/libmqtt"
+)
+
+func init() {
+	RegisterPublisher(MethodMQTT, NewMQTTPublisher)
+}
+
+var errNotConnected = fmt.Errorf("not connected")
+
+func NewMQTTPublisher(ctx context.Context, logger log.Interface, config *PublisherConfig) (Publisher, error) {
+	if len(config.MQTT.Publications) == 0 {
+		return nil, fmt.Errorf("no publication configured")
+	}
+
+	for _, p := range config.MQTT.Publications {
+		if p.QoS > 2 || p.QoS < 0 {
+			return nil, fmt.Errorf("invalid qos level %d", p.QoS)
+		}
+
+		if p.Topic == "" || p.DataKey == "" {
+			return nil, fmt.Errorf("topic and dataKey are required for publication")
+		}
+	}
+
+	client, err := newMQTTClient(&config.MQTT)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MQTTPublisher{
+		log:          logger,
+		broker:       config.MQTT.Broker,
+		client:       client,
+		publications: config.MQTT.Publications,
+
+		connErrCh: make(chan error),
+	}, nil
+}
+
+type MQTTPublisher struct {
+	log          log.Interface
+	broker       string
+	client       libmqtt.Client
+	publications []MQTTPublicationConfig
+
+	started   int32
+	connected int32
+
+	stopSig   <-chan struct{}
+	connErrCh chan error
+}
+
+// Start connects to MQTT broker, reconnect is handled by the client
+func (p *MQTTPublisher) Start(stop <-chan struct{}) (err error) {
+	p.stopSig = stop
+
+	err = p.client.ConnectServer(p.broker,
+		libmqtt.WithRouter(libmqtt.NewTextRouter()),
+		libmqtt.WithAutoReconnect(true),
+		libmqtt.WithConnHandleFunc(p.handleConn),
+		libmqtt.WithPubHandleFunc(p.handlePub),
+		libmqtt.WithNetHandleFunc(p.handleNet),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = p.Stop()
+		}
+	}()
+
+	select {
+	case <-p.stopSig:
+		return context.Canceled
+	case err, more := <-p.connErrCh:
+		if !more {
+			return nil
+		}
+
+		return err
+	}
+}
+
+// Publish data keys configured to their topics
+func (p *MQTTPublisher) Publish(data map[string][]byte) error {
+	if atomic.LoadInt32(&p.connected) == 0 {
+		return errNotConnected
+	}
+
+	var msgs []*libmqtt.PublishPacket
+	for _, pub := range p.publications {
+		d, ok := data[pub.DataKey]
+		if !ok {
+			continue
+		}
+
+		msgs = append(msgs, &libmqtt.PublishPacket{
+			TopicName: pub.Topic,
+			Qos:       libmqtt.QosLevel(pub.QoS),
+			IsRetain:  pub.Retain,
+			Payload:   d,
+		})
+	}
+
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	p.client.Publish(msgs...)
+	publishedCounter.Add(context.Background(), int64(len(msgs)), methodLabel(MethodMQTT))
+
+	return nil
+}
+
+func (p *MQTTPublisher) DataKeys() []string {
+	keys := make([]string, 0, len(p.publications))
+	for _, pub := range p.publications {
+		keys = append(keys, pub.DataKey)
+	}
+
+	return keys
+}
+
+// Stop mqtt client
+func (p *MQTTPublisher) Stop() error {
+	p.client.Destroy(false)
+	atomic.StoreInt32(&p.connected, 0)
+	return nil
+}
+
+func (p *MQTTPublisher) handlePub(client libmqtt.Client, topic string, err error) {
+	if err != nil {
+		p.log.I("failed to publish", log.String("topic", topic), log.Error(err))
+	} else {
+		p.log.V("published", log.String("topic", topic))
+	}
+}
+
+func (p *MQTTPublisher) handleNet(client libmqtt.Client, server string, err error) {
+	if err == nil {
+		return
+	}
+
+	atomic.StoreInt32(&p.connected, 0)
+	networkErrorCounter.Add(context.Background(), 1, methodLabel(MethodMQTT))
+
+	if atomic.CompareAndSwapInt32(&p.started, 0, 1) {
+		select {
+		case <-p.stopSig:
+		case p.connErrCh <- err:
+			close(p.connErrCh)
+		}
+		return
+	}
+
+	p.log.I("network error happened", log.String("server", server), log.Error(err))
+}
+
+func (p *MQTTPublisher) handleConn(client libmqtt.Client, server string, code byte, err error) {
+	if err == nil && code != libmqtt.CodeSuccess {
+		err = fmt.Errorf("rejected by mqtt broker, code: %d", code)
+	}
+
+	if err != nil {
+		if atomic.CompareAndSwapInt32(&p.started, 0, 1) {
+			select {
+			case <-p.stopSig:
+			case p.connErrCh <- err:
+				close(p.connErrCh)
+			}
+			return
+		}
+
+		reconnectCounter.Add(context.Background(), 1, methodLabel(MethodMQTT))
+		p.log.I("failed to connect to broker", log.Uint8("code", code), log.Error(err))
+		return
+	}
+
+	atomic.StoreInt32(&p.connected, 1)
+	if atomic.CompareAndSwapInt32(&p.started, 0, 1) {
+		// signal connection success
+		close(p.connErrCh)
+	}
+}
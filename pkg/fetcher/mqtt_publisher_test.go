@@ -0,0 +1,179 @@
+package fetcher
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"arhat.dev/pkg/log"
+)
+
+// testMQTTBroker is an in-process mqtt 3.1.1 broker stand-in accepting all connections and
+// recording published messages
+type testMQTTBroker struct {
+	l    net.Listener
+	msgs chan testMQTTMessage
+}
+
+type testMQTTMessage struct {
+	topic   string
+	payload string
+	retain  bool
+}
+
+func newTestMQTTBroker(t *testing.T) *testMQTTBroker {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &testMQTTBroker{l: l, msgs: make(chan testMQTTMessage, 16)}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go b.serve(conn)
+		}
+	}()
+
+	return b
+}
+
+func (b *testMQTTBroker) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	r := bufio.NewReader(conn)
+	for {
+		header, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+
+		// remaining length
+		length, multiplier := 0, 1
+		for {
+			d, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+
+			length += int(d&0x7f) * multiplier
+			multiplier *= 128
+			if d&0x80 == 0 {
+				break
+			}
+		}
+
+		body := make([]byte, length)
+		if _, err = io.ReadFull(r, body); err != nil {
+			return
+		}
+
+		switch header >> 4 {
+		case 1: // CONNECT
+			_, _ = conn.Write([]byte{0x20, 2, 0, 0})
+		case 3: // PUBLISH
+			n := int(body[0])<<8 | int(body[1])
+			msg := testMQTTMessage{topic: string(body[2 : 2+n]), retain: header&1 == 1}
+
+			payload := body[2+n:]
+			if qos := header >> 1 & 3; qos > 0 {
+				_, _ = conn.Write([]byte{0x40, 2, payload[0], payload[1]})
+				payload = payload[2:]
+			}
+
+			msg.payload = string(payload)
+			b.msgs <- msg
+		case 12: // PINGREQ
+			_, _ = conn.Write([]byte{0xd0, 0})
+		case 14: // DISCONNECT
+			return
+		}
+	}
+}
+
+func TestMQTTPublisher(t *testing.T) {
+	b := newTestMQTTBroker(t)
+
+	p, err := NewMQTTPublisher(context.TODO(), log.Log.WithName("test"), &PublisherConfig{
+		Method: MethodMQTT,
+		MQTT: MQTTConfig{
+			Broker: b.l.Addr().String(),
+			Publications: []MQTTPublicationConfig{
+				{Topic: "config/app", DataKey: "app.yaml", Retain: true},
+				{Topic: "config/log", DataKey: "log.yaml", QoS: 1},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.Publish(map[string][]byte{"app.yaml": []byte("a")}); !errors.Is(err, errNotConnected) {
+		t.Errorf("expected not connected error, got %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	if err = p.Start(stop); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Stop() }()
+
+	if keys := p.DataKeys(); len(keys) != 2 || keys[0] != "app.yaml" || keys[1] != "log.yaml" {
+		t.Errorf("unexpected data keys %v", keys)
+	}
+
+	err = p.Publish(map[string][]byte{
+		"app.yaml":   []byte("foo: bar"),
+		"log.yaml":   []byte("level: debug"),
+		"other.yaml": []byte("not published"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]testMQTTMessage{
+		"config/app": {topic: "config/app", payload: "foo: bar", retain: true},
+		"config/log": {topic: "config/log", payload: "level: debug"},
+	}
+	timeout := time.After(5 * time.Second)
+	for len(expected) != 0 {
+		select {
+		case msg := <-b.msgs:
+			if msg != expected[msg.topic] {
+				t.Errorf("unexpected message %+v", msg)
+			}
+
+			delete(expected, msg.topic)
+		case <-timeout:
+			t.Fatalf("timeout waiting for messages %v", expected)
+		}
+	}
+}
+
+func TestNewMQTTPublisherInvalid(t *testing.T) {
+	for _, pubs := range [][]MQTTPublicationConfig{
+		nil,
+		{{Topic: "a", DataKey: "a", QoS: 3}},
+		{{Topic: "a"}},
+	} {
+		_, err := NewMQTTPublisher(context.TODO(), log.Log.WithName("test"), &PublisherConfig{
+			Method: MethodMQTT,
+			MQTT:   MQTTConfig{Broker: "127.0.0.1:1883", Publications: pubs},
+		})
+		if err == nil {
+			t.Errorf("expected error for publications %v", pubs)
+		}
+	}
+}
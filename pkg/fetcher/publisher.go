@@ -0,0 +1,56 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+
+	"arhat.dev/pkg/log"
+)
+
+type PublisherFactoryFunc func(context.Context, log.Interface, *PublisherConfig) (Publisher, error)
+
+var (
+	publishers = make(map[string]PublisherFactoryFunc)
+)
+
+func RegisterPublisher(name string, factory PublisherFactoryFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	publishers[name] = factory
+}
+
+// Publisher is the outbound side of fetcher, it publishes data of configmap/secret to
+// remote sources
+type Publisher interface {
+	// Start until stopped by signal
+	Start(stop <-chan struct{}) error
+
+	// Publish data to remote sources, data keys not configured are ignored
+	Publish(data map[string][]byte) error
+
+	// DataKeys configured to be published
+	DataKeys() []string
+
+	// Stop this publisher
+	Stop() error
+}
+
+type PublisherConfig struct {
+	Method string `json:"method" yaml:"method"`
+
+	// method specific configuration
+	MQTT MQTTConfig `json:"mqtt" yaml:"mqtt"`
+}
+
+func NewPublisher(ctx context.Context, logger log.Interface, config *PublisherConfig) (Publisher, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	create, ok := publishers[config.Method]
+	if !ok || create == nil {
+		return nil, fmt.Errorf("publisher %q not found", config.Method)
+	}
+
+	return create(ctx, logger, config)
+}
@@ -0,0 +1,810 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"arhat.dev/pkg/log"
+	"arhat.dev/pkg/tlshelper"
+
+	"arhat.dev/ksync/pkg/dataref"
+)
+
+func init() {
+	RegisterFetcher(MethodRedis, NewRedisFetcher)
+}
+
+const (
+	MethodRedis = "redis"
+)
+
+const (
+	redisDefaultDialTimeout  = 10 * time.Second
+	redisDefaultPingInterval = 30 * time.Second
+
+	// keys in the secret referenced by secretRef
+	redisSecretKeyUsername = "username"
+	redisSecretKeyPassword = "password"
+)
+
+type RedisConfig struct {
+	// Addr of the redis server (host:port), not used when sentinel is configured
+	Addr string `json:"addr" yaml:"addr"`
+
+	// DB to select, keyspace notifications are watched in this db
+	DB int `json:"db" yaml:"db"`
+
+	// Username for ACL auth (redis 6+), only password is used if empty
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+
+	// SecretRef is the name of the secret containing `username` (optional) and `password` in
+	// the namespace of the sync config, overrides username and password, read every time
+	// connecting to redis
+	SecretRef string `json:"secretRef" yaml:"secretRef"`
+
+	TLS tlshelper.TLSConfig `json:"tls" yaml:"tls"`
+
+	// Sentinel to discover the master
+	Sentinel RedisSentinelConfig `json:"sentinel" yaml:"sentinel"`
+
+	// DialTimeout of connections, defaults to 10s
+	DialTimeout time.Duration `json:"dialTimeout" yaml:"dialTimeout"`
+
+	// PingInterval to check connection health, defaults to 30s
+	PingInterval time.Duration `json:"pingInterval" yaml:"pingInterval"`
+
+	// Subscriptions to channels, payloads of messages are used as data
+	Subscriptions []RedisSubscriptionConfig `json:"subscriptions" yaml:"subscriptions"`
+
+	// Keys to watch with keyspace notifications (requires `notify-keyspace-events` with `K`
+	// and events of the key type enabled), current values are used as data
+	Keys []RedisKeyConfig `json:"keys" yaml:"keys"`
+}
+
+type RedisSentinelConfig struct {
+	// MasterName monitored by sentinels, sentinel is used only when set
+	MasterName string `json:"masterName" yaml:"masterName"`
+
+	// Addrs of sentinels (host:port), tried in order
+	Addrs []string `json:"addrs" yaml:"addrs"`
+
+	// Password of sentinels
+	Password string `json:"password" yaml:"password"`
+}
+
+type RedisSubscriptionConfig struct {
+	// Channel to subscribe
+	Channel string `json:"channel" yaml:"channel"`
+
+	// Pattern subscription (PSUBSCRIBE), channel is a glob-style pattern
+	Pattern bool `json:"pattern" yaml:"pattern"`
+
+	// DataKey will be the configmap/secret data key, defaults to the channel name of messages
+	// received
+	DataKey string `json:"dataKey" yaml:"dataKey"`
+}
+
+type RedisKeyConfig struct {
+	// Key to watch, glob-style pattern is supported
+	Key string `json:"key" yaml:"key"`
+
+	// DataKey will be the configmap/secret data key, defaults to the name of the redis key
+	DataKey string `json:"dataKey" yaml:"dataKey"`
+}
+
+func NewRedisFetcher(ctx context.Context, logger log.Interface, config *Config) (Interface, error) {
+	rc := config.Redis
+	if rc.Addr == "" && rc.Sentinel.MasterName == "" {
+		return nil, fmt.Errorf("no redis addr or sentinel master provided")
+	}
+
+	if rc.Sentinel.MasterName != "" && len(rc.Sentinel.Addrs) == 0 {
+		return nil, fmt.Errorf("no sentinel addrs provided")
+	}
+
+	if len(rc.Subscriptions) == 0 && len(rc.Keys) == 0 {
+		return nil, fmt.Errorf("no redis subscriptions or keys provided")
+	}
+
+	if strings.Contains(rc.SecretRef, "/") {
+		return nil, fmt.Errorf("invalid secret ref %q: only secret in the namespace of the sync config is allowed",
+			rc.SecretRef)
+	}
+
+	for _, s := range rc.Subscriptions {
+		if s.Channel == "" {
+			return nil, fmt.Errorf("empty redis channel")
+		}
+	}
+
+	for _, k := range rc.Keys {
+		if k.Key == "" {
+			return nil, fmt.Errorf("empty redis key")
+		}
+	}
+
+	tlsConfig, err := rc.TLS.GetTLSConfig(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tls config: %w", err)
+	}
+
+	dialTimeout := rc.DialTimeout
+	if dialTimeout == 0 {
+		dialTimeout = redisDefaultDialTimeout
+	}
+
+	pingInterval := rc.PingInterval
+	if pingInterval == 0 {
+		pingInterval = redisDefaultPingInterval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	return &RedisFetcher{
+		ctx:    ctx,
+		cancel: cancel,
+		log:    logger,
+
+		config:       &rc,
+		tlsConfig:    tlsConfig,
+		dialTimeout:  dialTimeout,
+		pingInterval: pingInterval,
+
+		dataBuf: make(map[string][]byte),
+		dataCh:  make(chan map[string][]byte, 1),
+		mu:      new(sync.RWMutex),
+		batcher: NewBatcher(config.RequiredDataKeys, config.Batch),
+	}, nil
+}
+
+// RedisFetcher receives messages from redis channels and values of redis keys
+type RedisFetcher struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	log    log.Interface
+
+	config       *RedisConfig
+	tlsConfig    *tls.Config
+	dialTimeout  time.Duration
+	pingInterval time.Duration
+
+	dataBuf map[string][]byte
+	dataCh  chan map[string][]byte
+	mu      *sync.RWMutex
+	batcher *Batcher
+
+	connected      int32
+	lastMsgAt      int64
+	reconnects     int64
+	disconnectedAt int64
+	lastErr        atomic.Value
+}
+
+// redisSession is a pair of connections to redis, one for subscriptions and one for commands
+type redisSession struct {
+	sub *respConn
+
+	cmd     *respConn
+	cmdMu   *sync.Mutex
+	timeout time.Duration
+}
+
+// do runs command with timeout
+func (s *redisSession) do(args ...string) (interface{}, error) {
+	s.cmdMu.Lock()
+	defer s.cmdMu.Unlock()
+
+	_ = s.cmd.conn.SetDeadline(time.Now().Add(s.timeout))
+	defer func() { _ = s.cmd.conn.SetDeadline(time.Time{}) }()
+
+	return s.cmd.do(args...)
+}
+
+func (s *redisSession) close() {
+	_ = s.sub.Close()
+	_ = s.cmd.Close()
+}
+
+// Start connects to redis and keeps reconnecting until stopped
+func (f *RedisFetcher) Start(stop <-chan struct{}) error {
+	atomic.StoreInt64(&f.disconnectedAt, time.Now().UnixNano())
+
+	go func() {
+		select {
+		case <-stop:
+		case <-f.ctx.Done():
+		}
+
+		f.cancel()
+	}()
+
+	go f.batcher.Run(f.ctx.Done(), BatchHandler{
+		BufferedKeys: f.bufferedKeys,
+		Send:         f.sendData,
+		Drop:         f.dropData,
+		OnTimeout: func(missing []string, action string) {
+			stalledCounter.Add(context.Background(), 1, methodLabel(MethodRedis))
+			f.log.I("required data keys not received in time",
+				log.Strings("missing", missing), log.String("action", action))
+		},
+	})
+
+	session, err := f.connect()
+	if err != nil {
+		f.cancel()
+		return err
+	}
+
+	go f.run(session)
+
+	return nil
+}
+
+func (f *RedisFetcher) Retrieve() <-chan map[string][]byte {
+	return f.dataCh
+}
+
+// Stop the fetcher, connections are closed
+func (f *RedisFetcher) Stop() error {
+	f.cancel()
+	f.setConnected(false)
+	return nil
+}
+
+func (f *RedisFetcher) Status() Status {
+	var lastMsgAt, disconnectedAt time.Time
+	if ts := atomic.LoadInt64(&f.lastMsgAt); ts != 0 {
+		lastMsgAt = time.Unix(0, ts)
+	}
+
+	if ts := atomic.LoadInt64(&f.disconnectedAt); ts != 0 {
+		disconnectedAt = time.Unix(0, ts)
+	}
+
+	lastErr, _ := f.lastErr.Load().(string)
+
+	return Status{
+		Method:         MethodRedis,
+		Connected:      atomic.LoadInt32(&f.connected) == 1,
+		LastMessageAt:  lastMsgAt,
+		BufferedKeys:   f.bufferedKeys(),
+		StalledKeys:    f.batcher.StalledKeys(),
+		DisconnectedAt: disconnectedAt,
+		LastError:      lastErr,
+		Reconnects:     atomic.LoadInt64(&f.reconnects),
+	}
+}
+
+// run serves the session, and reconnects with backoff when the session failed
+func (f *RedisFetcher) run(session *redisSession) {
+	// no more data will be sent after exited
+	defer func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		close(f.dataCh)
+	}()
+
+	backoff := time.Second
+	for {
+		if session != nil {
+			err := f.serve(session)
+			session.close()
+
+			select {
+			case <-f.ctx.Done():
+				return
+			default:
+			}
+
+			f.setConnected(false)
+			f.setLastError(err)
+			networkErrorCounter.Add(context.Background(), 1, methodLabel(MethodRedis))
+			f.log.I("redis connection lost", log.Error(err))
+		}
+
+		select {
+		case <-f.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		atomic.AddInt64(&f.reconnects, 1)
+		reconnectCounter.Add(context.Background(), 1, methodLabel(MethodRedis))
+
+		var err error
+		session, err = f.connect()
+		if err != nil {
+			f.setLastError(err)
+			f.log.I("failed to reconnect to redis", log.Error(err))
+
+			backoff = time.Duration(float64(backoff) * 1.5)
+			if backoff > 10*time.Second {
+				backoff = 10 * time.Second
+			}
+
+			continue
+		}
+
+		backoff = time.Second
+	}
+}
+
+// connect to redis, subscribe channels and load current values of keys
+func (f *RedisFetcher) connect() (_ *redisSession, err error) {
+	addr := f.config.Addr
+	if f.config.Sentinel.MasterName != "" {
+		addr, err = f.resolveMaster()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	username, password, err := f.credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &redisSession{cmdMu: new(sync.Mutex), timeout: f.dialTimeout}
+	session.sub, err = f.dial(addr, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	session.cmd, err = f.dial(addr, username, password)
+	if err != nil {
+		_ = session.sub.Close()
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			session.close()
+		}
+	}()
+
+	for _, s := range f.config.Subscriptions {
+		cmd := "SUBSCRIBE"
+		if s.Pattern {
+			cmd = "PSUBSCRIBE"
+		}
+
+		if err = session.sub.send(cmd, s.Channel); err != nil {
+			return nil, fmt.Errorf("failed to subscribe %q: %w", s.Channel, err)
+		}
+	}
+
+	for _, k := range f.config.Keys {
+		if err = session.sub.send("PSUBSCRIBE", f.keyspaceChannel(k.Key)); err != nil {
+			return nil, fmt.Errorf("failed to watch key %q: %w", k.Key, err)
+		}
+	}
+
+	// wait for confirmations of subscriptions, messages may arrive in the meantime
+	for confirmed := 0; confirmed < len(f.config.Subscriptions)+len(f.config.Keys); {
+		var reply interface{}
+		reply, err = session.sub.receive()
+		if err != nil {
+			return nil, fmt.Errorf("failed to subscribe: %w", err)
+		}
+
+		if e, ok := reply.(respError); ok {
+			err = e
+			return nil, fmt.Errorf("failed to subscribe: %w", err)
+		}
+
+		var handled bool
+		handled, err = f.dispatch(session, reply)
+		if err != nil {
+			return nil, err
+		}
+
+		if !handled {
+			confirmed++
+		}
+	}
+
+	// load values after subscribed, so no update is missed
+	if err = f.loadKeys(session); err != nil {
+		return nil, err
+	}
+
+	f.setConnected(true)
+
+	return session, nil
+}
+
+// dial redis and authenticate
+func (f *RedisFetcher) dial(addr, username, password string) (*respConn, error) {
+	ctx, cancel := context.WithTimeout(f.ctx, f.dialTimeout)
+	defer cancel()
+
+	conn, err := dialRESP(ctx, addr, f.tlsConfig, f.dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis %q: %w", addr, err)
+	}
+
+	_ = conn.conn.SetDeadline(time.Now().Add(f.dialTimeout))
+	defer func() { _ = conn.conn.SetDeadline(time.Time{}) }()
+
+	if password != "" {
+		args := []string{"AUTH", password}
+		if username != "" {
+			args = []string{"AUTH", username, password}
+		}
+
+		if _, err = conn.do(args...); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("failed to auth to redis: %w", err)
+		}
+	}
+
+	if f.config.DB != 0 {
+		if _, err = conn.do("SELECT", strconv.Itoa(f.config.DB)); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("failed to select db %d: %w", f.config.DB, err)
+		}
+	}
+
+	return conn, nil
+}
+
+// resolveMaster returns address of the master from the first sentinel available
+func (f *RedisFetcher) resolveMaster() (string, error) {
+	var lastErr error
+	for _, addr := range f.config.Sentinel.Addrs {
+		master, err := func() (string, error) {
+			ctx, cancel := context.WithTimeout(f.ctx, f.dialTimeout)
+			defer cancel()
+
+			conn, err := dialRESP(ctx, addr, f.tlsConfig, f.dialTimeout)
+			if err != nil {
+				return "", err
+			}
+			defer func() { _ = conn.Close() }()
+
+			_ = conn.conn.SetDeadline(time.Now().Add(f.dialTimeout))
+
+			if f.config.Sentinel.Password != "" {
+				if _, err = conn.do("AUTH", f.config.Sentinel.Password); err != nil {
+					return "", err
+				}
+			}
+
+			reply, err := conn.do("SENTINEL", "get-master-addr-by-name", f.config.Sentinel.MasterName)
+			if err != nil {
+				return "", err
+			}
+
+			parts, ok := reply.([]interface{})
+			if !ok || len(parts) != 2 {
+				return "", fmt.Errorf("master %q not found", f.config.Sentinel.MasterName)
+			}
+
+			host, _ := respString(parts[0])
+			port, _ := respString(parts[1])
+			return net.JoinHostPort(host, port), nil
+		}()
+		if err == nil {
+			return master, nil
+		}
+
+		f.log.D("failed to resolve master from sentinel", log.String("sentinel", addr), log.Error(err))
+		lastErr = err
+	}
+
+	return "", fmt.Errorf("failed to resolve master %q from sentinels: %w", f.config.Sentinel.MasterName, lastErr)
+}
+
+// credentials returns username and password, read from the referenced secret if set
+func (f *RedisFetcher) credentials() (username, password string, err error) {
+	if f.config.SecretRef == "" {
+		return f.config.Username, f.config.Password, nil
+	}
+
+	ref := fmt.Sprintf("%s://%s/", dataref.KindSecret, f.config.SecretRef)
+	data, err := dataref.Resolve(f.ctx, ref+redisSecretKeyPassword)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get redis password: %w", err)
+	}
+	password = strings.TrimSpace(string(data))
+
+	data, err = dataref.Resolve(f.ctx, ref+redisSecretKeyUsername)
+	if err == nil {
+		username = strings.TrimSpace(string(data))
+	}
+
+	return username, password, nil
+}
+
+func (f *RedisFetcher) keyspaceChannel(key string) string {
+	return fmt.Sprintf("__keyspace@%d__:%s", f.config.DB, key)
+}
+
+// loadKeys gets current values of watched keys
+func (f *RedisFetcher) loadKeys(session *redisSession) error {
+	for i, k := range f.config.Keys {
+		keys := []string{k.Key}
+		if isGlobPattern(k.Key) {
+			var err error
+			keys, err = scanKeys(session, k.Key)
+			if err != nil {
+				return fmt.Errorf("failed to scan keys %q: %w", k.Key, err)
+			}
+		}
+
+		for _, key := range keys {
+			if err := f.loadKey(session, &f.config.Keys[i], key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadKey gets value of key and buffers it as data, missing key and key not holding a string
+// value are ignored
+func (f *RedisFetcher) loadKey(session *redisSession, config *RedisKeyConfig, key string) error {
+	reply, err := session.do("GET", key)
+	if err != nil {
+		var respErr respError
+		if errors.As(err, &respErr) && strings.HasPrefix(string(respErr), "WRONGTYPE") {
+			f.log.I("redis key ignored", log.String("key", key), log.Error(err))
+			return nil
+		}
+
+		return fmt.Errorf("failed to get key %q: %w", key, err)
+	}
+
+	value, ok := reply.([]byte)
+	if !ok || value == nil {
+		f.log.V("redis key not found", log.String("key", key))
+		return nil
+	}
+
+	dataKey := config.DataKey
+	if dataKey == "" {
+		dataKey = key
+	}
+
+	f.handleData(dataKey, value)
+	return nil
+}
+
+func scanKeys(session *redisSession, pattern string) ([]string, error) {
+	var (
+		keys   []string
+		cursor = "0"
+	)
+	for {
+		reply, err := session.do("SCAN", cursor, "MATCH", pattern, "COUNT", "100")
+		if err != nil {
+			return nil, err
+		}
+
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return nil, errInvalidReply
+		}
+
+		cursor, _ = respString(parts[0])
+		items, _ := parts[1].([]interface{})
+		for _, item := range items {
+			if key, ok := respString(item); ok {
+				keys = append(keys, key)
+			}
+		}
+
+		if cursor == "0" || cursor == "" {
+			return keys, nil
+		}
+	}
+}
+
+// serve messages received until connection failed or fetcher stopped
+func (f *RedisFetcher) serve(session *redisSession) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	// close connections to unblock read when stopped, check connection health with ping
+	go func() {
+		ticker := time.NewTicker(f.pingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-f.ctx.Done():
+				session.close()
+				return
+			case <-ticker.C:
+				if _, err := session.do("PING"); err != nil {
+					f.log.I("redis ping failed", log.Error(err))
+					session.close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		reply, err := session.sub.receive()
+		if err != nil {
+			return err
+		}
+
+		if _, err = f.dispatch(session, reply); err != nil {
+			return err
+		}
+	}
+}
+
+// dispatch reply received in subscribed state, returns true if it is a message
+func (f *RedisFetcher) dispatch(session *redisSession, reply interface{}) (bool, error) {
+	msg, ok := reply.([]interface{})
+	if !ok || len(msg) < 3 {
+		return false, nil
+	}
+
+	kind, _ := respString(msg[0])
+	switch kind {
+	case "message":
+		channel, _ := respString(msg[1])
+		payload, _ := msg[2].([]byte)
+
+		return true, f.handleMessage(session, "", channel, payload)
+	case "pmessage":
+		if len(msg) < 4 {
+			return true, nil
+		}
+
+		pattern, _ := respString(msg[1])
+		channel, _ := respString(msg[2])
+		payload, _ := msg[3].([]byte)
+
+		return true, f.handleMessage(session, pattern, channel, payload)
+	default:
+		return false, nil
+	}
+}
+
+// handleMessage maps message of channel (matched pattern if psubscribed) to data key, keyspace
+// events are resolved to current value of the key
+func (f *RedisFetcher) handleMessage(session *redisSession, pattern, channel string, payload []byte) error {
+	f.log.V("received message", log.String("channel", channel))
+
+	for _, s := range f.config.Subscriptions {
+		if s.Pattern && s.Channel != pattern || !s.Pattern && s.Channel != channel {
+			continue
+		}
+
+		dataKey := s.DataKey
+		if dataKey == "" {
+			dataKey = channel
+		}
+
+		if payload == nil {
+			// nil is reserved for removed keys
+			payload = []byte{}
+		}
+
+		f.handleData(dataKey, payload)
+		return nil
+	}
+
+	prefix := f.keyspaceChannel("")
+	for i, k := range f.config.Keys {
+		if f.keyspaceChannel(k.Key) != pattern {
+			continue
+		}
+
+		key := strings.TrimPrefix(channel, prefix)
+		switch event := string(payload); event {
+		case "del", "expired", "evicted":
+			f.log.I("watched redis key removed", log.String("key", key), log.String("event", event))
+
+			dataKey := f.config.Keys[i].DataKey
+			if dataKey == "" {
+				dataKey = key
+			}
+
+			f.handleData(dataKey, nil)
+			return nil
+		}
+
+		return f.loadKey(session, &f.config.Keys[i], key)
+	}
+
+	f.log.D("message ignored", log.String("channel", channel))
+	return nil
+}
+
+func (f *RedisFetcher) handleData(dataKey string, data []byte) {
+	messageCounter.Add(context.Background(), 1, methodLabel(MethodRedis))
+	atomic.StoreInt64(&f.lastMsgAt, time.Now().UnixNano())
+
+	func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		f.log.V("updating data buffer", log.String("dataKey", dataKey))
+		f.dataBuf[dataKey] = data
+	}()
+
+	f.batcher.Notify()
+}
+
+func (f *RedisFetcher) bufferedKeys() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	keys := make([]string, 0, len(f.dataBuf))
+	for k := range f.dataBuf {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (f *RedisFetcher) sendData() {
+	f.log.V("sending data update")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	select {
+	case <-f.ctx.Done():
+		// data channel closed
+		return
+	default:
+	}
+
+	select {
+	case f.dataCh <- f.dataBuf:
+		f.dataBuf = make(map[string][]byte)
+	case <-f.ctx.Done():
+		f.log.V("data update not sent due to exited")
+	}
+}
+
+func (f *RedisFetcher) dropData() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.dataBuf = make(map[string][]byte)
+}
+
+// setConnected updates connection state and the connected metric
+func (f *RedisFetcher) setConnected(connected bool) {
+	if connected {
+		if atomic.CompareAndSwapInt32(&f.connected, 0, 1) {
+			atomic.StoreInt64(&f.disconnectedAt, 0)
+			connectedCounter.Add(context.Background(), 1, methodLabel(MethodRedis))
+		}
+	} else if atomic.CompareAndSwapInt32(&f.connected, 1, 0) {
+		atomic.StoreInt64(&f.disconnectedAt, time.Now().UnixNano())
+		connectedCounter.Add(context.Background(), -1, methodLabel(MethodRedis))
+	}
+}
+
+// setLastError records the last connection error for status
+func (f *RedisFetcher) setLastError(err error) {
+	f.lastErr.Store(err.Error())
+}
+
+// isGlobPattern returns true if key contains glob-style special characters
+func isGlobPattern(key string) bool {
+	return strings.ContainsAny(key, "*?[")
+}
@@ -0,0 +1,509 @@
+package fetcher
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/dataref"
+)
+
+// testRedis is an in-process redis stand-in supporting commands used by the redis fetcher
+type testRedis struct {
+	l net.Listener
+
+	username string
+	password string
+
+	// masters for sentinel
+	masters map[string]string
+
+	data  map[string]string
+	lists map[string]struct{}
+	conns map[*testRedisConn]struct{}
+	mu    sync.Mutex
+}
+
+type testRedisConn struct {
+	*respConn
+
+	authed   bool
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	writeMu  sync.Mutex
+}
+
+func (c *testRedisConn) write(s string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, _ = c.conn.Write([]byte(s))
+}
+
+func bulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+func array(items ...string) string {
+	return "*" + strconv.Itoa(len(items)) + "\r\n" + strings.Join(items, "")
+}
+
+func newTestRedis(t *testing.T) *testRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &testRedis{
+		l:       l,
+		masters: make(map[string]string),
+		data:    make(map[string]string),
+		lists:   make(map[string]struct{}),
+		conns:   make(map[*testRedisConn]struct{}),
+	}
+	t.Cleanup(func() {
+		_ = l.Close()
+		r.dropConns()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go r.serve(conn)
+		}
+	}()
+
+	return r
+}
+
+func (r *testRedis) addr() string {
+	return r.l.Addr().String()
+}
+
+func (r *testRedis) serve(conn net.Conn) {
+	c := &testRedisConn{
+		respConn: &respConn{conn: conn, r: bufio.NewReader(conn)},
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+	}
+
+	func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.conns[c] = struct{}{}
+	}()
+
+	defer func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		delete(r.conns, c)
+		_ = conn.Close()
+	}()
+
+	for {
+		req, err := c.receive()
+		if err != nil {
+			return
+		}
+
+		items, _ := req.([]interface{})
+		var args []string
+		for _, item := range items {
+			s, _ := respString(item)
+			args = append(args, s)
+		}
+
+		if len(args) == 0 {
+			c.write("-ERR empty command\r\n")
+			continue
+		}
+
+		c.write(r.handle(c, strings.ToUpper(args[0]), args[1:]))
+	}
+}
+
+func (r *testRedis) handle(c *testRedisConn, cmd string, args []string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cmd == "AUTH" {
+		username, password := "", args[len(args)-1]
+		if len(args) == 2 {
+			username = args[0]
+		}
+
+		if username != r.username || password != r.password {
+			return "-WRONGPASS invalid username-password pair\r\n"
+		}
+
+		c.authed = true
+		return "+OK\r\n"
+	}
+
+	if r.password != "" && !c.authed {
+		return "-NOAUTH Authentication required.\r\n"
+	}
+
+	switch cmd {
+	case "SELECT":
+		return "+OK\r\n"
+	case "PING":
+		if len(c.channels)+len(c.patterns) != 0 {
+			return array(bulk("pong"), bulk(""))
+		}
+		return "+PONG\r\n"
+	case "GET":
+		if _, ok := r.lists[args[0]]; ok {
+			return "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+		}
+
+		v, ok := r.data[args[0]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(v)
+	case "SCAN":
+		var keys []string
+		for k := range r.data {
+			if ok, _ := path.Match(args[2], k); ok {
+				keys = append(keys, bulk(k))
+			}
+		}
+		for k := range r.lists {
+			if ok, _ := path.Match(args[2], k); ok {
+				keys = append(keys, bulk(k))
+			}
+		}
+		return array(bulk("0"), array(keys...))
+	case "SUBSCRIBE":
+		c.channels[args[0]] = struct{}{}
+		return array(bulk("subscribe"), bulk(args[0]), ":1\r\n")
+	case "PSUBSCRIBE":
+		c.patterns[args[0]] = struct{}{}
+		return array(bulk("psubscribe"), bulk(args[0]), ":1\r\n")
+	case "SENTINEL":
+		addr, ok := r.masters[args[1]]
+		if !ok {
+			return "*-1\r\n"
+		}
+
+		host, port, _ := net.SplitHostPort(addr)
+		return array(bulk(host), bulk(port))
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", cmd)
+	}
+}
+
+// publish message to subscribers
+func (r *testRedis) publish(channel, payload string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.publishLocked(channel, payload)
+}
+
+func (r *testRedis) publishLocked(channel, payload string) {
+	for c := range r.conns {
+		if _, ok := c.channels[channel]; ok {
+			c.write(array(bulk("message"), bulk(channel), bulk(payload)))
+		}
+
+		for p := range c.patterns {
+			if ok, _ := path.Match(p, channel); ok {
+				c.write(array(bulk("pmessage"), bulk(p), bulk(channel), bulk(payload)))
+			}
+		}
+	}
+}
+
+// set key and send keyspace notification
+func (r *testRedis) set(key, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.data[key] = value
+	r.publishLocked("__keyspace@0__:"+key, "set")
+}
+
+// remove key and send keyspace notification of event
+func (r *testRedis) remove(key, event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.data, key)
+	r.publishLocked("__keyspace@0__:"+key, event)
+}
+
+func (r *testRedis) dropConns() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for c := range r.conns {
+		_ = c.conn.Close()
+	}
+}
+
+type testDataGetter map[string]string
+
+func (g testDataGetter) Get(_ context.Context, ref *dataref.Ref) ([]byte, error) {
+	d, ok := g[ref.String()]
+	if !ok {
+		return nil, dataref.ErrNotFound
+	}
+
+	return []byte(d), nil
+}
+
+func newTestRedisFetcher(t *testing.T, ctx context.Context, config *RedisConfig) Interface {
+	f, err := NewRedisFetcher(ctx, log.Log.WithName("test"), &Config{
+		Method: MethodRedis,
+		Redis:  *config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	t.Cleanup(func() {
+		close(stop)
+		_ = f.Stop()
+	})
+
+	if err = f.Start(stop); err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+// waitData collects data from fetcher until all expected data received
+func waitData(t *testing.T, f Interface, expected map[string]string) {
+	received := make(map[string]string)
+	timeout := time.After(5 * time.Second)
+	for {
+		for k, v := range expected {
+			if received[k] != v {
+				goto wait
+			}
+		}
+		return
+
+	wait:
+		select {
+		case data, more := <-f.Retrieve():
+			if !more {
+				t.Fatal("data channel closed")
+			}
+
+			for k, v := range data {
+				received[k] = string(v)
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for %v, received %v", expected, received)
+		}
+	}
+}
+
+func TestRedisFetcherSubscriptions(t *testing.T) {
+	r := newTestRedis(t)
+	r.username, r.password = "ksync", "secret"
+
+	ctx := dataref.WithNamespace(dataref.WithGetter(context.TODO(), testDataGetter{
+		"secret://default/redis-auth/username": "ksync",
+		"secret://default/redis-auth/password": "secret\n",
+	}), "default")
+
+	f := newTestRedisFetcher(t, ctx, &RedisConfig{
+		Addr:      r.addr(),
+		SecretRef: "redis-auth",
+		Subscriptions: []RedisSubscriptionConfig{
+			{Channel: "app", DataKey: "app.yaml"},
+			{Channel: "settings.*", Pattern: true},
+		},
+	})
+
+	r.publish("app", "foo: bar")
+	r.publish("settings.log", "debug")
+	r.publish("ignored", "nothing")
+
+	waitData(t, f, map[string]string{
+		"app.yaml":     "foo: bar",
+		"settings.log": "debug",
+	})
+
+	if s := f.Status(); !s.Connected || s.LastMessageAt.IsZero() {
+		t.Errorf("unexpected status %+v", s)
+	}
+}
+
+func TestRedisFetcherAuthFailure(t *testing.T) {
+	r := newTestRedis(t)
+	r.password = "secret"
+
+	f, err := NewRedisFetcher(context.TODO(), log.Log.WithName("test"), &Config{
+		Method: MethodRedis,
+		Redis: RedisConfig{
+			Addr:          r.addr(),
+			Password:      "wrong",
+			Subscriptions: []RedisSubscriptionConfig{{Channel: "app"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	if err = f.Start(stop); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("expected auth error, got %v", err)
+	}
+}
+
+func TestRedisFetcherKeys(t *testing.T) {
+	r := newTestRedis(t)
+	r.data["feature.a"] = "on"
+	r.data["exact"] = "v1"
+	// keys not holding strings are skipped
+	r.lists["feature.list"] = struct{}{}
+
+	f := newTestRedisFetcher(t, context.TODO(), &RedisConfig{
+		Addr: r.addr(),
+		Keys: []RedisKeyConfig{
+			{Key: "feature.*"},
+			{Key: "exact", DataKey: "exact.txt"},
+		},
+	})
+
+	// current values loaded on start
+	waitData(t, f, map[string]string{
+		"feature.a": "on",
+		"exact.txt": "v1",
+	})
+
+	r.set("exact", "v2")
+	r.set("feature.b", "off")
+
+	waitData(t, f, map[string]string{
+		"feature.b": "off",
+		"exact.txt": "v2",
+	})
+
+	// removed keys are sent as nil
+	r.remove("exact", "del")
+	r.remove("feature.a", "expired")
+
+	removed := make(map[string]struct{})
+	timeout := time.After(5 * time.Second)
+	for len(removed) != 2 {
+		select {
+		case data := <-f.Retrieve():
+			for k, v := range data {
+				if v != nil {
+					t.Errorf("unexpected data for key %q: %q", k, v)
+				}
+
+				removed[k] = struct{}{}
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for removed keys, received %v", removed)
+		}
+	}
+
+	for _, k := range []string{"exact.txt", "feature.a"} {
+		if _, ok := removed[k]; !ok {
+			t.Errorf("key %q not removed", k)
+		}
+	}
+}
+
+func TestRedisFetcherSentinelReconnect(t *testing.T) {
+	master := newTestRedis(t)
+	sentinel := newTestRedis(t)
+	sentinel.masters["mymaster"] = master.addr()
+
+	f := newTestRedisFetcher(t, context.TODO(), &RedisConfig{
+		Sentinel: RedisSentinelConfig{
+			MasterName: "mymaster",
+			// unavailable sentinel is skipped
+			Addrs: []string{"127.0.0.1:1", sentinel.addr()},
+		},
+		DialTimeout:   time.Second,
+		Subscriptions: []RedisSubscriptionConfig{{Channel: "app"}},
+	})
+
+	master.publish("app", "v1")
+	waitData(t, f, map[string]string{"app": "v1"})
+
+	master.dropConns()
+
+	// wait for resubscription after reconnected
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s := f.Status()
+		if s.Connected && s.Reconnects > 0 {
+			if s.LastError == "" {
+				t.Error("expected last error recorded")
+			}
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("not reconnected, status %+v", s)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	master.publish("app", "v2")
+	waitData(t, f, map[string]string{"app": "v2"})
+}
+
+func TestRedisFetcherSecretRef(t *testing.T) {
+	_, err := NewRedisFetcher(context.TODO(), log.Log.WithName("test"), &Config{
+		Method: MethodRedis,
+		Redis: RedisConfig{
+			Addr:          "127.0.0.1:6379",
+			SecretRef:     "kube-system/redis-auth",
+			Subscriptions: []RedisSubscriptionConfig{{Channel: "app"}},
+		},
+	})
+	if err == nil {
+		t.Error("expected secret ref in other namespace rejected")
+	}
+}
+
+func TestRESPLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+	}{
+		{name: "Bulk String", reply: "$" + strconv.Itoa(respMaxBulkLen+1) + "\r\n"},
+		{name: "Array", reply: "*" + strconv.Itoa(respMaxArrayLen+1) + "\r\n"},
+		{name: "Truncated Array", reply: "*1000000\r\n:1\r\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &respConn{r: bufio.NewReader(strings.NewReader(test.reply))}
+			if _, err := c.receive(); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
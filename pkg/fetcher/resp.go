@@ -0,0 +1,207 @@
+package fetcher
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// respError is the error reply of redis
+type respError string
+
+func (e respError) Error() string {
+	return string(e)
+}
+
+var errInvalidReply = errors.New("invalid redis reply")
+
+// limits of replies, data larger than respMaxBulkLen can not be stored in a configmap or
+// secret anyway
+const (
+	respMaxBulkLen  = 8 << 20
+	respMaxArrayLen = 1 << 20
+)
+
+// respConn is a minimal redis client connection speaking RESP2, replies are one of
+//
+//	string (simple string), int64 (integer), []byte (bulk string, nil if null),
+//	[]interface{} (array, nil if null), respError (error)
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+func dialRESP(ctx context.Context, addr string, tlsConfig *tls.Config, timeout time.Duration) (*respConn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if tlsConfig != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &respConn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+	}, nil
+}
+
+func (c *respConn) Close() error {
+	return c.conn.Close()
+}
+
+// do sends command and reads its reply, error reply is returned as error
+func (c *respConn) do(args ...string) (interface{}, error) {
+	if err := c.send(args...); err != nil {
+		return nil, err
+	}
+
+	reply, err := c.receive()
+	if err != nil {
+		return nil, err
+	}
+
+	if e, ok := reply.(respError); ok {
+		return nil, e
+	}
+
+	return reply, nil
+}
+
+// send command as array of bulk strings
+func (c *respConn) send(args ...string) error {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+
+	if _, err := c.w.Write(buf); err != nil {
+		return err
+	}
+
+	return c.w.Flush()
+}
+
+// receive a reply
+func (c *respConn) receive() (interface{}, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, errInvalidReply
+	}
+
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return respError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n < -1 {
+			return nil, errInvalidReply
+		}
+
+		if n > respMaxBulkLen {
+			return nil, fmt.Errorf("%w: bulk string too large (%d bytes)", errInvalidReply, n)
+		}
+
+		if n == -1 {
+			return []byte(nil), nil
+		}
+
+		data := make([]byte, n+2)
+		if _, err = io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n < -1 {
+			return nil, errInvalidReply
+		}
+
+		if n > respMaxArrayLen {
+			return nil, fmt.Errorf("%w: array too large (%d items)", errInvalidReply, n)
+		}
+
+		if n == -1 {
+			return []interface{}(nil), nil
+		}
+
+		// grow with items received instead of trusting the length
+		ret := make([]interface{}, 0, minInt(n, 1024))
+		for i := 0; i < n; i++ {
+			item, err := c.receive()
+			if err != nil {
+				return nil, err
+			}
+
+			ret = append(ret, item)
+		}
+
+		return ret, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", errInvalidReply, line[0])
+	}
+}
+
+func (c *respConn) readLine() ([]byte, error) {
+	line, err := c.r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, errInvalidReply
+	}
+
+	return line[:len(line)-2], nil
+}
+
+// respString converts simple string or bulk string reply to string
+func respString(reply interface{}) (string, bool) {
+	switch r := reply.(type) {
+	case string:
+		return r, true
+	case []byte:
+		return string(r), r != nil
+	default:
+		return "", false
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
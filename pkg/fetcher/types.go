@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"arhat.dev/pkg/log"
 )
@@ -22,15 +23,62 @@ func RegisterFetcher(name string, factory FactoryFunc) {
 	fetchers[name] = factory
 }
 
+// UnregisterFetcher removes the fetcher registered with name, fetchers created are not affected
+func UnregisterFetcher(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	delete(fetchers, name)
+}
+
+// HasFetcher returns true if a fetcher is registered with name
+func HasFetcher(name string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	_, ok := fetchers[name]
+	return ok
+}
+
 type Interface interface {
 	// Start until stopped by signal
 	Start(stop <-chan struct{}) error
 
-	// Retrieve data from remote sources
+	// Retrieve data from remote sources, a nil value means the data key was removed upstream
 	Retrieve() <-chan map[string][]byte
 
 	// Stop this fetcher
 	Stop() error
+
+	// Status of this fetcher
+	Status() Status
+}
+
+// Status of a fetcher
+type Status struct {
+	Method string `json:"method"`
+
+	// Connected to the remote source
+	Connected bool `json:"connected"`
+
+	// LastMessageAt is the time when last message received, zero if none
+	LastMessageAt time.Time `json:"lastMessageAt,omitempty"`
+
+	// BufferedKeys are data keys received but not sent
+	BufferedKeys []string `json:"bufferedKeys"`
+
+	// StalledKeys are required data keys not received in time
+	StalledKeys []string `json:"stalledKeys,omitempty"`
+
+	// DisconnectedAt is the time when connection to the remote source lost (or the fetcher
+	// started if never connected), zero if connected
+	DisconnectedAt time.Time `json:"disconnectedAt,omitempty"`
+
+	// LastError is the last connection error
+	LastError string `json:"lastError,omitempty"`
+
+	// Reconnects is the count of reconnection attempts
+	Reconnects int64 `json:"reconnects"`
 }
 
 type Config struct {
@@ -38,8 +86,15 @@ type Config struct {
 
 	RequiredDataKeys []string `json:"requiredDataKeys" yaml:"requiredDataKeys"`
 
+	// Batch controls when data received is sent
+	Batch BatchConfig `json:"batch" yaml:"batch"`
+
 	// method specific configuration
-	MQTT MQTTConfig `json:"mqtt" yaml:"mqtt"`
+	MQTT  MQTTConfig  `json:"mqtt" yaml:"mqtt"`
+	Redis RedisConfig `json:"redis" yaml:"redis"`
+
+	// Plugin configuration, for methods served by plugins
+	Plugin map[string]string `json:"plugin" yaml:"plugin"`
 }
 
 func New(ctx context.Context, logger log.Interface, config *Config) (Interface, error) {
@@ -51,5 +106,9 @@ func New(ctx context.Context, logger log.Interface, config *Config) (Interface,
 		return nil, fmt.Errorf("fetcher %q not found", config.Method)
 	}
 
+	if err := config.Batch.Validate(); err != nil {
+		return nil, err
+	}
+
 	return create(ctx, logger, config)
 }
@@ -0,0 +1,205 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"arhat.dev/pkg/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"arhat.dev/ksync/pkg/fetcher"
+)
+
+func newFetcherFactory(pc *Config) fetcher.FactoryFunc {
+	return func(ctx context.Context, logger log.Interface, config *fetcher.Config) (fetcher.Interface, error) {
+		cc, err := dial(pc.Socket)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		return &pluginFetcher{
+			ctx:    ctx,
+			cancel: cancel,
+
+			log:     logger,
+			client:  NewFetcherClient(cc),
+			timeout: pc.timeout(),
+			request: &FetcherStartRequest{
+				Method:           config.Method,
+				RequiredDataKeys: config.RequiredDataKeys,
+				Config:           config.Plugin,
+			},
+
+			dataCh: make(chan map[string][]byte),
+			mu:     new(sync.RWMutex),
+		}, nil
+	}
+}
+
+// pluginFetcher is the fetcher served by plugin
+type pluginFetcher struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	log     log.Interface
+	client  FetcherClient
+	timeout time.Duration
+	request *FetcherStartRequest
+
+	dataCh chan map[string][]byte
+
+	// id of the fetcher in plugin
+	id string
+	mu *sync.RWMutex
+}
+
+// Start the fetcher in plugin
+func (f *pluginFetcher) Start(stop <-chan struct{}) error {
+	if err := f.start(); err != nil {
+		return err
+	}
+
+	go func() {
+		select {
+		case <-stop:
+			f.cancel()
+		case <-f.ctx.Done():
+		}
+	}()
+
+	go f.retrieve()
+
+	return nil
+}
+
+func (f *pluginFetcher) start() error {
+	ctx, cancel := context.WithTimeout(f.ctx, f.timeout)
+	defer cancel()
+
+	ref, err := f.client.Start(ctx, f.request)
+	if err != nil {
+		return fmt.Errorf("failed to start fetcher in plugin: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.id = ref.Id
+	return nil
+}
+
+func (f *pluginFetcher) ref() *FetcherRef {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return &FetcherRef{Id: f.id}
+}
+
+// retrieve data from plugin until stopped, the fetcher is started again if not found in
+// plugin (e.g. plugin restarted)
+func (f *pluginFetcher) retrieve() {
+	defer close(f.dataCh)
+
+	for {
+		err := f.receive()
+
+		select {
+		case <-f.ctx.Done():
+			return
+		default:
+		}
+
+		if status.Code(err) == codes.NotFound {
+			f.log.I("fetcher not found in plugin, starting again")
+			err = f.start()
+		}
+
+		if err != nil {
+			f.log.I("failed to retrieve data from plugin", log.Error(err))
+		}
+
+		select {
+		case <-f.ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (f *pluginFetcher) receive() error {
+	stream, err := f.client.Retrieve(f.ctx, f.ref())
+	if err != nil {
+		return err
+	}
+
+	for {
+		d, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		for k, v := range d.Data {
+			if v == nil {
+				// empty bytes are decoded as nil, which is reserved for removed keys
+				d.Data[k] = []byte{}
+			}
+		}
+
+		select {
+		case <-f.ctx.Done():
+			return f.ctx.Err()
+		case f.dataCh <- d.Data:
+		}
+	}
+}
+
+func (f *pluginFetcher) Retrieve() <-chan map[string][]byte {
+	return f.dataCh
+}
+
+// Stop the fetcher in plugin
+func (f *pluginFetcher) Stop() error {
+	f.cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+
+	_, err := f.client.Stop(ctx, f.ref())
+	if err != nil && status.Code(err) != codes.NotFound {
+		return fmt.Errorf("failed to stop fetcher in plugin: %w", err)
+	}
+
+	return nil
+}
+
+func (f *pluginFetcher) Status() fetcher.Status {
+	ctx, cancel := context.WithTimeout(f.ctx, f.timeout)
+	defer cancel()
+
+	ret := fetcher.Status{Method: f.request.Method}
+
+	s, err := f.client.Status(ctx, f.ref())
+	if err != nil {
+		f.log.D("failed to get fetcher status from plugin", log.Error(err))
+		ret.LastError = err.Error()
+		return ret
+	}
+
+	ret.Connected = s.Connected
+	if s.LastMessageAt != 0 {
+		ret.LastMessageAt = time.Unix(0, s.LastMessageAt)
+	}
+	if s.DisconnectedAt != 0 {
+		ret.DisconnectedAt = time.Unix(0, s.DisconnectedAt)
+	}
+	ret.BufferedKeys = s.BufferedKeys
+	ret.StalledKeys = s.StalledKeys
+	ret.LastError = s.LastError
+	ret.Reconnects = s.Reconnects
+
+	return ret
+}
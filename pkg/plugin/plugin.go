@@ -0,0 +1,167 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"arhat.dev/ksync/pkg/fetcher"
+	"arhat.dev/ksync/pkg/validator"
+)
+
+const defaultCallTimeout = 10 * time.Second
+
+// Methods of fetchers and validators served by plugins
+type Methods struct {
+	Fetchers   []Config `json:"fetchers" yaml:"fetchers"`
+	Validators []Config `json:"validators" yaml:"validators"`
+}
+
+// Config of a plugin serving a fetcher or validator method
+type Config struct {
+	// Method name of the fetcher or validator
+	Method string `json:"method" yaml:"method"`
+
+	// Socket is the path of unix socket the plugin listening on
+	Socket string `json:"socket" yaml:"socket"`
+
+	// Timeout of each call to the plugin, defaults to 10s
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+func (c *Config) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+
+	return defaultCallTimeout
+}
+
+var (
+	conns   = make(map[string]*grpc.ClientConn)
+	connsMu = new(sync.Mutex)
+
+	// methods registered by plugins, replaced on every Register call
+	registeredFetchers   = make(map[string]struct{})
+	registeredValidators = make(map[string]struct{})
+	registeredMu         = new(sync.Mutex)
+)
+
+// Register fetcher and validator methods served by plugins, methods registered by plugins
+// previously but not in config are unregistered, methods colliding with built-in ones are
+// rejected
+func Register(config *Methods) error {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+
+	var (
+		sockets    = make(map[string]struct{})
+		fetchers   = make(map[string]struct{})
+		validators = make(map[string]struct{})
+	)
+	for _, pc := range config.Fetchers {
+		if err := checkMethod(&pc, fetchers, registeredFetchers, fetcher.HasFetcher); err != nil {
+			return fmt.Errorf("invalid fetcher plugin: %w", err)
+		}
+
+		sockets[pc.Socket] = struct{}{}
+	}
+
+	for _, pc := range config.Validators {
+		if err := checkMethod(&pc, validators, registeredValidators, validator.HasValidator); err != nil {
+			return fmt.Errorf("invalid validator plugin: %w", err)
+		}
+
+		sockets[pc.Socket] = struct{}{}
+	}
+
+	for m := range registeredFetchers {
+		if _, ok := fetchers[m]; !ok {
+			fetcher.UnregisterFetcher(m)
+		}
+	}
+
+	for m := range registeredValidators {
+		if _, ok := validators[m]; !ok {
+			validator.UnregisterValidator(m)
+		}
+	}
+
+	for i := range config.Fetchers {
+		pc := config.Fetchers[i]
+		fetcher.RegisterFetcher(pc.Method, newFetcherFactory(&pc))
+	}
+
+	for i := range config.Validators {
+		pc := config.Validators[i]
+		validator.RegisterValidator(pc.Method, newValidatorFactory(&pc))
+	}
+
+	registeredFetchers, registeredValidators = fetchers, validators
+
+	// close connections to plugins not used any more
+	connsMu.Lock()
+	defer connsMu.Unlock()
+
+	for socket, cc := range conns {
+		if _, ok := sockets[socket]; !ok {
+			_ = cc.Close()
+			delete(conns, socket)
+		}
+	}
+
+	return nil
+}
+
+// checkMethod checks plugin config, and adds its method to methods if neither duplicate nor
+// registered by others than plugins (built-in)
+func checkMethod(
+	pc *Config,
+	methods, registered map[string]struct{},
+	isRegistered func(name string) bool,
+) error {
+	if pc.Method == "" || pc.Socket == "" {
+		return fmt.Errorf("method and socket are required for plugin")
+	}
+
+	if _, ok := methods[pc.Method]; ok {
+		return fmt.Errorf("duplicate method %q", pc.Method)
+	}
+
+	if _, ok := registered[pc.Method]; !ok && isRegistered(pc.Method) {
+		return fmt.Errorf("method %q collides with built-in one", pc.Method)
+	}
+
+	methods[pc.Method] = struct{}{}
+	return nil
+}
+
+// dial the plugin at unix socket, connections are shared by all fetchers and validators of
+// the plugin
+func dial(socket string) (*grpc.ClientConn, error) {
+	connsMu.Lock()
+	defer connsMu.Unlock()
+
+	if cc, ok := conns[socket]; ok {
+		return cc, nil
+	}
+
+	// connection is established lazily
+	cc, err := grpc.Dial("passthrough:///"+socket,
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial plugin %q: %w", socket, err)
+	}
+
+	conns[socket] = cc
+	return cc, nil
+}
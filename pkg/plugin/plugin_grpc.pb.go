@@ -0,0 +1,331 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: plugin.proto
+
+package plugin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// FetcherClient is the client API for Fetcher service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FetcherClient interface {
+	// Start a fetcher until stopped
+	Start(ctx context.Context, in *FetcherStartRequest, opts ...grpc.CallOption) (*FetcherRef, error)
+	// Retrieve data from the fetcher
+	Retrieve(ctx context.Context, in *FetcherRef, opts ...grpc.CallOption) (Fetcher_RetrieveClient, error)
+	// Status of the fetcher
+	Status(ctx context.Context, in *FetcherRef, opts ...grpc.CallOption) (*FetcherStatus, error)
+	// Stop the fetcher
+	Stop(ctx context.Context, in *FetcherRef, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type fetcherClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFetcherClient(cc grpc.ClientConnInterface) FetcherClient {
+	return &fetcherClient{cc}
+}
+
+func (c *fetcherClient) Start(ctx context.Context, in *FetcherStartRequest, opts ...grpc.CallOption) (*FetcherRef, error) {
+	out := new(FetcherRef)
+	err := c.cc.Invoke(ctx, "/ksync.plugin.Fetcher/Start", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fetcherClient) Retrieve(ctx context.Context, in *FetcherRef, opts ...grpc.CallOption) (Fetcher_RetrieveClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Fetcher_ServiceDesc.Streams[0], "/ksync.plugin.Fetcher/Retrieve", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &fetcherRetrieveClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Fetcher_RetrieveClient interface {
+	Recv() (*Data, error)
+	grpc.ClientStream
+}
+
+type fetcherRetrieveClient struct {
+	grpc.ClientStream
+}
+
+func (x *fetcherRetrieveClient) Recv() (*Data, error) {
+	m := new(Data)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *fetcherClient) Status(ctx context.Context, in *FetcherRef, opts ...grpc.CallOption) (*FetcherStatus, error) {
+	out := new(FetcherStatus)
+	err := c.cc.Invoke(ctx, "/ksync.plugin.Fetcher/Status", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fetcherClient) Stop(ctx context.Context, in *FetcherRef, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/ksync.plugin.Fetcher/Stop", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FetcherServer is the server API for Fetcher service.
+// All implementations should embed UnimplementedFetcherServer
+// for forward compatibility
+type FetcherServer interface {
+	// Start a fetcher until stopped
+	Start(context.Context, *FetcherStartRequest) (*FetcherRef, error)
+	// Retrieve data from the fetcher
+	Retrieve(*FetcherRef, Fetcher_RetrieveServer) error
+	// Status of the fetcher
+	Status(context.Context, *FetcherRef) (*FetcherStatus, error)
+	// Stop the fetcher
+	Stop(context.Context, *FetcherRef) (*Empty, error)
+}
+
+// UnimplementedFetcherServer should be embedded to have forward compatible implementations.
+type UnimplementedFetcherServer struct {
+}
+
+func (UnimplementedFetcherServer) Start(context.Context, *FetcherStartRequest) (*FetcherRef, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Start not implemented")
+}
+func (UnimplementedFetcherServer) Retrieve(*FetcherRef, Fetcher_RetrieveServer) error {
+	return status.Errorf(codes.Unimplemented, "method Retrieve not implemented")
+}
+func (UnimplementedFetcherServer) Status(context.Context, *FetcherRef) (*FetcherStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedFetcherServer) Stop(context.Context, *FetcherRef) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stop not implemented")
+}
+
+// UnsafeFetcherServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FetcherServer will
+// result in compilation errors.
+type UnsafeFetcherServer interface {
+	mustEmbedUnimplementedFetcherServer()
+}
+
+func RegisterFetcherServer(s grpc.ServiceRegistrar, srv FetcherServer) {
+	s.RegisterService(&Fetcher_ServiceDesc, srv)
+}
+
+func _Fetcher_Start_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FetcherStartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FetcherServer).Start(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ksync.plugin.Fetcher/Start",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FetcherServer).Start(ctx, req.(*FetcherStartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Fetcher_Retrieve_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FetcherRef)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FetcherServer).Retrieve(m, &fetcherRetrieveServer{stream})
+}
+
+type Fetcher_RetrieveServer interface {
+	Send(*Data) error
+	grpc.ServerStream
+}
+
+type fetcherRetrieveServer struct {
+	grpc.ServerStream
+}
+
+func (x *fetcherRetrieveServer) Send(m *Data) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Fetcher_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FetcherRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FetcherServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ksync.plugin.Fetcher/Status",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FetcherServer).Status(ctx, req.(*FetcherRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Fetcher_Stop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FetcherRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FetcherServer).Stop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ksync.plugin.Fetcher/Stop",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FetcherServer).Stop(ctx, req.(*FetcherRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Fetcher_ServiceDesc is the grpc.ServiceDesc for Fetcher service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Fetcher_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ksync.plugin.Fetcher",
+	HandlerType: (*FetcherServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Start",
+			Handler:    _Fetcher_Start_Handler,
+		},
+		{
+			MethodName: "Status",
+			Handler:    _Fetcher_Status_Handler,
+		},
+		{
+			MethodName: "Stop",
+			Handler:    _Fetcher_Stop_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Retrieve",
+			Handler:       _Fetcher_Retrieve_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "plugin.proto",
+}
+
+// ValidatorClient is the client API for Validator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ValidatorClient interface {
+	Validate(ctx context.Context, in *ValidateRequest, opts ...grpc.CallOption) (*ValidateResponse, error)
+}
+
+type validatorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewValidatorClient(cc grpc.ClientConnInterface) ValidatorClient {
+	return &validatorClient{cc}
+}
+
+func (c *validatorClient) Validate(ctx context.Context, in *ValidateRequest, opts ...grpc.CallOption) (*ValidateResponse, error) {
+	out := new(ValidateResponse)
+	err := c.cc.Invoke(ctx, "/ksync.plugin.Validator/Validate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ValidatorServer is the server API for Validator service.
+// All implementations should embed UnimplementedValidatorServer
+// for forward compatibility
+type ValidatorServer interface {
+	Validate(context.Context, *ValidateRequest) (*ValidateResponse, error)
+}
+
+// UnimplementedValidatorServer should be embedded to have forward compatible implementations.
+type UnimplementedValidatorServer struct {
+}
+
+func (UnimplementedValidatorServer) Validate(context.Context, *ValidateRequest) (*ValidateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Validate not implemented")
+}
+
+// UnsafeValidatorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ValidatorServer will
+// result in compilation errors.
+type UnsafeValidatorServer interface {
+	mustEmbedUnimplementedValidatorServer()
+}
+
+func RegisterValidatorServer(s grpc.ServiceRegistrar, srv ValidatorServer) {
+	s.RegisterService(&Validator_ServiceDesc, srv)
+}
+
+func _Validator_Validate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ValidatorServer).Validate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ksync.plugin.Validator/Validate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ValidatorServer).Validate(ctx, req.(*ValidateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Validator_ServiceDesc is the grpc.ServiceDesc for Validator service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Validator_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ksync.plugin.Validator",
+	HandlerType: (*ValidatorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Validate",
+			Handler:    _Validator_Validate_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "plugin.proto",
+}
@@ -0,0 +1,360 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"arhat.dev/pkg/log"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"arhat.dev/ksync/pkg/fetcher"
+	"arhat.dev/ksync/pkg/validator"
+)
+
+type testFetcher struct {
+	config *fetcher.Config
+	dataCh chan map[string][]byte
+}
+
+func (f *testFetcher) Start(stop <-chan struct{}) error {
+	go func() {
+		defer close(f.dataCh)
+
+		select {
+		case f.dataCh <- map[string][]byte{"foo": []byte(f.config.Plugin["foo"])}:
+		case <-stop:
+		}
+
+		<-stop
+	}()
+
+	return nil
+}
+
+func (f *testFetcher) Retrieve() <-chan map[string][]byte { return f.dataCh }
+
+func (f *testFetcher) Stop() error { return nil }
+
+func (f *testFetcher) Status() fetcher.Status {
+	return fetcher.Status{
+		Method:        f.config.Method,
+		Connected:     true,
+		LastMessageAt: time.Unix(0, 1),
+		BufferedKeys:  f.config.RequiredDataKeys,
+		LastError:     "timeout",
+		Reconnects:    2,
+	}
+}
+
+type testValidator struct {
+	config *validator.Config
+}
+
+func (v *testValidator) Validate(vctx *validator.ValidationContext, data map[string][]byte) *validator.DataMsg {
+	result := &validator.DataMsg{
+		Data:   make(map[string][]byte),
+		Errors: make(map[string]error),
+	}
+
+	for k, d := range data {
+		switch {
+		case string(d) == v.config.Plugin["reject"]:
+			result.Errors[k] = fmt.Errorf("rejected")
+		case vctx.TargetData != nil:
+			result.Data[k] = []byte(vctx.Target.Name + "/" + string(d) + vctx.TargetData["bin"])
+		default:
+			result.Data[k] = d
+		}
+	}
+
+	return result
+}
+
+func newTestServer(t *testing.T) string {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	socket := filepath.Join(t.TempDir(), "plugin.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(ctx, log.Log.WithName("test"),
+		map[string]fetcher.FactoryFunc{
+			"test": func(_ context.Context, _ log.Interface, config *fetcher.Config) (fetcher.Interface, error) {
+				return &testFetcher{config: config, dataCh: make(chan map[string][]byte)}, nil
+			},
+		},
+		map[string]validator.FactoryFunc{
+			"test": func(_ context.Context, _ log.Interface, config *validator.Config) (validator.Interface, error) {
+				return &testValidator{config: config}, nil
+			},
+		},
+	)
+
+	go func() { _ = srv.Serve(l) }()
+
+	return socket
+}
+
+func TestFetcher(t *testing.T) {
+	socket := newTestServer(t)
+
+	f, err := newFetcherFactory(&Config{Method: "test", Socket: socket})(
+		context.TODO(), log.Log.WithName("test"), &fetcher.Config{
+			Method:           "test",
+			RequiredDataKeys: []string{"foo"},
+			Plugin:           map[string]string{"foo": "bar"},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	if err = f.Start(stop); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case data := <-f.Retrieve():
+		if !reflect.DeepEqual(data, map[string][]byte{"foo": []byte("bar")}) {
+			t.Errorf("unexpected data %v", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for data")
+	}
+
+	expectedStatus := fetcher.Status{
+		Method:        "test",
+		Connected:     true,
+		LastMessageAt: time.Unix(0, 1),
+		BufferedKeys:  []string{"foo"},
+		LastError:     "timeout",
+		Reconnects:    2,
+	}
+	if s := f.Status(); !reflect.DeepEqual(s, expectedStatus) {
+		t.Errorf("unexpected status %+v", s)
+	}
+
+	if err = f.Stop(); err != nil {
+		t.Error(err)
+	}
+
+	select {
+	case _, more := <-f.Retrieve():
+		if more {
+			t.Error("unexpected data after stopped")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("data channel not closed after stopped")
+	}
+}
+
+func TestValidator(t *testing.T) {
+	socket := newTestServer(t)
+
+	tests := []struct {
+		name           string
+		socket         string
+		vctx           *validator.ValidationContext
+		data           map[string][]byte
+		expectedData   map[string][]byte
+		expectedErrors []string
+	}{
+		{
+			name:         "Valid",
+			socket:       socket,
+			data:         map[string][]byte{"a": []byte("ok")},
+			expectedData: map[string][]byte{"a": []byte("ok")},
+		},
+		{
+			name:   "With Context",
+			socket: socket,
+			vctx: &validator.ValidationContext{
+				Target:     validator.SyncTarget{Kind: "configmap", Name: "foo"},
+				TargetData: map[string]string{},
+			},
+			data:         map[string][]byte{"a": []byte("ok")},
+			expectedData: map[string][]byte{"a": []byte("foo/ok")},
+		},
+		{
+			name:   "With Binary Context",
+			socket: socket,
+			vctx: &validator.ValidationContext{
+				Target:       validator.SyncTarget{Kind: "secret", Name: "foo"},
+				TargetData:   map[string]string{"bin": "\xff\xfe"},
+				BufferedData: map[string]string{"bin": "\xff\xfe"},
+			},
+			data:         map[string][]byte{"a": []byte("ok")},
+			expectedData: map[string][]byte{"a": []byte("foo/ok\xff\xfe")},
+		},
+		{
+			name:           "Rejected",
+			socket:         socket,
+			data:           map[string][]byte{"a": []byte("ok"), "b": []byte("bad")},
+			expectedData:   map[string][]byte{"a": []byte("ok")},
+			expectedErrors: []string{"b"},
+		},
+		{
+			name:           "Plugin Unavailable",
+			socket:         filepath.Join(t.TempDir(), "none.sock"),
+			data:           map[string][]byte{"a": []byte("ok")},
+			expectedErrors: []string{"a"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := newValidatorFactory(&Config{Method: "test", Socket: test.socket, Timeout: time.Second})(
+				context.TODO(), log.Log.WithName("test"), &validator.Config{
+					Method: "test",
+					Plugin: map[string]string{"reject": "bad"},
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := v.Validate(test.vctx, test.data)
+			if len(test.expectedData) != 0 && !reflect.DeepEqual(result.Data, test.expectedData) {
+				t.Errorf("unexpected data %v", result.Data)
+			}
+
+			if len(result.Errors) != len(test.expectedErrors) {
+				t.Errorf("unexpected errors %v", result.Errors)
+			}
+
+			for _, k := range test.expectedErrors {
+				if result.Errors[k] == nil {
+					t.Errorf("expected error for %q", k)
+				}
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "plugin.sock")
+	methods := &Methods{
+		Fetchers:   []Config{{Method: "test-fetcher", Socket: socket}},
+		Validators: []Config{{Method: "test-validator", Socket: socket}},
+	}
+
+	registered := func() (bool, bool) {
+		return fetcher.HasFetcher("test-fetcher"), validator.HasValidator("test-validator")
+	}
+
+	if err := Register(methods); err != nil {
+		t.Fatal(err)
+	}
+
+	// registered again when reloaded
+	if err := Register(methods); err != nil {
+		t.Fatal(err)
+	}
+
+	if f, v := registered(); !f || !v {
+		t.Fatalf("plugin methods not registered: fetcher %v, validator %v", f, v)
+	}
+
+	for _, invalid := range []*Methods{
+		{Fetchers: []Config{{Method: fetcher.MethodMQTT, Socket: socket}}},
+		{Validators: []Config{{Method: validator.MethodJSONSchema, Socket: socket}}},
+		{Validators: []Config{{Method: "dup", Socket: socket}, {Method: "dup", Socket: socket}}},
+		{Validators: []Config{{Method: "no-socket"}}},
+	} {
+		if err := Register(invalid); err == nil {
+			t.Errorf("expected error registering %+v", invalid)
+		}
+	}
+
+	// nothing changed by invalid methods
+	if f, v := registered(); !f || !v {
+		t.Errorf("plugin methods unregistered by invalid methods: fetcher %v, validator %v", f, v)
+	}
+
+	if !fetcher.HasFetcher(fetcher.MethodMQTT) || !validator.HasValidator(validator.MethodJSONSchema) {
+		t.Errorf("built-in methods unregistered")
+	}
+
+	if err := Register(&Methods{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if f, v := registered(); f || v {
+		t.Errorf("removed plugin methods still registered: fetcher %v, validator %v", f, v)
+	}
+}
+
+func TestGeneratedCodeMatchesProto(t *testing.T) {
+	content, err := ioutil.ReadFile("plugin.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		msgExp   = regexp.MustCompile(`^message (\w+) \{`)
+		fieldExp = regexp.MustCompile(`^\s*((repeated )?(map<string, \w+>|\w+)) (\w+) = (\d+);`)
+
+		current  string
+		expected = make(map[string]map[string]string)
+	)
+	for _, line := range strings.Split(string(content), "\n") {
+		if m := msgExp.FindStringSubmatch(line); m != nil {
+			current = m[1]
+			expected[current] = make(map[string]string)
+			continue
+		}
+
+		m := fieldExp.FindStringSubmatch(line)
+		if m == nil || current == "" {
+			continue
+		}
+
+		expected[current][m[4]+"="+m[5]] = m[1]
+	}
+
+	typeName := func(fd protoreflect.FieldDescriptor) string {
+		if fd.Kind() == protoreflect.MessageKind {
+			return string(fd.Message().Name())
+		}
+
+		return fd.Kind().String()
+	}
+
+	actual := make(map[string]map[string]string)
+	msgs := File_plugin_proto.Messages()
+	for i := 0; i < msgs.Len(); i++ {
+		md := msgs.Get(i)
+		fields := make(map[string]string)
+		for j := 0; j < md.Fields().Len(); j++ {
+			fd := md.Fields().Get(j)
+
+			typ := typeName(fd)
+			switch {
+			case fd.IsMap():
+				typ = "map<" + typeName(fd.MapKey()) + ", " + typeName(fd.MapValue()) + ">"
+			case fd.IsList():
+				typ = "repeated " + typ
+			}
+
+			fields[fmt.Sprintf("%s=%d", fd.Name(), fd.Number())] = typ
+		}
+
+		actual[string(md.Name())] = fields
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("generated code does not match plugin.proto, run `make gen.proto`\nexpected %v\ngot %v", expected, actual)
+	}
+}
@@ -0,0 +1,261 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"sync"
+
+	"arhat.dev/pkg/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"arhat.dev/ksync/pkg/fetcher"
+	"arhat.dev/ksync/pkg/validator"
+)
+
+// Server serves fetchers and validators to ksync
+type Server struct {
+	ctx context.Context
+	log log.Interface
+
+	fetcherFactories   map[string]fetcher.FactoryFunc
+	validatorFactories map[string]validator.FactoryFunc
+
+	fetchers map[string]*fetcherInstance
+	nextID   uint64
+
+	// validators are stateless, cached by method and config
+	validators map[string]validator.Interface
+
+	mu *sync.Mutex
+}
+
+type fetcherInstance struct {
+	fetcher fetcher.Interface
+	stop    chan struct{}
+}
+
+// NewServer creates a plugin server for fetchers and validators created by factories
+func NewServer(
+	ctx context.Context,
+	logger log.Interface,
+	fetchers map[string]fetcher.FactoryFunc,
+	validators map[string]validator.FactoryFunc,
+) *Server {
+	return &Server{
+		ctx: ctx,
+		log: logger,
+
+		fetcherFactories:   fetchers,
+		validatorFactories: validators,
+
+		fetchers:   make(map[string]*fetcherInstance),
+		validators: make(map[string]validator.Interface),
+
+		mu: new(sync.Mutex),
+	}
+}
+
+// Serve grpc requests on listener until the context of the server canceled
+func (s *Server) Serve(l net.Listener) error {
+	srv := grpc.NewServer()
+	RegisterFetcherServer(srv, s)
+	RegisterValidatorServer(srv, s)
+
+	go func() {
+		<-s.ctx.Done()
+
+		srv.Stop()
+		s.stopAllFetchers()
+	}()
+
+	return srv.Serve(l)
+}
+
+func (s *Server) Start(_ context.Context, req *FetcherStartRequest) (*FetcherRef, error) {
+	create, ok := s.fetcherFactories[req.Method]
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "fetcher %q not found", req.Method)
+	}
+
+	f, err := create(s.ctx, s.log.WithFields(log.String("fetcher", req.Method)), &fetcher.Config{
+		Method:           req.Method,
+		RequiredDataKeys: req.RequiredDataKeys,
+		Plugin:           req.Config,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to create fetcher: %v", err)
+	}
+
+	stop := make(chan struct{})
+	if err = f.Start(stop); err != nil {
+		close(stop)
+		return nil, status.Errorf(codes.Internal, "failed to start fetcher: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	id := strconv.FormatUint(s.nextID, 10)
+	s.fetchers[id] = &fetcherInstance{fetcher: f, stop: stop}
+
+	return &FetcherRef{Id: id}, nil
+}
+
+func (s *Server) getFetcher(ref *FetcherRef) (*fetcherInstance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fi, ok := s.fetchers[ref.Id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "fetcher %q not found", ref.Id)
+	}
+
+	return fi, nil
+}
+
+func (s *Server) Retrieve(ref *FetcherRef, stream Fetcher_RetrieveServer) error {
+	fi, err := s.getFetcher(ref)
+	if err != nil {
+		return err
+	}
+
+	dataCh := fi.fetcher.Retrieve()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case data, more := <-dataCh:
+			if !more {
+				return status.Errorf(codes.NotFound, "fetcher %q stopped", ref.Id)
+			}
+
+			if err = stream.Send(&Data{Data: data}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) Status(_ context.Context, ref *FetcherRef) (*FetcherStatus, error) {
+	fi, err := s.getFetcher(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	st := fi.fetcher.Status()
+	ret := &FetcherStatus{
+		Connected:    st.Connected,
+		BufferedKeys: st.BufferedKeys,
+		StalledKeys:  st.StalledKeys,
+		LastError:    st.LastError,
+		Reconnects:   st.Reconnects,
+	}
+
+	if !st.LastMessageAt.IsZero() {
+		ret.LastMessageAt = st.LastMessageAt.UnixNano()
+	}
+
+	if !st.DisconnectedAt.IsZero() {
+		ret.DisconnectedAt = st.DisconnectedAt.UnixNano()
+	}
+
+	return ret, nil
+}
+
+func (s *Server) Stop(_ context.Context, ref *FetcherRef) (*Empty, error) {
+	fi, err := func() (*fetcherInstance, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		fi, ok := s.fetchers[ref.Id]
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "fetcher %q not found", ref.Id)
+		}
+
+		delete(s.fetchers, ref.Id)
+		return fi, nil
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	close(fi.stop)
+	if err = fi.fetcher.Stop(); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to stop fetcher: %v", err)
+	}
+
+	return &Empty{}, nil
+}
+
+func (s *Server) stopAllFetchers() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, fi := range s.fetchers {
+		close(fi.stop)
+		if err := fi.fetcher.Stop(); err != nil {
+			s.log.I("failed to stop fetcher", log.String("id", id), log.Error(err))
+		}
+
+		delete(s.fetchers, id)
+	}
+}
+
+func (s *Server) Validate(_ context.Context, req *ValidateRequest) (*ValidateResponse, error) {
+	v, err := s.getValidator(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := v.Validate(validationContextFromMsg(req.Context), req.Data)
+
+	resp := &ValidateResponse{
+		Data:   result.Data,
+		Errors: make(map[string]string, len(result.Errors)),
+		Drop:   result.Drop,
+	}
+
+	for k, err := range result.Errors {
+		if err != nil {
+			resp.Errors[k] = err.Error()
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *Server) getValidator(req *ValidateRequest) (validator.Interface, error) {
+	create, ok := s.validatorFactories[req.Method]
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "validator %q not found", req.Method)
+	}
+
+	key, err := json.Marshal([]interface{}{req.Method, req.DataKeys, req.Config})
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid validator config: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := s.validators[string(key)]; ok {
+		return v, nil
+	}
+
+	v, err := create(s.ctx, s.log.WithFields(log.String("validator", req.Method)), &validator.Config{
+		Method:   req.Method,
+		DataKeys: req.DataKeys,
+		Plugin:   req.Config,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to create validator: %v", err)
+	}
+
+	s.validators[string(key)] = v
+	return v, nil
+}
@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/validator"
+)
+
+func newValidatorFactory(pc *Config) validator.FactoryFunc {
+	return func(ctx context.Context, logger log.Interface, config *validator.Config) (validator.Interface, error) {
+		cc, err := dial(pc.Socket)
+		if err != nil {
+			return nil, err
+		}
+
+		return &pluginValidator{
+			ctx:     ctx,
+			log:     logger,
+			client:  NewValidatorClient(cc),
+			timeout: pc.timeout(),
+
+			method:   config.Method,
+			dataKeys: config.DataKeys,
+			config:   config.Plugin,
+		}, nil
+	}
+}
+
+// pluginValidator is the validator served by plugin
+type pluginValidator struct {
+	ctx     context.Context
+	log     log.Interface
+	client  ValidatorClient
+	timeout time.Duration
+
+	method   string
+	dataKeys []string
+	config   map[string]string
+}
+
+// Validate data with plugin, all data keys of the validator are rejected if failed to call
+// the plugin
+func (v *pluginValidator) Validate(vctx *validator.ValidationContext, data map[string][]byte) *validator.DataMsg {
+	ctx, cancel := context.WithTimeout(v.ctx, v.timeout)
+	defer cancel()
+
+	resp, err := v.client.Validate(ctx, &ValidateRequest{
+		Method:   v.method,
+		DataKeys: v.dataKeys,
+		Config:   v.config,
+		Context:  newValidationContextMsg(vctx),
+		Data:     data,
+	})
+	if err != nil {
+		v.log.I("failed to validate data with plugin", log.Error(err))
+
+		keys := v.dataKeys
+		if len(keys) == 0 {
+			for k := range data {
+				keys = append(keys, k)
+			}
+		}
+
+		result := &validator.DataMsg{Errors: make(map[string]error)}
+		for _, k := range keys {
+			if _, ok := data[k]; ok {
+				result.Errors[k] = fmt.Errorf("plugin call failed: %w", err)
+			}
+		}
+
+		return result
+	}
+
+	result := &validator.DataMsg{
+		Data:   resp.Data,
+		Errors: make(map[string]error, len(resp.Errors)),
+		Drop:   resp.Drop,
+	}
+
+	for k, msg := range resp.Errors {
+		result.Errors[k] = errors.New(msg)
+	}
+
+	return result
+}
+
+func newValidationContextMsg(vctx *validator.ValidationContext) *ValidationContext {
+	if vctx == nil {
+		return nil
+	}
+
+	return &ValidationContext{
+		Target: &SyncTarget{
+			Kind:      vctx.Target.Kind,
+			Namespace: vctx.Target.Namespace,
+			Name:      vctx.Target.Name,
+			Syncer:    vctx.Target.Syncer,
+		},
+		TargetData:   stringMapToBytes(vctx.TargetData),
+		TargetFound:  vctx.TargetData != nil,
+		BufferedData: stringMapToBytes(vctx.BufferedData),
+		Fetcher:      vctx.Fetcher,
+	}
+}
+
+func validationContextFromMsg(msg *ValidationContext) *validator.ValidationContext {
+	vctx := &validator.ValidationContext{}
+	if msg == nil {
+		return vctx
+	}
+
+	if msg.Target != nil {
+		vctx.Target = validator.SyncTarget{
+			Kind:      msg.Target.Kind,
+			Namespace: msg.Target.Namespace,
+			Name:      msg.Target.Name,
+			Syncer:    msg.Target.Syncer,
+		}
+	}
+
+	if msg.TargetFound {
+		vctx.TargetData = bytesMapToString(msg.TargetData)
+		if vctx.TargetData == nil {
+			vctx.TargetData = make(map[string]string)
+		}
+	}
+
+	vctx.BufferedData = bytesMapToString(msg.BufferedData)
+	vctx.Fetcher = msg.Fetcher
+
+	return vctx
+}
+
+func stringMapToBytes(m map[string]string) map[string][]byte {
+	if m == nil {
+		return nil
+	}
+
+	ret := make(map[string][]byte, len(m))
+	for k, v := range m {
+		ret[k] = []byte(v)
+	}
+
+	return ret
+}
+
+func bytesMapToString(m map[string][]byte) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	ret := make(map[string]string, len(m))
+	for k, v := range m {
+		ret[k] = string(v)
+	}
+
+	return ret
+}
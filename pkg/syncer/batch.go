@@ -0,0 +1,128 @@
+package syncer
+
+import (
+	"fmt"
+	"sort"
+)
+
+func validateDependsOn(dependsOn map[string][]string) error {
+	for k, deps := range dependsOn {
+		if k == "" {
+			return fmt.Errorf("empty data key in dependsOn")
+		}
+
+		for _, d := range deps {
+			if d == "" {
+				return fmt.Errorf("empty dependency of data key %q", k)
+			}
+		}
+	}
+
+	return nil
+}
+
+// dependentsOf returns data keys depending on any of keys (directly or indirectly), keys
+// themselves are not included
+func dependentsOf(dependsOn map[string][]string, keys []string) []string {
+	if len(dependsOn) == 0 || len(keys) == 0 {
+		return nil
+	}
+
+	found := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		found[k] = false
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for k, deps := range dependsOn {
+			if _, ok := found[k]; ok {
+				continue
+			}
+
+			for _, d := range deps {
+				if _, ok := found[d]; ok {
+					found[k] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	var ret []string
+	for k, isDependent := range found {
+		if isDependent {
+			ret = append(ret, k)
+		}
+	}
+	sort.Strings(ret)
+
+	return ret
+}
+
+// splitBatch splits buffered data into the batch to be sent and data held until keys they
+// depend on are available, so data keys are always sent with (or after) their dependencies,
+// a dependency not buffered is available if satisfied returns true (e.g. already written),
+// missing are dependencies not available
+func splitBatch(
+	dependsOn map[string][]string,
+	buf map[string][]byte,
+	satisfied func(key string) bool,
+) (batch, held map[string][]byte, missing []string) {
+	batch = make(map[string][]byte, len(buf))
+	for k, v := range buf {
+		batch[k] = v
+	}
+
+	if len(dependsOn) == 0 {
+		return batch, nil, nil
+	}
+
+	available := make(map[string]bool)
+	isAvailable := func(key string) bool {
+		if _, ok := batch[key]; ok {
+			return true
+		}
+
+		if _, ok := buf[key]; ok {
+			// buffered but held
+			return false
+		}
+
+		ok, checked := available[key]
+		if !checked {
+			ok = satisfied != nil && satisfied(key)
+			available[key] = ok
+		}
+
+		return ok
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for k := range batch {
+			for _, d := range dependsOn[k] {
+				if !isAvailable(d) {
+					if held == nil {
+						held = make(map[string][]byte)
+					}
+
+					held[k] = batch[k]
+					delete(batch, k)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	for k, ok := range available {
+		if !ok {
+			missing = append(missing, k)
+		}
+	}
+	sort.Strings(missing)
+
+	return batch, held, missing
+}
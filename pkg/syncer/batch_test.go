@@ -0,0 +1,93 @@
+package syncer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDependentsOf(t *testing.T) {
+	dependsOn := map[string][]string{
+		"tls.crt":    {"tls.key"},
+		"ca.crt":     {"tls.crt"},
+		"app.yaml":   {"app.schema"},
+		"circular.a": {"circular.b"},
+		"circular.b": {"circular.a"},
+	}
+
+	tests := []struct {
+		name     string
+		keys     []string
+		expected []string
+	}{
+		{name: "Direct And Indirect", keys: []string{"tls.key"}, expected: []string{"ca.crt", "tls.crt"}},
+		{name: "No Dependents", keys: []string{"ca.crt"}, expected: nil},
+		{name: "Circular", keys: []string{"circular.a"}, expected: []string{"circular.b"}},
+		{name: "Empty", keys: nil, expected: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := dependentsOf(dependsOn, test.keys); !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSplitBatch(t *testing.T) {
+	dependsOn := map[string][]string{
+		"tls.crt": {"tls.key"},
+		"ca.crt":  {"tls.crt"},
+	}
+
+	batch, held, missing := splitBatch(dependsOn, map[string][]byte{
+		"tls.crt": []byte("crt"),
+		"ca.crt":  []byte("ca"),
+		"foo":     []byte("foo"),
+	}, nil)
+
+	if len(batch) != 1 || string(batch["foo"]) != "foo" {
+		t.Errorf("unexpected batch %v", batch)
+	}
+
+	if len(held) != 2 || string(held["tls.crt"]) != "crt" || string(held["ca.crt"]) != "ca" {
+		t.Errorf("unexpected held data %v", held)
+	}
+
+	if !reflect.DeepEqual(missing, []string{"tls.key"}) {
+		t.Errorf("unexpected missing dependencies %v", missing)
+	}
+
+	batch, held, missing = splitBatch(dependsOn, map[string][]byte{
+		"tls.crt": []byte("crt"),
+		"tls.key": []byte("key"),
+	}, nil)
+
+	if len(batch) != 2 || len(held) != 0 || len(missing) != 0 {
+		t.Errorf("expected all data in batch, got batch %v, held %v", batch, held)
+	}
+
+	// tls.key already written
+	batch, held, missing = splitBatch(dependsOn, map[string][]byte{
+		"tls.crt": []byte("crt"),
+		"ca.crt":  []byte("ca"),
+	}, func(key string) bool { return key == "tls.key" })
+
+	if len(batch) != 2 || len(held) != 0 || len(missing) != 0 {
+		t.Errorf("expected all data in batch, got batch %v, held %v", batch, held)
+	}
+
+	// ca.crt must wait for the buffered tls.crt even if an old tls.crt was written
+	batch, held, missing = splitBatch(dependsOn, map[string][]byte{
+		"tls.crt": []byte("crt"),
+		"ca.crt":  []byte("ca"),
+	}, func(key string) bool { return key == "tls.crt" })
+
+	if len(batch) != 0 || len(held) != 2 || !reflect.DeepEqual(missing, []string{"tls.key"}) {
+		t.Errorf("unexpected batch %v, held %v, missing %v", batch, held, missing)
+	}
+
+	if err := validateDependsOn(map[string][]string{"foo": {""}}); err == nil {
+		t.Errorf("expected error for empty dependency")
+	}
+}
@@ -0,0 +1,160 @@
+package syncer
+
+import (
+	"fmt"
+	"time"
+
+	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/fetcher"
+)
+
+const defaultHealthCheckInterval = 10 * time.Second
+
+// HealthConfig for fetchers of the syncer
+type HealthConfig struct {
+	// MaxDisconnected is the duration a fetcher can stay disconnected before it is reported
+	// unhealthy, 0 (default) disables health check
+	MaxDisconnected time.Duration `json:"maxDisconnected" yaml:"maxDisconnected"`
+
+	// CheckInterval of fetcher status, defaults to 10s
+	CheckInterval time.Duration `json:"checkInterval" yaml:"checkInterval"`
+
+	// FailLiveness fails liveness check of ksync while any fetcher is unhealthy
+	FailLiveness bool `json:"failLiveness" yaml:"failLiveness"`
+}
+
+func (c *HealthConfig) Validate() error {
+	if c.MaxDisconnected < 0 || c.CheckInterval < 0 {
+		return fmt.Errorf("negative health maxDisconnected or checkInterval")
+	}
+
+	return nil
+}
+
+// HealthHandleFunc is called when a fetcher became unhealthy (disconnected longer than
+// maxDisconnected) or recovered
+type HealthHandleFunc func(status fetcher.Status, healthy bool)
+
+// fetcherHealth is the health state of a fetcher
+type fetcherHealth struct {
+	// disconnectedSince is the time fetcher disconnected, for fetchers not reporting it,
+	// the time first observed disconnected
+	disconnectedSince time.Time
+	unhealthy         bool
+}
+
+// Healthy returns false if any fetcher is unhealthy
+func (s *Syncer) Healthy() bool {
+	s.healthMu.RLock()
+	defer s.healthMu.RUnlock()
+
+	for _, h := range s.fetcherHealth {
+		if h.unhealthy {
+			return false
+		}
+	}
+
+	return true
+}
+
+// resetHealth marks all fetchers healthy, used when syncer stopped
+func (s *Syncer) resetHealth() {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	for i, h := range s.fetcherHealth {
+		if h.unhealthy {
+			unhealthyFetcherCounter.Add(s.ctx, -1, methodLabel(s.fetchers[i].Status().Method))
+		}
+
+		s.fetcherHealth[i] = fetcherHealth{}
+	}
+}
+
+// runHealthCheck checks fetcher status periodically until stopped
+func (s *Syncer) runHealthCheck(stop <-chan struct{}) {
+	interval := s.health.CheckInterval
+	if interval == 0 {
+		interval = defaultHealthCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			s.checkHealth(now)
+		}
+	}
+}
+
+// checkHealth updates health state of fetchers, calls onHealthChanged for fetchers with
+// health state changed
+func (s *Syncer) checkHealth(now time.Time) {
+	type change struct {
+		status  fetcher.Status
+		since   time.Time
+		healthy bool
+	}
+
+	var changes []change
+	func() {
+		s.healthMu.Lock()
+		defer s.healthMu.Unlock()
+
+		select {
+		case <-s.ctx.Done():
+			// health reset when stopped
+			return
+		default:
+		}
+
+		for i, f := range s.fetchers {
+			status := f.Status()
+			h := &s.fetcherHealth[i]
+
+			unhealthy := false
+			switch {
+			case status.Connected:
+				h.disconnectedSince = time.Time{}
+			case !status.DisconnectedAt.IsZero():
+				h.disconnectedSince = status.DisconnectedAt
+			case h.disconnectedSince.IsZero():
+				h.disconnectedSince = now
+			}
+
+			if !h.disconnectedSince.IsZero() {
+				unhealthy = now.Sub(h.disconnectedSince) >= s.health.MaxDisconnected
+			}
+
+			if unhealthy == h.unhealthy {
+				continue
+			}
+
+			h.unhealthy = unhealthy
+			changes = append(changes, change{status: status, since: h.disconnectedSince, healthy: !unhealthy})
+		}
+	}()
+
+	for _, c := range changes {
+		if c.healthy {
+			unhealthyFetcherCounter.Add(s.ctx, -1, methodLabel(c.status.Method))
+			s.logger.I("fetcher recovered", log.String("method", c.status.Method))
+		} else {
+			unhealthyFetcherCounter.Add(s.ctx, 1, methodLabel(c.status.Method))
+			s.logger.I("fetcher disconnected for too long",
+				log.String("method", c.status.Method),
+				log.Time("since", c.since),
+				log.String("lastError", c.status.LastError),
+			)
+		}
+
+		if s.onHealthChanged != nil {
+			s.onHealthChanged(c.status, c.healthy)
+		}
+	}
+}
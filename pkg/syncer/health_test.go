@@ -0,0 +1,131 @@
+package syncer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/fetcher"
+)
+
+type testFetcher struct {
+	status fetcher.Status
+}
+
+func (f *testFetcher) Start(stop <-chan struct{}) error   { return nil }
+func (f *testFetcher) Retrieve() <-chan map[string][]byte { return nil }
+func (f *testFetcher) Stop() error                        { return nil }
+func (f *testFetcher) Status() fetcher.Status             { return f.status }
+
+func TestCheckHealth(t *testing.T) {
+	start := time.Now()
+	mqtt := &testFetcher{status: fetcher.Status{Method: "mqtt", Connected: true}}
+	// fetchers not reporting disconnected time
+	plugin := &testFetcher{status: fetcher.Status{Method: "plugin", Connected: true}}
+
+	type change struct {
+		method  string
+		healthy bool
+	}
+	var changes []change
+
+	s := &Syncer{
+		ctx:      context.TODO(),
+		logger:   log.Log.WithName("test"),
+		fetchers: []fetcher.Interface{mqtt, plugin},
+		onHealthChanged: func(status fetcher.Status, healthy bool) {
+			changes = append(changes, change{method: status.Method, healthy: healthy})
+		},
+		health:        HealthConfig{MaxDisconnected: time.Minute},
+		fetcherHealth: make([]fetcherHealth, 2),
+		healthMu:      new(sync.RWMutex),
+	}
+
+	steps := []struct {
+		name            string
+		at              time.Duration
+		update          func()
+		expectedHealthy bool
+		expectedChanges []change
+	}{
+		{
+			name:            "Connected",
+			expectedHealthy: true,
+		},
+		{
+			name: "Disconnected",
+			at:   time.Second,
+			update: func() {
+				mqtt.status.Connected = false
+				mqtt.status.DisconnectedAt = start
+				plugin.status.Connected = false
+			},
+			expectedHealthy: true,
+		},
+		{
+			name:            "Disconnected Too Long",
+			at:              time.Minute,
+			expectedHealthy: false,
+			expectedChanges: []change{{method: "mqtt", healthy: false}},
+		},
+		{
+			name:            "Observed Disconnected Too Long",
+			at:              time.Minute + time.Second,
+			expectedHealthy: false,
+			expectedChanges: []change{{method: "plugin", healthy: false}},
+		},
+		{
+			name:            "No Change",
+			at:              2 * time.Minute,
+			expectedHealthy: false,
+		},
+		{
+			name: "Recovered",
+			at:   3 * time.Minute,
+			update: func() {
+				mqtt.status.Connected = true
+				mqtt.status.DisconnectedAt = time.Time{}
+				plugin.status.Connected = true
+			},
+			expectedHealthy: true,
+			expectedChanges: []change{{method: "mqtt", healthy: true}, {method: "plugin", healthy: true}},
+		},
+	}
+
+	for _, step := range steps {
+		changes = nil
+		if step.update != nil {
+			step.update()
+		}
+
+		s.checkHealth(start.Add(step.at))
+
+		if s.Healthy() != step.expectedHealthy {
+			t.Errorf("%s: expected healthy %v", step.name, step.expectedHealthy)
+		}
+
+		if len(changes) != len(step.expectedChanges) {
+			t.Errorf("%s: unexpected changes %v", step.name, changes)
+			continue
+		}
+
+		for i, c := range step.expectedChanges {
+			if changes[i] != c {
+				t.Errorf("%s: expected change %v, got %v", step.name, c, changes[i])
+			}
+		}
+	}
+}
+
+func TestHealthConfigValidate(t *testing.T) {
+	if err := (&HealthConfig{MaxDisconnected: -time.Second}).Validate(); err == nil {
+		t.Error("expected error for negative maxDisconnected")
+	}
+
+	if err := (&HealthConfig{MaxDisconnected: time.Minute}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
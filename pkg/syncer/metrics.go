@@ -0,0 +1,32 @@
+package syncer
+
+import (
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/metric"
+	"go.opentelemetry.io/otel/label"
+)
+
+var (
+	meter = metric.Must(global.Meter("arhat.dev/ksync/pkg/syncer"))
+
+	validDataCounter = meter.NewInt64Counter("ksync_syncer_data_valid_total",
+		metric.WithDescription("count of data keys accepted by validators"))
+	rejectedDataCounter = meter.NewInt64Counter("ksync_syncer_data_rejected_total",
+		metric.WithDescription("count of data keys rejected by validators"))
+	stalledCounter = meter.NewInt64Counter("ksync_syncer_stalled_total",
+		metric.WithDescription("count of timeouts waiting for required data keys"))
+	unhealthyFetcherCounter = meter.NewInt64UpDownCounter("ksync_syncer_fetchers_unhealthy",
+		metric.WithDescription("count of fetchers disconnected longer than maxDisconnected"))
+)
+
+func timeoutActionLabel(action string) label.KeyValue {
+	return label.String("action", action)
+}
+
+func methodLabel(method string) label.KeyValue {
+	return label.String("method", method)
+}
+
+func validatorLabel(method string) label.KeyValue {
+	return label.String("validator", method)
+}
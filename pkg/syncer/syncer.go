@@ -3,6 +3,7 @@ package syncer
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 
 	"arhat.dev/pkg/log"
@@ -14,11 +15,87 @@ import (
 
 type Config struct {
 	RequiredDataKeys []string            `json:"requiredDataKeys" yaml:"requiredDataKeys"`
+	Batch            fetcher.BatchConfig `json:"batch" yaml:"batch"`
 	Fetchers         []*fetcher.Config   `json:"fetchers" yaml:"fetchers"`
 	Validators       []*validator.Config `json:"validators" yaml:"validators"`
+
+	// Transformers are validators applied to all buffered data right before sending,
+	// while validators are applied to data from each fetcher
+	Transformers []*validator.Config `json:"transformers" yaml:"transformers"`
+
+	// WriteStrategy of synced data, one of merge-keys (default), replace-all,
+	// deep-merge-yaml, deep-merge-json, append
+	WriteStrategy string `json:"writeStrategy" yaml:"writeStrategy"`
+
+	// ProtectedKeys are data keys in the sync target never touched by sync
+	ProtectedKeys []string `json:"protectedKeys" yaml:"protectedKeys"`
+
+	// Target to write synced data, defaults to the object annotated with the sync config
+	Target *TargetConfig `json:"target" yaml:"target"`
+
+	// Targets to write synced data in addition to Target, for each object annotated with
+	// the sync config
+	Targets []*TargetConfig `json:"targets" yaml:"targets"`
+
+	// Atomic rejects all buffered data if any data key is rejected, nothing is written
+	// until a new batch of data is fully valid
+	Atomic bool `json:"atomic" yaml:"atomic"`
+
+	// DependsOn declares data keys each data key depends on, a data key is only written
+	// together with its dependencies, and is rejected when any of them rejected
+	DependsOn map[string][]string `json:"dependsOn" yaml:"dependsOn"`
+
+	// Health check of fetchers
+	Health HealthConfig `json:"health" yaml:"health"`
 }
 
-func NewSyncer(ctx context.Context, logger log.Interface, config *Config) (*Syncer, error) {
+// methodDependsOn is reported as validator method for data keys rejected due to dependencies
+const methodDependsOn = "dependsOn"
+
+// RejectionHandleFunc is called when data for key is rejected by the validator, target is the
+// sync target in the validation context
+type RejectionHandleFunc func(target validator.SyncTarget, validatorMethod, key string, err error)
+
+// TargetsFunc returns current write targets of the syncer
+type TargetsFunc func() []validator.SyncTarget
+
+// StallHandleFunc is called when required data keys are not synced in time, with the action
+// taken (one of report, flush, drop)
+type StallHandleFunc func(missing []string, action string)
+
+// NewSyncer creates a syncer from config, targets, onRejected, onStalled and onHealthChanged
+// are optional, the sync target in ctx is the only write target if targets is nil
+func NewSyncer(
+	ctx context.Context,
+	logger log.Interface,
+	config *Config,
+	targets TargetsFunc,
+	onRejected RejectionHandleFunc,
+	onStalled StallHandleFunc,
+	onHealthChanged HealthHandleFunc,
+) (*Syncer, error) {
+	if err := validateWriteStrategy(config.WriteStrategy); err != nil {
+		return nil, err
+	}
+
+	if err := config.Batch.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := validateDependsOn(config.DependsOn); err != nil {
+		return nil, err
+	}
+
+	if err := config.Health.Validate(); err != nil {
+		return nil, err
+	}
+
+	for i, t := range config.TargetConfigs() {
+		if err := validateTarget(t); err != nil {
+			return nil, fmt.Errorf("invalid target %d: %w", i, err)
+		}
+	}
+
 	mu := new(sync.RWMutex)
 	ctx, exit := context.WithCancel(ctx)
 	_ = exit
@@ -33,7 +110,10 @@ func NewSyncer(ctx context.Context, logger log.Interface, config *Config) (*Sync
 		fetchers = append(fetchers, f)
 	}
 
-	var validators []validator.Interface
+	var (
+		validators       []validator.Interface
+		validatorMethods []string
+	)
 	for i, vc := range config.Validators {
 		logger.V(fmt.Sprintf("creating validator %d, method %q", i, vc.Method))
 		v, err := validator.New(ctx, logger, vc)
@@ -41,22 +121,55 @@ func NewSyncer(ctx context.Context, logger log.Interface, config *Config) (*Sync
 			return nil, fmt.Errorf("failed to create validator: %w", err)
 		}
 		validators = append(validators, v)
+		validatorMethods = append(validatorMethods, vc.Method)
+	}
+
+	var (
+		transformers       []validator.Interface
+		transformerMethods []string
+	)
+	for i, tc := range config.Transformers {
+		logger.V(fmt.Sprintf("creating transformer %d, method %q", i, tc.Method))
+		t, err := validator.New(ctx, logger, tc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create transformer: %w", err)
+		}
+		transformers = append(transformers, t)
+		transformerMethods = append(transformerMethods, tc.Method)
 	}
 
 	s := &Syncer{
 		ctx:  ctx,
 		exit: exit,
 
-		logger:     logger,
-		fetchers:   fetchers,
-		validators: validators,
-
-		dataKeys: config.RequiredDataKeys,
-		dataBuf:  make(map[string][]byte),
-		mu:       mu,
-		cond:     sync.NewCond(new(sync.Mutex)),
-		stopped:  false,
-		dataCh:   make(chan map[string][]byte),
+		logger:           logger,
+		fetchers:         fetchers,
+		validators:       validators,
+		validatorMethods: validatorMethods,
+		targets:          targets,
+		onRejected:       onRejected,
+		onStalled:        onStalled,
+		onHealthChanged:  onHealthChanged,
+
+		transformers:       transformers,
+		transformerMethods: transformerMethods,
+
+		writeStrategy: config.WriteStrategy,
+		protectedKeys: config.ProtectedKeys,
+		synced:        make(map[string][]byte),
+		syncedMu:      new(sync.Mutex),
+
+		atomic:    config.Atomic,
+		dependsOn: config.DependsOn,
+
+		health:        config.Health,
+		fetcherHealth: make([]fetcherHealth, len(fetchers)),
+		healthMu:      new(sync.RWMutex),
+
+		dataBuf: make(map[string][]byte),
+		mu:      mu,
+		batcher: fetcher.NewBatcher(config.RequiredDataKeys, config.Batch),
+		dataCh:  make(chan map[string][]byte),
 	}
 
 	return s, nil
@@ -67,16 +180,38 @@ type Syncer struct {
 	ctx  context.Context
 	exit context.CancelFunc
 
-	logger     log.Interface
-	fetchers   []fetcher.Interface
-	validators []validator.Interface
-
-	dataKeys []string
-	dataBuf  map[string][]byte
-	mu       *sync.RWMutex
-	cond     *sync.Cond
-	stopped  bool
-	dataCh   chan map[string][]byte
+	logger           log.Interface
+	fetchers         []fetcher.Interface
+	validators       []validator.Interface
+	validatorMethods []string
+	targets          TargetsFunc
+	onRejected       RejectionHandleFunc
+	onStalled        StallHandleFunc
+	onHealthChanged  HealthHandleFunc
+
+	transformers       []validator.Interface
+	transformerMethods []string
+
+	writeStrategy string
+	protectedKeys []string
+
+	// all data synced since started, used as the full data set for replace-all (since
+	// a single batch may only contain part of data keys) and to check dependencies,
+	// data keys removed upstream are deleted
+	synced   map[string][]byte
+	syncedMu *sync.Mutex
+
+	atomic    bool
+	dependsOn map[string][]string
+
+	health        HealthConfig
+	fetcherHealth []fetcherHealth
+	healthMu      *sync.RWMutex
+
+	dataBuf map[string][]byte
+	mu      *sync.RWMutex
+	batcher *fetcher.Batcher
+	dataCh  chan map[string][]byte
 }
 
 func (s *Syncer) Start(stop <-chan struct{}) (err error) {
@@ -95,14 +230,100 @@ func (s *Syncer) Start(stop <-chan struct{}) (err error) {
 	}
 
 	for i := range s.fetchers {
-		go s.handleDataRetrievedFromFetcher(s.fetchers[i].Retrieve())
+		go s.handleDataRetrievedFromFetcher(s.fetchers[i].Status().Method, s.fetchers[i].Retrieve())
 	}
 
-	go s.handleDataUpdated()
+	go s.batcher.Run(s.ctx.Done(), fetcher.BatchHandler{
+		BufferedKeys:        s.bufferedKeys,
+		Send:                func() { s.sendData(false) },
+		Flush:               func() { s.sendData(true) },
+		Drop:                s.dropData,
+		MissingDependencies: s.missingDependencies,
+		OnTimeout: func(missing []string, action string) {
+			stalledCounter.Add(s.ctx, 1, timeoutActionLabel(action))
+			s.logger.I("required data keys not synced in time",
+				log.Strings("missing", missing), log.String("action", action))
+
+			if s.onStalled != nil {
+				s.onStalled(missing, action)
+			}
+		},
+	})
+
+	if s.health.MaxDisconnected > 0 {
+		go s.runHealthCheck(s.ctx.Done())
+	}
 
 	return nil
 }
 
+// Status of the syncer
+type Status struct {
+	// BufferedKeys are validated data keys not sent
+	BufferedKeys []string `json:"bufferedKeys"`
+
+	// StalledKeys are required data keys not synced in time
+	StalledKeys []string `json:"stalledKeys,omitempty"`
+
+	// Healthy is false if any fetcher disconnected longer than maxDisconnected
+	Healthy bool `json:"healthy"`
+
+	Fetchers []fetcher.Status `json:"fetchers"`
+}
+
+func (s *Syncer) Status() Status {
+	var fetcherStatus []fetcher.Status
+	for _, f := range s.fetchers {
+		fetcherStatus = append(fetcherStatus, f.Status())
+	}
+
+	return Status{
+		BufferedKeys: s.bufferedKeys(),
+		StalledKeys:  s.batcher.StalledKeys(),
+		Healthy:      s.Healthy(),
+		Fetchers:     fetcherStatus,
+	}
+}
+
+// Merge synced data into current data of the sync target with the write strategy of the syncer,
+// for replace-all, data keys synced in previous batches are kept unless removed upstream (nil value)
+func (s *Syncer) Merge(current, update map[string][]byte) (map[string][]byte, error) {
+	full := s.recordSynced(update, s.writeStrategy == WriteStrategyReplaceAll)
+	if full != nil {
+		update = full
+	} else {
+		update, _ = splitRemoved(update)
+	}
+
+	return MergeData(s.writeStrategy, s.protectedKeys, current, update)
+}
+
+// recordSynced records update and returns all data synced since started if full is true
+func (s *Syncer) recordSynced(update map[string][]byte, full bool) map[string][]byte {
+	s.syncedMu.Lock()
+	defer s.syncedMu.Unlock()
+
+	for k, v := range update {
+		if v == nil {
+			delete(s.synced, k)
+			continue
+		}
+
+		s.synced[k] = v
+	}
+
+	if !full {
+		return nil
+	}
+
+	ret := make(map[string][]byte, len(s.synced))
+	for k, v := range s.synced {
+		ret[k] = v
+	}
+
+	return ret
+}
+
 func (s *Syncer) Retrieve() <-chan map[string][]byte {
 	return s.dataCh
 }
@@ -122,75 +343,166 @@ func (s *Syncer) Stop() (err error) {
 	}
 
 	s.exit()
+	s.resetHealth()
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.cond.Broadcast()
-
 	close(s.dataCh)
 
 	return err
 }
 
-func (s *Syncer) handleDataUpdated() {
-	stopped := func() bool {
-		select {
-		case <-s.ctx.Done():
-			return true
-		default:
-			return false
-		}
+func (s *Syncer) bufferedKeys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.dataBuf))
+	for k := range s.dataBuf {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
-	for !stopped() {
-		s.logger.V("waiting to be signaled")
+	return keys
+}
 
-		s.cond.L.Lock()
-		s.cond.Wait()
+// sendData sends buffered data after transformation, data held for dependencies are kept
+// unless force is true
+func (s *Syncer) sendData(force bool) {
+	// resolved before locking, targets are resolved with locks held when stopping syncers
+	targets := s.writeTargets()
 
-		s.logger.V("signaled")
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-		send := func() bool {
-			s.mu.RLock()
-			defer s.mu.RUnlock()
+	select {
+	case <-s.ctx.Done():
+		// data channel closed
+		return
+	default:
+	}
 
-			for _, k := range s.dataKeys {
-				if _, ok := s.dataBuf[k]; !ok {
-					// not all data key updated
-					return false
-				}
+	batch, held := s.dataBuf, map[string][]byte(nil)
+	if !force {
+		batch, held, _ = splitBatch(s.dependsOn, s.dataBuf, s.dependencySatisfied(targets))
+	}
+
+	if len(batch) == 0 {
+		s.logger.V("data held for dependencies")
+		return
+	}
+
+	if held == nil {
+		held = make(map[string][]byte)
+	}
+
+	d := s.transform(batch)
+	if len(d) == 0 {
+		s.logger.V("no data left after transformation")
+		s.dataBuf = held
+		return
+	}
+
+	select {
+	case <-s.ctx.Done():
+		return
+	case s.dataCh <- d:
+		s.dataBuf = held
+	}
+}
+
+// missingDependencies returns data keys buffered data is held for
+func (s *Syncer) missingDependencies() []string {
+	targets := s.writeTargets()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, _, missing := splitBatch(s.dependsOn, s.dataBuf, s.dependencySatisfied(targets))
+	return missing
+}
+
+// writeTargets returns current write targets of the syncer
+func (s *Syncer) writeTargets() []validator.SyncTarget {
+	if s.targets == nil {
+		return []validator.SyncTarget{validator.SyncTargetFromContext(s.ctx)}
+	}
+
+	return s.targets()
+}
+
+// dependencySatisfied returns a func checking whether a data key not buffered was synced
+// or exists in all write targets
+func (s *Syncer) dependencySatisfied(targets []validator.SyncTarget) func(key string) bool {
+	var targetData []map[string]string
+	return func(key string) bool {
+		s.syncedMu.Lock()
+		_, ok := s.synced[key]
+		s.syncedMu.Unlock()
+		if ok {
+			return true
+		}
+
+		if targetData == nil {
+			targetData = make([]map[string]string, 0, len(targets))
+			for _, t := range targets {
+				vctx := s.newValidationContext(validator.WithSyncTarget(s.ctx, t), "", nil)
+				targetData = append(targetData, vctx.TargetData)
 			}
+		}
 
-			return !stopped()
-		}()
+		if len(targetData) == 0 {
+			return false
+		}
 
-		if send {
-			func() {
-				s.mu.Lock()
-				defer s.mu.Unlock()
-
-				d := s.dataBuf
-				select {
-				case <-s.ctx.Done():
-					return
-				case s.dataCh <- d:
-					s.dataBuf = make(map[string][]byte)
-				}
-			}()
+		for _, d := range targetData {
+			if _, ok = d[key]; !ok {
+				return false
+			}
 		}
 
-		s.cond.L.Unlock()
+		return true
 	}
 }
 
-func (s *Syncer) handleDataRetrievedFromFetcher(ch <-chan map[string][]byte) {
+func (s *Syncer) dropData() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dataBuf = make(map[string][]byte)
+}
+
+// handleDataRetrievedFromFetcher validates data from fetcher and buffers valid data, target
+// dependent validators are evaluated when writing (see ValidateTarget), data keys removed
+// upstream are only buffered (as nil) for replace-all
+func (s *Syncer) handleDataRetrievedFromFetcher(method string, ch <-chan map[string][]byte) {
 	for msg := range ch {
-		data := msg
+		data, removed := splitRemoved(msg)
+		if s.writeStrategy != WriteStrategyReplaceAll {
+			removed = nil
+		}
 
-		for i, v := range s.validators {
-			s.logger.V(fmt.Sprintf("validating with validator %d", i))
-			data = s.processData(v, data)
+		var rejected []string
+		if len(s.validators) != 0 && len(data) != 0 {
+			vctx := s.newValidationContext(s.ctx, method, func() map[string][]byte {
+				s.mu.RLock()
+				defer s.mu.RUnlock()
+
+				buf, _ := splitRemoved(s.dataBuf)
+				return buf
+			}())
+
+			for i, v := range s.validators {
+				if validator.IsTargetDependent(v) {
+					continue
+				}
+
+				s.logger.V(fmt.Sprintf("validating with validator %d", i))
+
+				var r []string
+				data, r = s.processData(v, s.validatorMethods[i], vctx, data)
+				rejected = append(rejected, r...)
+			}
 		}
 
 		func() {
@@ -198,28 +510,183 @@ func (s *Syncer) handleDataRetrievedFromFetcher(ch <-chan map[string][]byte) {
 			defer func() {
 				s.mu.Unlock()
 
-				s.cond.Signal()
+				s.batcher.Notify()
 			}()
 
-			for k, v := range data {
-				s.dataBuf[k] = v
+			if len(rejected) != 0 && s.atomic {
+				s.logger.I("all buffered data rejected due to invalid data", log.Strings("keys", rejected))
+				s.dataBuf = make(map[string][]byte)
+			} else {
+				for k, v := range data {
+					s.dataBuf[k] = v
+				}
+
+				s.rejectDependents(validator.SyncTargetFromContext(s.ctx), rejected, s.dataBuf)
+			}
+
+			for _, k := range removed {
+				s.logger.V(fmt.Sprintf("data for key %q removed upstream", k))
+				s.dataBuf[k] = nil
 			}
 		}()
 	}
 }
 
-func (s *Syncer) processData(p validator.Interface, data map[string][]byte) map[string][]byte {
-	dataMsg := p.Validate(data)
+// transform a copy of buffered data with transformers, data keys removed upstream are kept
+func (s *Syncer) transform(buf map[string][]byte) map[string][]byte {
+	data, removed := splitRemoved(buf)
+	if len(s.transformers) != 0 && len(data) != 0 {
+		var rejected []string
+		vctx := s.newValidationContext(s.ctx, "", data)
+		for i, t := range s.transformers {
+			s.logger.V(fmt.Sprintf("transforming with transformer %d", i))
+
+			var r []string
+			data, r = s.processData(t, s.transformerMethods[i], vctx, data)
+			rejected = append(rejected, r...)
+		}
+
+		if len(rejected) != 0 && s.atomic {
+			s.logger.I("all buffered data rejected due to invalid data", log.Strings("keys", rejected))
+			return nil
+		}
+
+		s.rejectDependents(vctx.Target, rejected, data)
+	}
+
+	for _, k := range removed {
+		data[k] = nil
+	}
+
+	return data
+}
+
+// ValidateTarget validates update for the write target with target dependent validators,
+// returns data to write (data keys removed upstream are kept), nil if any data key rejected
+// in atomic mode
+func (s *Syncer) ValidateTarget(target validator.SyncTarget, update map[string][]byte) map[string][]byte {
+	data, removed := splitRemoved(update)
+
+	var (
+		vctx     *validator.ValidationContext
+		rejected []string
+	)
+	for i, v := range s.validators {
+		if !validator.IsTargetDependent(v) || len(data) == 0 {
+			continue
+		}
+
+		// target data is only retrieved when required
+		if vctx == nil {
+			vctx = s.newValidationContext(validator.WithSyncTarget(s.ctx, target), "", data)
+		}
+
+		s.logger.V(fmt.Sprintf("validating with validator %d", i),
+			log.String("target", target.Namespace+"/"+target.Name))
+
+		var r []string
+		data, r = s.processData(v, s.validatorMethods[i], vctx, data)
+		rejected = append(rejected, r...)
+	}
+
+	if len(rejected) != 0 && s.atomic {
+		s.logger.I("all data rejected for target due to invalid data",
+			log.String("target", target.Namespace+"/"+target.Name), log.Strings("keys", rejected))
+		return nil
+	}
+
+	s.rejectDependents(target, rejected, data)
+
+	for _, k := range removed {
+		data[k] = nil
+	}
+
+	return data
+}
+
+// splitRemoved splits data keys removed upstream (nil value) from a copy of data
+func splitRemoved(data map[string][]byte) (map[string][]byte, []string) {
+	var (
+		ret     = make(map[string][]byte, len(data))
+		removed []string
+	)
+	for k, v := range data {
+		if v == nil {
+			removed = append(removed, k)
+			continue
+		}
+
+		ret[k] = v
+	}
+	sort.Strings(removed)
+
+	return ret, removed
+}
+
+func (s *Syncer) newValidationContext(
+	ctx context.Context, fetcherMethod string, buffered map[string][]byte,
+) *validator.ValidationContext {
+	vctx, err := validator.NewValidationContext(ctx, fetcherMethod, buffered)
+	if err != nil {
+		s.logger.I("failed to get current data of sync target for validation", log.Error(err))
+	}
+
+	return vctx
+}
+
+// rejectDependents removes data keys depending on rejected keys from data
+func (s *Syncer) rejectDependents(target validator.SyncTarget, rejected []string, data map[string][]byte) {
+	for _, k := range dependentsOf(s.dependsOn, rejected) {
+		if _, ok := data[k]; !ok {
+			continue
+		}
+
+		s.logger.I(fmt.Sprintf("data for key %q rejected due to its dependencies", k))
+		delete(data, k)
+
+		if s.onRejected != nil {
+			s.onRejected(target, methodDependsOn, k, fmt.Errorf("depends on rejected data keys %v", rejected))
+		}
+	}
+}
+
+// processData validates data with validator p, returns valid data and rejected data keys
+func (s *Syncer) processData(
+	p validator.Interface,
+	method string,
+	vctx *validator.ValidationContext,
+	data map[string][]byte,
+) (map[string][]byte, []string) {
+	dataMsg := p.Validate(vctx, data)
+
+	if n := len(dataMsg.Data); n != 0 {
+		validDataCounter.Add(s.ctx, int64(n), validatorLabel(method))
+	}
+
+	if n := len(dataMsg.Errors); n != 0 {
+		rejectedDataCounter.Add(s.ctx, int64(n), validatorLabel(method))
+	}
 
 	for k, v := range dataMsg.Data {
 		data[k] = v
 		s.logger.V(fmt.Sprintf("data for key %q is valid", k))
 	}
 
+	for _, k := range dataMsg.Drop {
+		delete(data, k)
+		s.logger.V(fmt.Sprintf("data for key %q dropped", k))
+	}
+
+	var rejected []string
 	for k, v := range dataMsg.Errors {
 		s.logger.I(fmt.Sprintf("data for key %q not valid", k), log.Error(v))
 		delete(data, k)
+		rejected = append(rejected, k)
+
+		if s.onRejected != nil {
+			s.onRejected(vctx.Target, method, k, v)
+		}
 	}
 
-	return data
+	return data, rejected
 }
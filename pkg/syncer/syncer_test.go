@@ -0,0 +1,152 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/fetcher"
+	"arhat.dev/ksync/pkg/validator"
+)
+
+const (
+	testFetcherMethod   = "test-chan"
+	testValidatorMethod = "test-reject-bad"
+)
+
+func init() {
+	fetcher.RegisterFetcher(testFetcherMethod,
+		func(context.Context, log.Interface, *fetcher.Config) (fetcher.Interface, error) {
+			return &testChanFetcher{ch: make(chan map[string][]byte, 4)}, nil
+		},
+	)
+
+	validator.RegisterValidator(testValidatorMethod,
+		func(context.Context, log.Interface, *validator.Config) (validator.Interface, error) {
+			return testRejectBad{}, nil
+		},
+	)
+}
+
+// testChanFetcher sends data pushed to its channel
+type testChanFetcher struct {
+	ch chan map[string][]byte
+}
+
+func (f *testChanFetcher) Start(stop <-chan struct{}) error   { return nil }
+func (f *testChanFetcher) Retrieve() <-chan map[string][]byte { return f.ch }
+func (f *testChanFetcher) Stop() error                        { return nil }
+
+func (f *testChanFetcher) Status() fetcher.Status {
+	return fetcher.Status{Method: testFetcherMethod, Connected: true}
+}
+
+// testRejectBad rejects data with value `bad`
+type testRejectBad struct{}
+
+func (testRejectBad) Validate(vctx *validator.ValidationContext, data map[string][]byte) *validator.DataMsg {
+	result := &validator.DataMsg{
+		Data:   make(map[string][]byte),
+		Errors: make(map[string]error),
+	}
+
+	for k, v := range data {
+		if string(v) == "bad" {
+			result.Errors[k] = fmt.Errorf("bad data")
+			continue
+		}
+
+		result.Data[k] = v
+	}
+
+	return result
+}
+
+func TestSyncerAtomicDependsOn(t *testing.T) {
+	var (
+		rejected []string
+		stalled  []string
+		mu       sync.Mutex
+	)
+
+	s, err := NewSyncer(context.TODO(), log.Log.WithName("test"), &Config{
+		Fetchers:   []*fetcher.Config{{Method: testFetcherMethod}},
+		Validators: []*validator.Config{{Method: testValidatorMethod}},
+		Batch:      fetcher.BatchConfig{MaxWait: 100 * time.Millisecond},
+		Atomic:     true,
+		DependsOn:  map[string][]string{"tls.key": {"tls.crt"}},
+	}, nil, func(_ validator.SyncTarget, _, key string, _ error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		rejected = append(rejected, key)
+	}, func(missing []string, _ string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		stalled = append(stalled, missing...)
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	if err = s.Start(stop); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Stop() }()
+
+	ch := s.fetchers[0].(*testChanFetcher).ch
+	expectNothingSent := func(name string) {
+		select {
+		case d := <-s.Retrieve():
+			t.Fatalf("%s: unexpected data sent %v", name, d)
+		case <-time.After(300 * time.Millisecond):
+		}
+	}
+
+	// one invalid key in the batch, nothing written
+	ch <- map[string][]byte{"tls.crt": []byte("bad"), "tls.key": []byte("key-1")}
+	expectNothingSent("atomic")
+
+	mu.Lock()
+	if len(rejected) != 1 || rejected[0] != "tls.crt" {
+		t.Errorf("unexpected rejected keys %v", rejected)
+	}
+	mu.Unlock()
+
+	if keys := s.bufferedKeys(); len(keys) != 0 {
+		t.Errorf("rejected batch still buffered: %v", keys)
+	}
+
+	// held for the missing dependency and reported as stalled
+	ch <- map[string][]byte{"tls.key": []byte("key-2")}
+	expectNothingSent("held")
+
+	mu.Lock()
+	if len(stalled) != 1 || stalled[0] != "tls.crt" {
+		t.Errorf("unexpected stalled keys %v", stalled)
+	}
+	mu.Unlock()
+
+	if keys := s.Status().StalledKeys; len(keys) != 1 || keys[0] != "tls.crt" {
+		t.Errorf("unexpected stalled keys in status %v", keys)
+	}
+
+	// sent together with the dependency
+	ch <- map[string][]byte{"tls.crt": []byte("cert-2")}
+	select {
+	case d := <-s.Retrieve():
+		if len(d) != 2 || string(d["tls.crt"]) != "cert-2" || string(d["tls.key"]) != "key-2" {
+			t.Errorf("unexpected data sent %v", d)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for data")
+	}
+}
@@ -0,0 +1,127 @@
+package syncer
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Kinds of sync target
+const (
+	TargetKindConfigMap = "configmap"
+	TargetKindSecret    = "secret"
+)
+
+// TargetConfig of the object to write synced data to
+type TargetConfig struct {
+	// Kind of the target, one of configmap, secret, defaults to kind of the annotated object
+	Kind string `json:"kind" yaml:"kind"`
+
+	// Namespace of the target, defaults to namespace of the annotated object, other namespaces
+	// must be allowed in the ksync config
+	Namespace string `json:"namespace" yaml:"namespace"`
+
+	// NamespaceSelector selects namespaces to write the target in, conflicts with Namespace
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector" yaml:"namespaceSelector"`
+
+	// Name of the target, defaults to name of the annotated object
+	Name string `json:"name" yaml:"name"`
+
+	// KeyMapping maps synced data keys to data keys in the target, keys not in the mapping
+	// are written as is, keys mapped to empty string are not written to the target
+	KeyMapping map[string]string `json:"keyMapping" yaml:"keyMapping"`
+
+	// Create the target if not found
+	Create bool `json:"create" yaml:"create"`
+
+	// Labels of the created target
+	Labels map[string]string `json:"labels" yaml:"labels"`
+
+	// Annotations of the created target
+	Annotations map[string]string `json:"annotations" yaml:"annotations"`
+
+	// Type of the created secret
+	Type string `json:"type" yaml:"type"`
+
+	// OwnerReferences of the created target
+	OwnerReferences []metav1.OwnerReference `json:"ownerReferences" yaml:"ownerReferences"`
+}
+
+func validateTarget(config *TargetConfig) error {
+	if config == nil {
+		return nil
+	}
+
+	switch config.Kind {
+	case TargetKindConfigMap:
+		if config.Type != "" {
+			return fmt.Errorf("type is only allowed for secret target")
+		}
+	case "", TargetKindSecret:
+	default:
+		return fmt.Errorf("unknown target kind %q", config.Kind)
+	}
+
+	if config.NamespaceSelector != nil {
+		if config.Namespace != "" {
+			return fmt.Errorf("only one of namespace and namespaceSelector can be set")
+		}
+
+		_, err := config.Selector()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Selector converts namespace selector, returns nil if not set
+func (t *TargetConfig) Selector() (labels.Selector, error) {
+	if t == nil || t.NamespaceSelector == nil {
+		return nil, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(t.NamespaceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespace selector: %w", err)
+	}
+
+	return selector, nil
+}
+
+// MapKeys returns data with keys mapped for the target
+func (t *TargetConfig) MapKeys(data map[string][]byte) map[string][]byte {
+	if t == nil || len(t.KeyMapping) == 0 {
+		return data
+	}
+
+	ret := make(map[string][]byte, len(data))
+	for k, v := range data {
+		newKey, ok := t.KeyMapping[k]
+		switch {
+		case !ok:
+			ret[k] = v
+		case newKey != "":
+			ret[newKey] = v
+		}
+	}
+
+	return ret
+}
+
+// TargetConfigs returns all target configs, a nil config means the annotated object
+func (c *Config) TargetConfigs() []*TargetConfig {
+	var ret []*TargetConfig
+	if c.Target != nil {
+		ret = append(ret, c.Target)
+	}
+
+	ret = append(ret, c.Targets...)
+	if len(ret) == 0 {
+		ret = append(ret, nil)
+	}
+
+	return ret
+}
@@ -0,0 +1,132 @@
+package syncer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Write strategies of synced data
+const (
+	// WriteStrategyMergeKeys adds or overwrites synced data keys, other keys untouched (default)
+	WriteStrategyMergeKeys = "merge-keys"
+
+	// WriteStrategyReplaceAll replaces all data with synced data, keys not synced (since the
+	// syncer started) or removed upstream are removed
+	WriteStrategyReplaceAll = "replace-all"
+
+	// WriteStrategyDeepMergeYAML deep merges synced yaml documents into existing ones
+	WriteStrategyDeepMergeYAML = "deep-merge-yaml"
+
+	// WriteStrategyDeepMergeJSON deep merges synced json documents into existing ones
+	WriteStrategyDeepMergeJSON = "deep-merge-json"
+
+	// WriteStrategyAppend appends synced data to existing data
+	WriteStrategyAppend = "append"
+)
+
+func validateWriteStrategy(strategy string) error {
+	switch strategy {
+	case "", WriteStrategyMergeKeys, WriteStrategyReplaceAll,
+		WriteStrategyDeepMergeYAML, WriteStrategyDeepMergeJSON, WriteStrategyAppend:
+		return nil
+	default:
+		return fmt.Errorf("unknown write strategy %q", strategy)
+	}
+}
+
+// MergeData merges synced data (update) into current data of the sync target with write strategy,
+// protected keys in current data are always kept as is
+func MergeData(
+	strategy string,
+	protectedKeys []string,
+	current, update map[string][]byte,
+) (map[string][]byte, error) {
+	protected := make(map[string]struct{}, len(protectedKeys))
+	for _, k := range protectedKeys {
+		protected[k] = struct{}{}
+	}
+
+	result := make(map[string][]byte, len(current)+len(update))
+	for k, v := range current {
+		if _, ok := protected[k]; ok || strategy != WriteStrategyReplaceAll {
+			result[k] = v
+		}
+	}
+
+	for k, d := range update {
+		if _, ok := protected[k]; ok {
+			continue
+		}
+
+		old, exists := current[k]
+		if !exists {
+			result[k] = d
+			continue
+		}
+
+		switch strategy {
+		case WriteStrategyDeepMergeYAML, WriteStrategyDeepMergeJSON:
+			merged, err := deepMergeDocuments(strategy == WriteStrategyDeepMergeJSON, old, d)
+			if err != nil {
+				return nil, fmt.Errorf("failed to deep merge data for key %q: %w", k, err)
+			}
+
+			result[k] = merged
+		case WriteStrategyAppend:
+			result[k] = append(append(make([]byte, 0, len(old)+len(d)), old...), d...)
+		default:
+			result[k] = d
+		}
+	}
+
+	return result, nil
+}
+
+func deepMergeDocuments(isJSON bool, old, d []byte) ([]byte, error) {
+	var oldDoc, newDoc interface{}
+
+	err := yaml.Unmarshal(old, &oldDoc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid existing document: %w", err)
+	}
+
+	err = yaml.Unmarshal(d, &newDoc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid synced document: %w", err)
+	}
+
+	oldMap, ok1 := oldDoc.(map[string]interface{})
+	newMap, ok2 := newDoc.(map[string]interface{})
+	if !ok1 || !ok2 {
+		// only objects can be merged
+		return d, nil
+	}
+
+	merged := deepMerge(oldMap, newMap)
+	if isJSON {
+		return json.Marshal(merged)
+	}
+
+	return yaml.Marshal(merged)
+}
+
+// deepMerge src into dst, objects are merged recursively, other values in src replace ones in dst
+func deepMerge(dst, src interface{}) interface{} {
+	dstMap, ok1 := dst.(map[string]interface{})
+	srcMap, ok2 := src.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return src
+	}
+
+	for k, v := range srcMap {
+		if old, ok := dstMap[k]; ok {
+			dstMap[k] = deepMerge(old, v)
+		} else {
+			dstMap[k] = v
+		}
+	}
+
+	return dstMap
+}
@@ -0,0 +1,210 @@
+package syncer
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/fetcher"
+)
+
+func TestMergeData(t *testing.T) {
+	current := map[string][]byte{
+		"app.yaml":  []byte("db:\n  host: a\n  port: 5432\nname: foo\n"),
+		"app.json":  []byte(`{"db": {"host": "a", "port": 5432}}`),
+		"log.txt":   []byte("line1\n"),
+		"owned.txt": []byte("old"),
+		"local.txt": []byte("local"),
+	}
+
+	update := map[string][]byte{
+		"app.yaml":  []byte("db:\n  host: b\n"),
+		"app.json":  []byte(`{"db": {"host": "b"}}`),
+		"log.txt":   []byte("line2\n"),
+		"local.txt": []byte("synced"),
+	}
+
+	tests := []struct {
+		name     string
+		strategy string
+		expected map[string]string
+	}{
+		{
+			name:     "Merge Keys",
+			strategy: "",
+			expected: map[string]string{
+				"app.yaml":  "db:\n  host: b\n",
+				"app.json":  `{"db": {"host": "b"}}`,
+				"log.txt":   "line2\n",
+				"owned.txt": "old",
+				"local.txt": "local",
+			},
+		},
+		{
+			name:     "Replace All",
+			strategy: WriteStrategyReplaceAll,
+			expected: map[string]string{
+				"app.yaml":  "db:\n  host: b\n",
+				"app.json":  `{"db": {"host": "b"}}`,
+				"log.txt":   "line2\n",
+				"local.txt": "local",
+			},
+		},
+		{
+			name:     "Deep Merge YAML",
+			strategy: WriteStrategyDeepMergeYAML,
+			expected: map[string]string{
+				"app.yaml":  "db:\n  host: b\n  port: 5432\nname: foo\n",
+				"app.json":  "db:\n  host: b\n  port: 5432\n",
+				"log.txt":   "line2\n",
+				"owned.txt": "old",
+				"local.txt": "local",
+			},
+		},
+		{
+			name:     "Append",
+			strategy: WriteStrategyAppend,
+			expected: map[string]string{
+				"app.yaml":  "db:\n  host: a\n  port: 5432\nname: foo\ndb:\n  host: b\n",
+				"app.json":  `{"db": {"host": "a", "port": 5432}}{"db": {"host": "b"}}`,
+				"log.txt":   "line1\nline2\n",
+				"owned.txt": "old",
+				"local.txt": "local",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := MergeData(test.strategy, []string{"local.txt"}, current, update)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result) != len(test.expected) {
+				t.Errorf("expected %d keys, got %d", len(test.expected), len(result))
+			}
+
+			for k, exp := range test.expected {
+				if actual := string(result[k]); actual != exp {
+					t.Errorf("unexpected %s:\nexpected: %q\nactual:   %q", k, exp, actual)
+				}
+			}
+		})
+	}
+
+	result, err := MergeData(WriteStrategyDeepMergeJSON, nil, current, map[string][]byte{
+		"app.json": []byte(`{"db": {"host": "b"}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := string(result["app.json"]); actual != `{"db":{"host":"b","port":5432}}` {
+		t.Errorf("unexpected deep merged json: %q", actual)
+	}
+
+	_, err = MergeData(WriteStrategyDeepMergeJSON, nil, current, map[string][]byte{"log.txt": []byte("{")})
+	if err == nil {
+		t.Errorf("expected error when merging invalid documents")
+	}
+}
+
+func TestSyncerMergeReplaceAll(t *testing.T) {
+	s := &Syncer{
+		writeStrategy: WriteStrategyReplaceAll,
+		protectedKeys: []string{"local.txt"},
+		synced:        make(map[string][]byte),
+		syncedMu:      new(sync.Mutex),
+	}
+
+	current := map[string][]byte{"local.txt": []byte("local"), "stale.txt": []byte("stale")}
+
+	result, err := s.Merge(current, map[string][]byte{"a": []byte("1"), "b": []byte("2")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only part of data keys in this batch
+	result, err = s.Merge(result, map[string][]byte{"b": []byte("3")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"a": "1", "b": "3", "local.txt": "local"}
+	if len(result) != len(expected) {
+		t.Fatalf("unexpected result %v", result)
+	}
+
+	for k, v := range expected {
+		if string(result[k]) != v {
+			t.Errorf("unexpected data for key %q: %q", k, result[k])
+		}
+	}
+}
+
+func newTestSyncer(writeStrategy string) *Syncer {
+	return &Syncer{
+		ctx:           context.TODO(),
+		logger:        log.Log.WithName("test"),
+		writeStrategy: writeStrategy,
+		synced:        make(map[string][]byte),
+		syncedMu:      new(sync.Mutex),
+		dataBuf:       make(map[string][]byte),
+		mu:            new(sync.RWMutex),
+		batcher:       fetcher.NewBatcher(nil, fetcher.BatchConfig{}),
+		dataCh:        make(chan map[string][]byte, 1),
+	}
+}
+
+func TestSyncerRemovedKeys(t *testing.T) {
+	s := newTestSyncer(WriteStrategyReplaceAll)
+
+	result, err := s.Merge(nil, map[string][]byte{"a": []byte("1"), "b": []byte("2")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan map[string][]byte, 1)
+	ch <- map[string][]byte{"a": nil}
+	close(ch)
+	s.handleDataRetrievedFromFetcher("redis", ch)
+
+	s.sendData(true)
+	update := <-s.dataCh
+	if v, ok := update["a"]; !ok || v != nil {
+		t.Fatalf("expected removal of key a, got %v", update)
+	}
+
+	result, err = s.Merge(result, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result) != 1 || string(result["b"]) != "2" {
+		t.Errorf("unexpected result %v", result)
+	}
+
+	// removal is not synced with other write strategies
+	s = newTestSyncer(WriteStrategyMergeKeys)
+
+	ch = make(chan map[string][]byte, 1)
+	ch <- map[string][]byte{"a": nil, "b": []byte("3")}
+	close(ch)
+	s.handleDataRetrievedFromFetcher("redis", ch)
+
+	if keys := s.bufferedKeys(); len(keys) != 1 || keys[0] != "b" {
+		t.Errorf("unexpected buffered keys %v", keys)
+	}
+
+	result, err = s.Merge(map[string][]byte{"a": []byte("1")}, map[string][]byte{"a": nil, "b": []byte("3")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result) != 2 || string(result["a"]) != "1" || string(result["b"]) != "3" {
+		t.Errorf("unexpected result %v", result)
+	}
+}
@@ -0,0 +1,222 @@
+package validator
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"arhat.dev/pkg/envhelper"
+	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/dataref"
+)
+
+func init() {
+	RegisterValidator(MethodDecrypt, NewDecryptValidator)
+}
+
+const (
+	MethodDecrypt = "decrypt"
+)
+
+const (
+	DecryptFormatAge  = "age"
+	DecryptFormatSOPS = "sops"
+)
+
+const (
+	// key of age identities (age key file format) in the identity secret
+	ageIdentityKey = "age.agekey"
+
+	// key of base64 encoded sops data key in the identity secret
+	sopsDataKeyIdentityKey = "sops.datakey"
+)
+
+type DecryptConfig struct {
+	// Format of encrypted data, one of age, sops
+	Format string `json:"format" yaml:"format"`
+
+	// Schema of sops documents, one of json, yaml, detected from data if not set
+	Schema string `json:"schema" yaml:"schema"`
+
+	// IdentitySecret is the name of the secret in ksync's namespace containing identities
+	//
+	// X25519 age identities are expected in key `age.agekey`, for sops, data key is unwrapped
+	// from age recipients using these identities, or the base64 encoded data key in key `sops.datakey`
+	// is used directly
+	IdentitySecret string `json:"identitySecret" yaml:"identitySecret"`
+}
+
+func NewDecryptValidator(ctx context.Context, logger log.Interface, config *Config) (Interface, error) {
+	if config.Decrypt == nil {
+		return nil, fmt.Errorf("no decrypt validator configuration provided")
+	}
+
+	switch config.Decrypt.Format {
+	case DecryptFormatSOPS, DecryptFormatAge:
+	default:
+		return nil, fmt.Errorf("unsupported encryption format %q", config.Decrypt.Format)
+	}
+
+	switch config.Decrypt.Schema {
+	case "", TextSchemaJSON, TextSchemaYAML:
+	default:
+		return nil, fmt.Errorf("unsupported schema %q for sops documents", config.Decrypt.Schema)
+	}
+
+	if config.Decrypt.IdentitySecret == "" {
+		return nil, fmt.Errorf("no identity secret provided")
+	}
+
+	getter := dataref.GetterFromContext(ctx)
+	if getter == nil {
+		return nil, fmt.Errorf("unable to load identities: no data getter available")
+	}
+
+	getIdentity := func(key string) ([]byte, error) {
+		data, err := getter.Get(ctx, &dataref.Ref{
+			Kind:      dataref.KindSecret,
+			Namespace: envhelper.ThisPodNS(),
+			Name:      config.Decrypt.IdentitySecret,
+			Key:       key,
+		})
+		if err != nil && !errors.Is(err, dataref.ErrNotFound) {
+			return nil, err
+		}
+
+		return data, nil
+	}
+
+	v := &DecryptValidator{
+		dataKeys: config.DataKeys,
+		format:   config.Decrypt.Format,
+		schema:   config.Decrypt.Schema,
+	}
+
+	ageKeys, err := getIdentity(ageIdentityKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load age identities: %w", err)
+	}
+
+	if len(ageKeys) != 0 {
+		v.identities, err = parseAgeIdentities(ageKeys)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age identities: %w", err)
+		}
+	}
+
+	if v.format == DecryptFormatSOPS {
+		var encodedKey []byte
+		encodedKey, err = getIdentity(sopsDataKeyIdentityKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load sops data key: %w", err)
+		}
+
+		if len(encodedKey) != 0 {
+			v.dataKey, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encodedKey)))
+			if err != nil {
+				return nil, fmt.Errorf("invalid sops data key encoding: %w", err)
+			}
+		}
+	}
+
+	if len(v.identities) == 0 && len(v.dataKey) == 0 {
+		return nil, fmt.Errorf("no identity found in secret %q", config.Decrypt.IdentitySecret)
+	}
+
+	return v, nil
+}
+
+// DecryptValidator decrypts age encrypted data or sops documents, data is rejected when failed to decrypt
+type DecryptValidator struct {
+	dataKeys []string
+	format   string
+	schema   string
+
+	identities []*ageIdentity
+	dataKey    []byte
+}
+
+func (v *DecryptValidator) Validate(vctx *ValidationContext, data map[string][]byte) *DataMsg {
+	result := &DataMsg{
+		Data:   make(map[string][]byte),
+		Errors: make(map[string]error),
+	}
+
+	for _, k := range v.dataKeys {
+		d, ok := data[k]
+		if !ok {
+			continue
+		}
+
+		var (
+			plaintext []byte
+			err       error
+		)
+		switch v.format {
+		case DecryptFormatAge:
+			plaintext, err = v.decryptAge(d)
+		case DecryptFormatSOPS:
+			plaintext, err = v.decryptSOPS(d)
+		}
+		if err != nil {
+			result.Errors[k] = fmt.Errorf("failed to decrypt %s data: %w", v.format, err)
+			continue
+		}
+
+		result.Data[k] = plaintext
+	}
+
+	return result
+}
+
+func (v *DecryptValidator) decryptAge(d []byte) ([]byte, error) {
+	if len(v.identities) == 0 {
+		return nil, fmt.Errorf("no age identity available")
+	}
+
+	return decryptAge(v.identities, d)
+}
+
+func (v *DecryptValidator) decryptSOPS(d []byte) ([]byte, error) {
+	schema := v.schema
+	if schema == "" {
+		schema = TextSchemaYAML
+		if bytes.HasPrefix(bytes.TrimSpace(d), []byte("{")) {
+			schema = TextSchemaJSON
+		}
+	}
+
+	doc, err := parseSOPSDocument(d)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey := v.dataKey
+	if len(v.identities) != 0 && len(doc.metadata.Age) != 0 {
+		var unwrapErr error
+		dataKey, unwrapErr = doc.unwrapAgeDataKey(v.identities)
+		if unwrapErr != nil {
+			if len(v.dataKey) == 0 {
+				return nil, unwrapErr
+			}
+
+			// fallback to the configured data key
+			dataKey = v.dataKey
+		}
+	}
+
+	if len(dataKey) == 0 {
+		// unwrapping data key with pgp or kms is not supported
+		return nil, fmt.Errorf("no age recipient matched and no data key provided")
+	}
+
+	err = doc.decrypt(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.marshal(schema)
+}
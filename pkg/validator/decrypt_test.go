@@ -0,0 +1,281 @@
+package validator
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/dataref"
+)
+
+type testDataGetter map[string][]byte
+
+func (g testDataGetter) Get(_ context.Context, ref *dataref.Ref) ([]byte, error) {
+	d, ok := g[ref.Name+"/"+ref.Key]
+	if !ok {
+		return nil, dataref.ErrNotFound
+	}
+	return d, nil
+}
+
+type testObjectGetter map[string]*dataref.Object
+
+func (g testObjectGetter) Get(ctx context.Context, ref *dataref.Ref) ([]byte, error) {
+	obj, err := g.GetObject(ctx, ref.Kind, ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	d, ok := obj.Data[ref.Key]
+	if !ok {
+		return nil, dataref.ErrNotFound
+	}
+	return d, nil
+}
+
+func (g testObjectGetter) GetObject(_ context.Context, _ dataref.Kind, _, name string) (*dataref.Object, error) {
+	obj, ok := g[name]
+	if !ok {
+		return nil, dataref.ErrNotFound
+	}
+	return obj, nil
+}
+
+func encryptSOPSValue(t *testing.T, gcm cipher.AEAD, value, valueType, additionalData string) string {
+	iv := make([]byte, gcm.NonceSize())
+	_, err := rand.Read(iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed := gcm.Seal(nil, iv, []byte(value), []byte(additionalData))
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag),
+		valueType,
+	)
+}
+
+func TestDecryptValidator(t *testing.T) {
+	dataKey := make([]byte, 32)
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gcm, err := newSOPSCipher(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := sha512.New()
+	for _, v := range []string{"bar", "10", "True", "plain"} {
+		_, _ = h.Write([]byte(v))
+	}
+
+	const lastModified = "2020-11-20T08:00:00Z"
+	doc := fmt.Sprintf(`foo:
+  bar: %s
+  num: %s
+list:
+- %s
+name_unencrypted: plain
+sops:
+  lastmodified: "%s"
+  mac: %s
+  unencrypted_suffix: _unencrypted
+`,
+		encryptSOPSValue(t, gcm, "bar", "str", "foo:bar:"),
+		encryptSOPSValue(t, gcm, "10", "int", "foo:num:"),
+		encryptSOPSValue(t, gcm, "True", "bool", "list:"),
+		lastModified,
+		encryptSOPSValue(t, gcm, fmt.Sprintf("%X", h.Sum(nil)), "str", lastModified),
+	)
+
+	ctx := dataref.WithGetter(context.TODO(), testDataGetter{
+		"identities/sops.datakey": []byte(base64.StdEncoding.EncodeToString(dataKey)),
+	})
+
+	v, err := NewDecryptValidator(ctx, log.Log.WithName("test"), &Config{
+		Method:   MethodDecrypt,
+		DataKeys: []string{"good", "tampered"},
+		Decrypt: &DecryptConfig{
+			Format:         DecryptFormatSOPS,
+			IdentitySecret: "identities",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := v.Validate(&ValidationContext{}, map[string][]byte{
+		"good":     []byte(doc),
+		"tampered": []byte(strings.Replace(doc, "plain", "changed", 1)),
+	})
+
+	expected := "foo:\n  bar: bar\n  num: 10\nlist:\n- true\nname_unencrypted: plain\n"
+	if string(result.Data["good"]) != expected {
+		t.Errorf("unexpected decrypted data %q, error: %v", result.Data["good"], result.Errors["good"])
+	}
+
+	if _, ok := result.Data["tampered"]; ok || result.Errors["tampered"] == nil {
+		t.Errorf("expected tampered document rejected")
+	}
+
+	_, err = NewDecryptValidator(ctx, nil, &Config{
+		Decrypt: &DecryptConfig{Format: DecryptFormatAge, IdentitySecret: "identities"},
+	})
+	if err == nil {
+		t.Errorf("expected error for age format without age identities")
+	}
+}
+
+func readTestData(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// fixtures in testdata are generated with filippo.io/age and sops v3 document layout
+func newTestAgeDecryptValidator(t *testing.T, format string, identities testDataGetter) Interface {
+	v, err := NewDecryptValidator(dataref.WithGetter(context.TODO(), identities), log.Log.WithName("test"), &Config{
+		Method:   MethodDecrypt,
+		DataKeys: []string{"a", "b", "c"},
+		Decrypt: &DecryptConfig{
+			Format:         format,
+			IdentitySecret: "identities",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
+
+func TestDecryptValidatorAge(t *testing.T) {
+	v := newTestAgeDecryptValidator(t, DecryptFormatAge, testDataGetter{
+		"identities/age.agekey": readTestData(t, "age.agekey"),
+	})
+
+	large := make([]byte, 150000)
+	for i := range large {
+		large[i] = byte(i % 251)
+	}
+
+	result := v.Validate(&ValidationContext{}, map[string][]byte{
+		// armored, encrypted to two recipients
+		"a": readTestData(t, "age-armored.txt"),
+		// binary, multiple chunks
+		"b": readTestData(t, "age-large.age"),
+		// encrypted to other recipient
+		"c": readTestData(t, "age-other.age"),
+	})
+
+	if d := string(result.Data["a"]); d != "hello: world\n" {
+		t.Errorf("unexpected decrypted armored data %q, error: %v", d, result.Errors["a"])
+	}
+
+	if d := result.Data["b"]; !bytes.Equal(d, large) {
+		t.Errorf("unexpected decrypted data of size %d, error: %v", len(d), result.Errors["b"])
+	}
+
+	if _, ok := result.Data["c"]; ok || result.Errors["c"] == nil {
+		t.Errorf("expected data for other recipient rejected")
+	}
+
+	tampered := readTestData(t, "age-large.age")
+	tampered[len(tampered)-1] ^= 1
+	result = v.Validate(&ValidationContext{}, map[string][]byte{"a": tampered})
+	if result.Errors["a"] == nil {
+		t.Errorf("expected tampered data rejected")
+	}
+}
+
+func TestDecryptValidatorSOPSAge(t *testing.T) {
+	identities := testDataGetter{
+		"identities/age.agekey": readTestData(t, "age.agekey"),
+	}
+	doc := readTestData(t, "sops-age.yaml")
+
+	v := newTestAgeDecryptValidator(t, DecryptFormatSOPS, identities)
+	result := v.Validate(&ValidationContext{}, map[string][]byte{
+		"a": doc,
+		"b": bytes.Replace(doc, []byte("name_unencrypted: plain"), []byte("name_unencrypted: changed"), 1),
+	})
+
+	expected := "foo:\n  bar: secret\n  num: 10\nlist:\n- true\nname_unencrypted: plain\n"
+	if string(result.Data["a"]) != expected {
+		t.Errorf("unexpected decrypted data %q, error: %v", result.Data["a"], result.Errors["a"])
+	}
+
+	if _, ok := result.Data["b"]; ok || result.Errors["b"] == nil {
+		t.Errorf("expected tampered document rejected")
+	}
+
+	// identities not matching any recipient
+	other := make([]byte, 32)
+	_, err := rand.Read(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v = newTestAgeDecryptValidator(t, DecryptFormatSOPS, testDataGetter{
+		"identities/sops.datakey": []byte(base64.StdEncoding.EncodeToString(other)),
+	})
+	result = v.Validate(&ValidationContext{}, map[string][]byte{"a": doc})
+	if result.Errors["a"] == nil {
+		t.Errorf("expected error with wrong data key")
+	}
+}
+
+func TestBech32Decode(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		s       string
+		hrp     string
+		data    []byte
+		invalid bool
+	}{
+		{name: "Empty Data", s: "A12UEL5L", hrp: "a"},
+		{name: "Upper Case", s: "ABCDEF1QPZRY9X8GF2TVDW0S3JN54KHCE6MUA7LMQQQXW", hrp: "abcdef", data: []byte{
+			0x00, 0x44, 0x32, 0x14, 0xc7, 0x42, 0x54, 0xb6, 0x35, 0xcf, 0x84, 0x65, 0x3a, 0x56, 0xd7, 0xc6, 0x75, 0xbe, 0x77, 0xdf,
+		}},
+		{name: "Mixed Case", s: "a12UEL5L", invalid: true},
+		{name: "Bad Checksum", s: "a12uel5m", invalid: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			hrp, data, err := bech32Decode(test.s)
+			if test.invalid {
+				if err == nil {
+					t.Errorf("expected error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if hrp != test.hrp || !bytes.Equal(data, test.data) {
+				t.Errorf("unexpected result %q %x", hrp, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,449 @@
+package validator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"arhat.dev/pkg/log"
+)
+
+func init() {
+	RegisterValidator(MethodExec, NewExecValidator)
+}
+
+const (
+	MethodExec = "exec"
+)
+
+const (
+	ExecInputStdin = "stdin"
+	ExecInputFile  = "file"
+)
+
+const (
+	defaultExecTimeout        = 30 * time.Second
+	defaultExecMaxOutputBytes = 1024 * 1024
+
+	// time to wait for remaining output after the command exited
+	execOutputDrainTimeout = time.Second
+)
+
+type ExecConfig struct {
+	// Command to run, args support templating with extra `.DataFile` (when input is file)
+	Command []string `json:"command" yaml:"command"`
+
+	// Input of data, one of stdin, file (defaults to stdin)
+	Input string `json:"input" yaml:"input"`
+
+	// Env of the command (with template support), only PATH and HOME are inherited from ksync
+	// and the data key is available as `KSYNC_DATA_KEY` (data file as `KSYNC_DATA_FILE`)
+	Env NameValuePairs `json:"env" yaml:"env"`
+
+	// Timeout of the command, defaults to 30s
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+
+	// StdoutAsData to use stdout of the command as data
+	StdoutAsData bool `json:"stdoutAsData" yaml:"stdoutAsData"`
+
+	// MaxOutputBytes of stdout and stderr each, defaults to 1MiB
+	MaxOutputBytes int `json:"maxOutputBytes" yaml:"maxOutputBytes"`
+
+	// Limits of resources (linux only)
+	Limits ExecLimits `json:"limits" yaml:"limits"`
+}
+
+type ExecLimits struct {
+	// CPUTime in seconds (RLIMIT_CPU)
+	CPUTime uint64 `json:"cpuTime" yaml:"cpuTime"`
+
+	// MemoryBytes of address space (RLIMIT_AS)
+	MemoryBytes uint64 `json:"memoryBytes" yaml:"memoryBytes"`
+
+	// FileSizeBytes of files created (RLIMIT_FSIZE)
+	FileSizeBytes uint64 `json:"fileSizeBytes" yaml:"fileSizeBytes"`
+
+	// OpenFiles count (RLIMIT_NOFILE)
+	OpenFiles uint64 `json:"openFiles" yaml:"openFiles"`
+}
+
+func (l *ExecLimits) empty() bool {
+	return l.CPUTime == 0 && l.MemoryBytes == 0 && l.FileSizeBytes == 0 && l.OpenFiles == 0
+}
+
+// variables used when evaluating templates of exec validator
+type execTemplateVars struct {
+	*templateVars
+
+	DataFile string
+}
+
+func NewExecValidator(ctx context.Context, logger log.Interface, config *Config) (Interface, error) {
+	if config.Exec == nil {
+		return nil, fmt.Errorf("no exec validator configuration provided")
+	}
+
+	if len(config.Exec.Command) == 0 {
+		return nil, fmt.Errorf("no command provided")
+	}
+
+	input := config.Exec.Input
+	switch input {
+	case "":
+		input = ExecInputStdin
+	case ExecInputStdin, ExecInputFile:
+	default:
+		return nil, fmt.Errorf("unsupported input %q", input)
+	}
+
+	if !config.Exec.Limits.empty() && !resourceLimitsSupported {
+		return nil, fmt.Errorf("resource limits are not supported on this platform")
+	}
+
+	var cmdTpls []*template.Template
+	for _, c := range config.Exec.Command {
+		tpl, err := template.New("").Funcs(funcMapWithJQ()).Parse(c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse command %q as template: %w", c, err)
+		}
+
+		cmdTpls = append(cmdTpls, tpl)
+	}
+
+	env, err := config.Exec.Env.ToNameValueTemplatePairs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse env as template: %w", err)
+	}
+
+	timeout := config.Exec.Timeout
+	if timeout <= 0 {
+		timeout = defaultExecTimeout
+	}
+
+	maxOutput := config.Exec.MaxOutputBytes
+	if maxOutput <= 0 {
+		maxOutput = defaultExecMaxOutputBytes
+	}
+
+	return &ExecValidator{
+		ctx:      ctx,
+		logger:   logger,
+		dataKeys: config.DataKeys,
+
+		cmdTpls:      cmdTpls,
+		input:        input,
+		env:          env,
+		timeout:      timeout,
+		stdoutAsData: config.Exec.StdoutAsData,
+		maxOutput:    maxOutput,
+		limits:       config.Exec.Limits,
+	}, nil
+}
+
+// ExecValidator validates data with local command, data is valid when the command exited
+// with code 0
+type ExecValidator struct {
+	ctx      context.Context
+	logger   log.Interface
+	dataKeys []string
+
+	cmdTpls      []*template.Template
+	input        string
+	env          NameValueTemplatePairs
+	timeout      time.Duration
+	stdoutAsData bool
+	maxOutput    int
+	limits       ExecLimits
+}
+
+func (e *ExecValidator) Validate(vctx *ValidationContext, data map[string][]byte) *DataMsg {
+	result := &DataMsg{
+		Data:   make(map[string][]byte),
+		Errors: make(map[string]error),
+	}
+
+	for _, k := range e.dataKeys {
+		d, ok := data[k]
+		if !ok {
+			continue
+		}
+
+		stdout, err := e.run(&templateVars{
+			DataKeys: e.dataKeys,
+			DataKey:  k,
+			Data:     d,
+			Context:  vctx,
+		})
+		if err != nil {
+			result.Errors[k] = err
+			continue
+		}
+
+		if e.stdoutAsData {
+			result.Data[k] = stdout
+		} else {
+			result.Data[k] = d
+		}
+	}
+
+	return result
+}
+
+func (e *ExecValidator) run(tplVars *templateVars) (_ []byte, err error) {
+	vars := &execTemplateVars{templateVars: tplVars}
+
+	if e.input == ExecInputFile {
+		var dir string
+		dir, err = ioutil.TempDir("", "ksync-exec-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary dir: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(dir) }()
+
+		// keep the data key as file name since some tools check file extension
+		vars.DataFile = filepath.Join(dir, filepath.Base(filepath.Clean("/"+tplVars.DataKey)))
+		err = ioutil.WriteFile(vars.DataFile, tplVars.Data, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write data file: %w", err)
+		}
+	}
+
+	args := make([]string, len(e.cmdTpls))
+	for i, tpl := range e.cmdTpls {
+		buf := new(bytes.Buffer)
+		err = tpl.Execute(buf, vars)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute command template: %w", err)
+		}
+		args[i] = buf.String()
+	}
+
+	envMap, err := e.env.EvalAndConvertToStringInterfacesMap(vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to eval env: %w", err)
+	}
+
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + os.Getenv("HOME"),
+		"KSYNC_DATA_KEY=" + tplVars.DataKey,
+	}
+	if vars.DataFile != "" {
+		env = append(env, "KSYNC_DATA_FILE="+vars.DataFile)
+	}
+	for name, values := range envMap {
+		if len(values) != 0 {
+			env = append(env, fmt.Sprintf("%s=%v", name, values[0]))
+		}
+	}
+
+	var cmd *exec.Cmd
+	if e.limits.empty() {
+		// nolint:gosec
+		cmd = exec.Command(args[0], args[1:]...)
+	} else {
+		cmd, err = commandWithResourceLimits(args, &e.limits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start command: %w", err)
+		}
+	}
+	cmd.Env = env
+	setProcessGroup(cmd)
+
+	var stdin []byte
+	if e.input == ExecInputStdin {
+		stdin = tplVars.Data
+	}
+
+	stdio, err := newExecIO(cmd, stdin, e.maxOutput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pipes: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, e.timeout)
+	defer cancel()
+
+	err = cmd.Start()
+	stdio.started()
+	if err != nil {
+		stdio.finish(0)
+		return nil, fmt.Errorf("failed to start command: %w", err)
+	}
+
+	exited, killerStopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(killerStopped)
+
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd.Process)
+		case <-exited:
+		}
+	}()
+
+	err = waitCommand(cmd, func() {
+		close(exited)
+		<-killerStopped
+	})
+	stdout, stderr := stdio.finish(execOutputDrainTimeout)
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("command timed out after %s", e.timeout)
+	}
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("failed to run command: %w", err)
+		}
+
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+
+		return nil, fmt.Errorf("command exited with code %d: %s", exitErr.ExitCode(), msg)
+	}
+
+	if stdout.truncated && e.stdoutAsData {
+		return nil, fmt.Errorf("stdout exceeded %d bytes", e.maxOutput)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// execIO connects stdio of the command with pipes owned by the validator instead of exec.Cmd,
+// so waiting for the command doesn't block on pipes inherited by its child processes
+type execIO struct {
+	// child side of pipes, closed once the command started
+	childFiles []*os.File
+	// our side of pipes
+	files []*os.File
+
+	stdout *limitedBuffer
+	stderr *limitedBuffer
+
+	wg   sync.WaitGroup
+	done chan struct{}
+}
+
+func newExecIO(cmd *exec.Cmd, stdin []byte, maxOutput int) (_ *execIO, err error) {
+	s := &execIO{
+		stdout: &limitedBuffer{max: maxOutput},
+		stderr: &limitedBuffer{max: maxOutput},
+		done:   make(chan struct{}),
+	}
+	defer func() {
+		if err != nil {
+			s.closeAll()
+		}
+	}()
+
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	s.files, s.childFiles = append(s.files, stdoutR), append(s.childFiles, stdoutW)
+
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	s.files, s.childFiles = append(s.files, stderrR), append(s.childFiles, stderrW)
+
+	cmd.Stdout, cmd.Stderr = stdoutW, stderrW
+
+	copyOutput := func(dst *limitedBuffer, src *os.File) {
+		defer s.wg.Done()
+
+		_, _ = io.Copy(dst, src)
+	}
+
+	s.wg.Add(2)
+	go copyOutput(s.stdout, stdoutR)
+	go copyOutput(s.stderr, stderrR)
+
+	if stdin != nil {
+		var stdinR, stdinW *os.File
+		stdinR, stdinW, err = os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		s.files, s.childFiles = append(s.files, stdinW), append(s.childFiles, stdinR)
+
+		cmd.Stdin = stdinR
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+
+			_, _ = stdinW.Write(stdin)
+			_ = stdinW.Close()
+		}()
+	}
+
+	go func() {
+		s.wg.Wait()
+		close(s.done)
+	}()
+
+	return s, nil
+}
+
+// started closes child side of pipes in this process
+func (s *execIO) started() {
+	for _, f := range s.childFiles {
+		_ = f.Close()
+	}
+}
+
+// finish waits until output drained or timeout, then closes all pipes
+func (s *execIO) finish(timeout time.Duration) (stdout, stderr *limitedBuffer) {
+	select {
+	case <-s.done:
+	case <-time.After(timeout):
+	}
+
+	s.closeAll()
+	<-s.done
+
+	return s.stdout, s.stderr
+}
+
+func (s *execIO) closeAll() {
+	for _, f := range append(s.childFiles, s.files...) {
+		_ = f.Close()
+	}
+}
+
+// limitedBuffer discards data written after max bytes
+type limitedBuffer struct {
+	bytes.Buffer
+
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if remain := b.max - b.Len(); remain < len(p) {
+		b.truncated = true
+		if remain <= 0 {
+			return n, nil
+		}
+		p = p[:remain]
+	}
+
+	_, _ = b.Buffer.Write(p)
+	return n, nil
+}
@@ -0,0 +1,142 @@
+//go:build linux
+// +build linux
+
+package validator
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const resourceLimitsSupported = true
+
+// argv[0] of ksync executed as a shim applying resource limits right before executing the command
+const execLimitsShimArgv0 = "ksync-exec-limits"
+
+// resources in the order of ExecLimits.values()
+var execLimitResources = []int{unix.RLIMIT_CPU, unix.RLIMIT_AS, unix.RLIMIT_FSIZE, unix.RLIMIT_NOFILE}
+
+// RunExecLimitsShim sets resource limits and executes the command (never returns) if the process
+// was started as the resource limits shim by exec validators, it must be called at the beginning
+// of main of executables using exec validators with resource limits
+func RunExecLimitsShim() {
+	if len(os.Args) != 0 && os.Args[0] == execLimitsShimArgv0 {
+		runExecLimitsShim(os.Args[1:])
+	}
+}
+
+func (l *ExecLimits) values() []uint64 {
+	return []uint64{l.CPUTime, l.MemoryBytes, l.FileSizeBytes, l.OpenFiles}
+}
+
+// commandWithResourceLimits creates command running ksync itself as a shim, which sets resource limits
+// and then executes the actual command, so limits are in effect since the first instruction
+func commandWithResourceLimits(args []string, limits *ExecLimits) (*exec.Cmd, error) {
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find ksync executable: %w", err)
+	}
+
+	shimArgs := []string{execLimitsShimArgv0}
+	for _, v := range limits.values() {
+		shimArgs = append(shimArgs, strconv.FormatUint(v, 10))
+	}
+	shimArgs = append(append(shimArgs, path), args...)
+
+	// nolint:gosec
+	cmd := exec.Command(self)
+	cmd.Args = shimArgs
+	return cmd, nil
+}
+
+// runExecLimitsShim sets resource limits and executes the command, args are limits in the order of
+// execLimitResources, path of the command and args of the command (including argv[0])
+func runExecLimitsShim(args []string) {
+	n := len(execLimitResources)
+	if len(args) < n+2 {
+		exitExecLimitsShim(fmt.Errorf("invalid args %v", args))
+	}
+
+	for i, resource := range execLimitResources {
+		limit, err := strconv.ParseUint(args[i], 10, 64)
+		if err != nil {
+			exitExecLimitsShim(fmt.Errorf("invalid resource limit %q: %w", args[i], err))
+		}
+
+		if limit == 0 {
+			continue
+		}
+
+		err = unix.Setrlimit(resource, &unix.Rlimit{Cur: limit, Max: limit})
+		if err != nil {
+			exitExecLimitsShim(fmt.Errorf("failed to set resource limit %d: %w", resource, err))
+		}
+	}
+
+	// nolint:gosec
+	err := syscall.Exec(args[n], args[n+1:], os.Environ())
+	exitExecLimitsShim(fmt.Errorf("failed to execute %q: %w", args[n], err))
+}
+
+func exitExecLimitsShim(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err.Error())
+	os.Exit(126)
+}
+
+// setProcessGroup to run the command in a new process group, so its child processes can be
+// killed together
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(p *os.Process) {
+	_ = syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
+
+// waitCommand waits for the command to exit and kills child processes left behind before the
+// command reaped, the process group id can be reused by other processes once reaped
+//
+// stopKiller is called before the command reaped to stop killing the process group on timeout
+func waitCommand(cmd *exec.Cmd, stopKiller func()) error {
+	err := waitExited(cmd.Process)
+	stopKiller()
+	if err == nil {
+		// child processes left behind may still hold the pipes
+		killProcessGroup(cmd.Process)
+	}
+
+	return cmd.Wait()
+}
+
+// waitExited waits for the process to exit without reaping it
+func waitExited(p *os.Process) error {
+	// P_PID of idtype_t
+	const idTypePID = 1
+
+	// siginfo_t is 128 bytes on linux
+	var siginfo [16]uint64
+	for {
+		// nolint:gosec
+		_, _, errno := syscall.Syscall6(syscall.SYS_WAITID, idTypePID, uintptr(p.Pid),
+			uintptr(unsafe.Pointer(&siginfo)), syscall.WEXITED|unix.WNOWAIT, 0, 0)
+		switch errno {
+		case 0:
+			return nil
+		case syscall.EINTR:
+			continue
+		default:
+			return errno
+		}
+	}
+}
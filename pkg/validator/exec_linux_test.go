@@ -0,0 +1,33 @@
+package validator
+
+import (
+	"context"
+	"testing"
+
+	"arhat.dev/pkg/log"
+)
+
+func TestExecValidatorResourceLimits(t *testing.T) {
+	v, err := NewExecValidator(context.TODO(), log.Log.WithName("test"), &Config{
+		Method:   MethodExec,
+		DataKeys: []string{"app.conf"},
+		Exec: &ExecConfig{
+			// limits must be in effect when the command started
+			Command:      []string{"sh", "-c", "ulimit -n; ulimit -t"},
+			StdoutAsData: true,
+			Limits: ExecLimits{
+				CPUTime:       10,
+				FileSizeBytes: 1024 * 1024,
+				OpenFiles:     64,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := v.Validate(&ValidationContext{}, map[string][]byte{"app.conf": []byte("foo")})
+	if actual := string(result.Data["app.conf"]); actual != "64\n10\n" {
+		t.Errorf("unexpected limits %q, error: %v", actual, result.Errors["app.conf"])
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+// +build !linux
+
+package validator
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+const resourceLimitsSupported = false
+
+// RunExecLimitsShim is a no-op since resource limits are not supported on this platform
+func RunExecLimitsShim() {}
+
+func commandWithResourceLimits(args []string, limits *ExecLimits) (*exec.Cmd, error) {
+	return nil, fmt.Errorf("resource limits are not supported on this platform")
+}
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(p *os.Process) {
+	_ = p.Kill()
+}
+
+// waitCommand waits for the command to exit, stopKiller is called after the command reaped since
+// only the command itself is killed on timeout, which is a no-op once reaped
+func waitCommand(cmd *exec.Cmd, stopKiller func()) error {
+	err := cmd.Wait()
+	stopKiller()
+	return err
+}
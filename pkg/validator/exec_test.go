@@ -0,0 +1,149 @@
+package validator
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"arhat.dev/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	// the test binary is the resource limits shim of exec validators
+	RunExecLimitsShim()
+
+	os.Exit(m.Run())
+}
+
+func TestExecValidator(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *ExecConfig
+
+		data     string
+		expected string
+		errMsg   string
+	}{
+		{
+			name: "Stdin Stdout As Data",
+			config: &ExecConfig{
+				Command:      []string{"sh", "-c", "tr a-z A-Z"},
+				StdoutAsData: true,
+			},
+			data:     "foo",
+			expected: "FOO",
+		},
+		{
+			name: "File Input",
+			config: &ExecConfig{
+				Command: []string{"sh", "-c", `grep -q foo "{{ .DataFile }}" && test "$KSYNC_DATA_FILE" = "{{ .DataFile }}"`},
+				Input:   ExecInputFile,
+			},
+			data:     "foo",
+			expected: "foo",
+		},
+		{
+			name: "Non-zero Exit",
+			config: &ExecConfig{
+				Command: []string{"sh", "-c", "echo invalid config >&2; exit 3"},
+			},
+			data:   "foo",
+			errMsg: "command exited with code 3: invalid config",
+		},
+		{
+			name: "Timeout",
+			config: &ExecConfig{
+				Command: []string{"sleep", "10"},
+				Timeout: 100 * time.Millisecond,
+			},
+			data:   "foo",
+			errMsg: "timed out",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := NewExecValidator(context.TODO(), log.Log.WithName("test"), &Config{
+				Method:   MethodExec,
+				DataKeys: []string{"app.conf"},
+				Exec:     test.config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := v.Validate(&ValidationContext{}, map[string][]byte{"app.conf": []byte(test.data)})
+			if test.errMsg != "" {
+				err := result.Errors["app.conf"]
+				if err == nil || !strings.Contains(err.Error(), test.errMsg) {
+					t.Errorf("expected error containing %q, got %v", test.errMsg, err)
+				}
+				return
+			}
+
+			if actual := string(result.Data["app.conf"]); actual != test.expected {
+				t.Errorf("expected %q, got %q, error: %v", test.expected, actual, result.Errors["app.conf"])
+			}
+		})
+	}
+}
+
+func TestExecValidatorChildProcesses(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *ExecConfig
+
+		expected string
+		errMsg   string
+	}{
+		{
+			name: "Background Child",
+			config: &ExecConfig{
+				Command:      []string{"sh", "-c", "sleep 30 & echo done"},
+				StdoutAsData: true,
+			},
+			expected: "done\n",
+		},
+		{
+			name: "Timeout With Child",
+			config: &ExecConfig{
+				Command: []string{"sh", "-c", "sleep 30 & wait"},
+				Timeout: 100 * time.Millisecond,
+			},
+			errMsg: "timed out",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := NewExecValidator(context.TODO(), log.Log.WithName("test"), &Config{
+				Method:   MethodExec,
+				DataKeys: []string{"app.conf"},
+				Exec:     test.config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			result := v.Validate(&ValidationContext{}, map[string][]byte{"app.conf": []byte("foo")})
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Errorf("validation blocked by child process for %s", elapsed)
+			}
+
+			if test.errMsg != "" {
+				err := result.Errors["app.conf"]
+				if err == nil || !strings.Contains(err.Error(), test.errMsg) {
+					t.Errorf("expected error containing %q, got %v", test.errMsg, err)
+				}
+				return
+			}
+
+			if actual := string(result.Data["app.conf"]); actual != test.expected {
+				t.Errorf("expected %q, got %q, error: %v", test.expected, actual, result.Errors["app.conf"])
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+
+	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/constant"
+)
+
+func init() {
+	RegisterValidator(MethodGuard, NewGuardValidator)
+}
+
+const (
+	MethodGuard = "guard"
+)
+
+const (
+	// maximum total size of data in a configmap or secret
+	maxObjectDataBytes = 1024 * 1024
+)
+
+type GuardConfig struct {
+	// MaxShrinkPercent rejects data smaller than current one by more than this percentage
+	// (0 to disable)
+	MaxShrinkPercent int `json:"maxShrinkPercent" yaml:"maxShrinkPercent"`
+
+	// MaxRemovedKeys rejects json/yaml documents removing more than this number of top-level keys
+	MaxRemovedKeys *int `json:"maxRemovedKeys" yaml:"maxRemovedKeys"`
+
+	// AllowEmpty allows emptying a non-empty data key
+	AllowEmpty bool `json:"allowEmpty" yaml:"allowEmpty"`
+
+	// MaxSizeBytes of each data key (0 to disable), total data size of the sync target is
+	// always limited to 1MiB
+	MaxSizeBytes int `json:"maxSizeBytes" yaml:"maxSizeBytes"`
+}
+
+func NewGuardValidator(ctx context.Context, logger log.Interface, config *Config) (Interface, error) {
+	if config.Guard == nil {
+		return nil, fmt.Errorf("no guard validator configuration provided")
+	}
+
+	if p := config.Guard.MaxShrinkPercent; p < 0 || p > 100 {
+		return nil, fmt.Errorf("invalid maxShrinkPercent %d, must be in [0, 100]", p)
+	}
+
+	return &GuardValidator{
+		ctx:      ctx,
+		logger:   logger,
+		dataKeys: config.DataKeys,
+		config:   config.Guard,
+	}, nil
+}
+
+// GuardValidator rejects suspicious changes compared to data in the sync target, changes
+// except exceeding size limits are allowed once if the sync target has annotation
+// `ksync.arhat.dev/guard-override`
+type GuardValidator struct {
+	ctx      context.Context
+	logger   log.Interface
+	dataKeys []string
+	config   *GuardConfig
+}
+
+// TargetDependent returns true, data is checked against the sync target
+func (g *GuardValidator) TargetDependent() bool {
+	return true
+}
+
+func (g *GuardValidator) Validate(vctx *ValidationContext, data map[string][]byte) *DataMsg {
+	result := &DataMsg{
+		Data:   make(map[string][]byte),
+		Errors: make(map[string]error),
+	}
+
+	var keys []string
+	for _, k := range g.dataKeys {
+		if _, ok := data[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+
+	if len(keys) == 0 {
+		return result
+	}
+
+	current, err := getTargetObject(g.ctx, vctx.Target)
+	if err != nil {
+		for _, k := range keys {
+			result.Errors[k] = fmt.Errorf("failed to get sync target: %w", err)
+		}
+
+		return result
+	}
+
+	override := false
+	currentData := make(map[string][]byte)
+	if current != nil {
+		_, override = current.Annotations[constant.AnnotationGuardOverride]
+		currentData = current.Data
+	}
+
+	if override {
+		g.logger.I("guard override annotation found, allowing changes once")
+	}
+
+	// total size after update
+	total := 0
+	for k, v := range currentData {
+		if _, updated := data[k]; !updated {
+			total += len(k) + len(v)
+		}
+	}
+	for k, v := range data {
+		total += len(k) + len(v)
+	}
+
+	for _, k := range keys {
+		d := data[k]
+
+		if total > maxObjectDataBytes {
+			result.Errors[k] = fmt.Errorf("total data size %d exceeds %d bytes", total, maxObjectDataBytes)
+			continue
+		}
+
+		if max := g.config.MaxSizeBytes; max > 0 && len(d) > max {
+			result.Errors[k] = fmt.Errorf("data size %d exceeds %d bytes", len(d), max)
+			continue
+		}
+
+		if old, ok := currentData[k]; ok && !override {
+			if err := g.checkChange(old, d); err != nil {
+				result.Errors[k] = err
+				continue
+			}
+		}
+
+		result.Data[k] = d
+	}
+
+	return result
+}
+
+func (g *GuardValidator) checkChange(old, d []byte) error {
+	if len(old) != 0 && len(d) == 0 && !g.config.AllowEmpty {
+		return fmt.Errorf("emptying data is not allowed")
+	}
+
+	if p := g.config.MaxShrinkPercent; p > 0 && len(d) < len(old) {
+		if shrink := (len(old) - len(d)) * 100 / len(old); shrink > p {
+			return fmt.Errorf("data size shrinks by %d%% (%d to %d bytes), more than %d%%", shrink, len(old), len(d), p)
+		}
+	}
+
+	if g.config.MaxRemovedKeys != nil {
+		oldDoc, err1 := unmarshalJSONOrYAML(old)
+		newDoc, err2 := unmarshalJSONOrYAML(d)
+
+		oldMap, ok1 := oldDoc.(map[string]interface{})
+		newMap, ok2 := newDoc.(map[string]interface{})
+
+		// only compare objects
+		if err1 == nil && err2 == nil && ok1 {
+			var removed []string
+			for k := range oldMap {
+				if _, ok := newMap[k]; !ok2 || !ok {
+					removed = append(removed, k)
+				}
+			}
+
+			if max := *g.config.MaxRemovedKeys; len(removed) > max {
+				return fmt.Errorf("%d top-level keys removed, more than %d", len(removed), max)
+			}
+		}
+	}
+
+	return nil
+}
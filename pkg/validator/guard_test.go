@@ -0,0 +1,97 @@
+package validator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/constant"
+	"arhat.dev/ksync/pkg/dataref"
+)
+
+func TestGuardValidator(t *testing.T) {
+	maxRemovedKeys := 1
+	current := map[string][]byte{
+		"doc":  []byte(`{"a": 1, "b": 2, "c": 3}`),
+		"text": []byte(strings.Repeat("x", 100)),
+	}
+
+	tests := []struct {
+		name        string
+		data        map[string][]byte
+		annotations map[string]string
+		rejected    []string
+	}{
+		{
+			name: "Valid",
+			data: map[string][]byte{
+				"doc":  []byte(`{"a": 1, "b": 2}`),
+				"text": []byte(strings.Repeat("y", 60)),
+				"new":  []byte("foo"),
+			},
+		},
+		{
+			name: "Too Many Keys Removed",
+			data: map[string][]byte{"doc": []byte(`{"a": 1}`)},
+
+			rejected: []string{"doc"},
+		},
+		{
+			name: "Shrink And Empty",
+			data: map[string][]byte{
+				"text": []byte(strings.Repeat("x", 10)),
+				"doc":  []byte(""),
+			},
+			rejected: []string{"doc", "text"},
+		},
+		{
+			name: "Override",
+			data: map[string][]byte{
+				"text": []byte(""),
+				"doc":  []byte(`{}`),
+			},
+			annotations: map[string]string{constant.AnnotationGuardOverride: ""},
+		},
+		{
+			name: "Exceeds Object Size",
+			data: map[string][]byte{"new": []byte(strings.Repeat("x", maxObjectDataBytes))},
+
+			rejected: []string{"new"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := SyncTarget{Kind: "configmap", Namespace: "default", Name: "foo"}
+			ctx := dataref.WithGetter(WithSyncTarget(context.TODO(), target), testObjectGetter{
+				"foo": &dataref.Object{Annotations: test.annotations, Data: current},
+			})
+
+			v, err := NewGuardValidator(ctx, log.Log.WithName("test"), &Config{
+				Method:   MethodGuard,
+				DataKeys: []string{"doc", "text", "new"},
+				Guard: &GuardConfig{
+					MaxShrinkPercent: 50,
+					MaxRemovedKeys:   &maxRemovedKeys,
+					MaxSizeBytes:     2 * maxObjectDataBytes,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := v.Validate(&ValidationContext{Target: target}, test.data)
+			if len(result.Errors) != len(test.rejected) {
+				t.Fatalf("expected %d keys rejected, got %v", len(test.rejected), result.Errors)
+			}
+
+			for _, k := range test.rejected {
+				if result.Errors[k] == nil {
+					t.Errorf("expected %q rejected", k)
+				}
+			}
+		})
+	}
+}
@@ -4,16 +4,19 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	pkgurl "net/url"
+	"regexp"
 	"strings"
 	"text/template"
 	"time"
 
 	"arhat.dev/pkg/log"
 	"arhat.dev/pkg/tlshelper"
+	"github.com/itchyny/gojq"
 	"golang.org/x/net/http/httpproxy"
 )
 
@@ -32,6 +35,25 @@ type HTTPProxyConfig struct {
 	CGI     bool   `json:"cgi" yaml:"cgi"`
 }
 
+type HTTPRetryConfig struct {
+	// MaxAttempts of a request (including the first one), defaults to 1 (no retry)
+	MaxAttempts int `json:"maxAttempts" yaml:"maxAttempts"`
+
+	// Backoff before the first retry, doubled for each retry, defaults to 1s
+	Backoff time.Duration `json:"backoff" yaml:"backoff"`
+
+	// MaxBackoff between retries, defaults to 30s
+	MaxBackoff time.Duration `json:"maxBackoff" yaml:"maxBackoff"`
+}
+
+const (
+	defaultHTTPRetryBackoff    = time.Second
+	defaultHTTPRetryMaxBackoff = 30 * time.Second
+)
+
+// variables available to response body queries in addition to the parsed response body (`.`)
+var httpResponseQueryVariables = []string{"$dataKey", "$statusCode", "$ctx"}
+
 type HTTPConfig struct {
 	DryRun             bool `json:"dryRun" yaml:"dryRun"`
 	RequestBodyAsData  bool `json:"requestBodyAsData" yaml:"requestBodyAsData"`
@@ -44,6 +66,13 @@ type HTTPConfig struct {
 		Proxy       *HTTPProxyConfig    `json:"proxy" yaml:"proxy"`
 		Body        string              `json:"body" yaml:"body"`
 		TLS         tlshelper.TLSConfig `json:"tls" yaml:"tls"`
+
+		// Timeout of each request attempt (0 to disable)
+		Timeout time.Duration `json:"timeout" yaml:"timeout"`
+		// Retry failed requests (connection errors, 429 and 5xx responses)
+		Retry HTTPRetryConfig `json:"retry" yaml:"retry"`
+		// Auth with credentials from secret
+		Auth *HTTPAuthConfig `json:"auth" yaml:"auth"`
 	} `json:"request" yaml:"request"`
 
 	Response struct {
@@ -54,6 +83,14 @@ type HTTPConfig struct {
 		ResponseCode    int            `json:"responseCode" yaml:"responseCode"`
 		ResponseBody    string         `json:"responseBody" yaml:"responseBody"`
 		ResponseHeaders NameValuePairs `json:"responseHeaders" yaml:"responseHeaders"`
+
+		// ResponseBodyRegex the response body must match
+		ResponseBodyRegex string `json:"responseBodyRegex" yaml:"responseBodyRegex"`
+
+		// ResponseBodyQueries are jq expressions all must output true, the response body is
+		// parsed as json or yaml (used as string if failed), and following variables are available:
+		// 	$dataKey, $statusCode, $ctx (validation context)
+		ResponseBodyQueries []string `json:"responseBodyQueries" yaml:"responseBodyQueries"`
 	} `json:"expect" yaml:"expect"`
 }
 
@@ -75,6 +112,8 @@ func NewHTTPValidator(context context.Context, logger log.Interface, config *Con
 		http.MethodGet:     {},
 		http.MethodPost:    {},
 		http.MethodPut:     {},
+		http.MethodPatch:   {},
+		http.MethodDelete:  {},
 		http.MethodHead:    {},
 		http.MethodOptions: {},
 	}[method]
@@ -119,6 +158,48 @@ func NewHTTPValidator(context context.Context, logger log.Interface, config *Con
 		return nil, fmt.Errorf("failed to parse expected headers as text template: %w", err)
 	}
 
+	var respBodyRegex *regexp.Regexp
+	if config.HTTP.Expect.ResponseBodyRegex != "" {
+		respBodyRegex, err = regexp.Compile(config.HTTP.Expect.ResponseBodyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid response body regex: %w", err)
+		}
+	}
+
+	var respBodyQueries []*gojq.Code
+	for _, q := range config.HTTP.Expect.ResponseBodyQueries {
+		query, err := gojq.Parse(q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse response body query %q: %w", q, err)
+		}
+
+		code, err := gojq.Compile(query, gojq.WithVariables(httpResponseQueryVariables))
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile response body query %q: %w", q, err)
+		}
+
+		respBodyQueries = append(respBodyQueries, code)
+	}
+
+	var auth *httpAuth
+	if config.HTTP.Request.Auth != nil {
+		auth, err = newHTTPAuth(context, config.HTTP.Request.Auth)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	retry := config.HTTP.Request.Retry
+	if retry.MaxAttempts < 1 {
+		retry.MaxAttempts = 1
+	}
+	if retry.Backoff <= 0 {
+		retry.Backoff = defaultHTTPRetryBackoff
+	}
+	if retry.MaxBackoff <= 0 {
+		retry.MaxBackoff = defaultHTTPRetryMaxBackoff
+	}
+
 	var proxy func(*http.Request) (*pkgurl.URL, error)
 	if p := config.HTTP.Request.Proxy; p != nil {
 		cfg := httpproxy.Config{
@@ -177,8 +258,11 @@ func NewHTTPValidator(context context.Context, logger log.Interface, config *Con
 		ctx:      context,
 		dataKeys: append([]string{}, config.DataKeys...),
 
-		dryRun: config.HTTP.DryRun,
-		client: client,
+		dryRun:  config.HTTP.DryRun,
+		client:  client,
+		timeout: config.HTTP.Request.Timeout,
+		retry:   retry,
+		auth:    auth,
 
 		method:        method,
 		urlTpl:        urlTpl,
@@ -192,6 +276,8 @@ func NewHTTPValidator(context context.Context, logger log.Interface, config *Con
 		expectResponseCode:    config.HTTP.Expect.ResponseCode,
 		expectResponseBody:    config.HTTP.Expect.ResponseBody,
 		expectResponseHeaders: expHeaders,
+		expectResponseRegex:   respBodyRegex,
+		expectResponseQueries: respBodyQueries,
 	}
 
 	return v, nil
@@ -203,8 +289,11 @@ type HTTPValidator struct {
 	ctx      context.Context
 	dataKeys []string
 
-	dryRun bool
-	client *http.Client
+	dryRun  bool
+	client  *http.Client
+	timeout time.Duration
+	retry   HTTPRetryConfig
+	auth    *httpAuth
 
 	// request
 	method        string
@@ -221,9 +310,11 @@ type HTTPValidator struct {
 	expectResponseCode    int
 	expectResponseBody    string
 	expectResponseHeaders NameValueTemplatePairs
+	expectResponseRegex   *regexp.Regexp
+	expectResponseQueries []*gojq.Code
 }
 
-func (h *HTTPValidator) Validate(data map[string][]byte) *DataMsg {
+func (h *HTTPValidator) Validate(vctx *ValidationContext, data map[string][]byte) *DataMsg {
 	if len(data) == 0 {
 		return nil
 	}
@@ -244,6 +335,7 @@ func (h *HTTPValidator) Validate(data map[string][]byte) *DataMsg {
 			DataKeys: h.dataKeys,
 			DataKey:  k,
 			Data:     d,
+			Context:  vctx,
 		}
 
 		h.logger.V("creating request")
@@ -256,7 +348,7 @@ func (h *HTTPValidator) Validate(data map[string][]byte) *DataMsg {
 		var respBody []byte
 		if !h.dryRun {
 			h.logger.V("sending request")
-			resp, err := h.client.Do(req)
+			resp, err := h.do(req)
 			if err != nil {
 				result.Errors[k] = fmt.Errorf("failed to do validation request for key %q: %w", k, err)
 				continue
@@ -303,9 +395,14 @@ func (h *HTTPValidator) newRequest(tplVars *templateVars) (*http.Request, []byte
 		requestBody = buf.Bytes()
 	}
 
-	req, err := http.NewRequestWithContext(h.ctx, h.method, url, buf)
+	var body io.Reader
+	if requestBody != nil {
+		body = bytes.NewReader(requestBody)
+	}
+
+	req, err := http.NewRequestWithContext(h.ctx, h.method, url, body)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create request ")
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	headers, err := h.headers.EvalAndConvertToStringStringsMap(tplVars)
@@ -368,6 +465,7 @@ func (h *HTTPValidator) handleResponse(tplVar *templateVars, resp *http.Response
 			DataKeys: tplVar.DataKeys,
 			DataKey:  tplVar.DataKey,
 			Data:     tplVar.Data,
+			Context:  tplVar.Context,
 			Extra: &responseVars{
 				Resp: struct{ Body []byte }{Body: respBody},
 			},
@@ -382,7 +480,18 @@ func (h *HTTPValidator) handleResponse(tplVar *templateVars, resp *http.Response
 
 	if h.expectResponseBody != "" {
 		if h.expectResponseBody != string(respBody) {
-			return nil, fmt.Errorf("response body not valid: %w", err)
+			return nil, fmt.Errorf("response body not valid: not equal to expected one")
+		}
+	}
+
+	if h.expectResponseRegex != nil && !h.expectResponseRegex.Match(respBody) {
+		return nil, fmt.Errorf("response body not valid: not matching regex %q", h.expectResponseRegex.String())
+	}
+
+	if len(h.expectResponseQueries) != 0 {
+		err = h.checkResponseQueries(tplVar, resp.StatusCode, respBody)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -391,6 +500,7 @@ func (h *HTTPValidator) handleResponse(tplVar *templateVars, resp *http.Response
 			DataKeys: tplVar.DataKeys,
 			DataKey:  tplVar.DataKey,
 			Data:     tplVar.Data,
+			Context:  tplVar.Context,
 			Extra: &responseVars{
 				Resp: struct{ Body []byte }{Body: respBody},
 			},
@@ -424,3 +534,120 @@ func (h *HTTPValidator) handleResponse(tplVar *templateVars, resp *http.Response
 
 	return respBody, nil
 }
+
+// do request with timeout and retries, response body is fully read and can be read again
+func (h *HTTPValidator) do(req *http.Request) (*http.Response, error) {
+	backoff := h.retry.Backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := h.doOnce(req)
+
+		retryable := err != nil ||
+			resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		if !retryable || attempt >= h.retry.MaxAttempts {
+			return resp, err
+		}
+
+		if err != nil {
+			h.logger.I("http request failed, retrying", log.Int("attempt", attempt), log.Error(err))
+		} else {
+			h.logger.I("http request failed, retrying", log.Int("attempt", attempt), log.Int("code", resp.StatusCode))
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-h.ctx.Done():
+			timer.Stop()
+			return nil, h.ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > h.retry.MaxBackoff {
+			backoff = h.retry.MaxBackoff
+		}
+	}
+}
+
+func (h *HTTPValidator) doOnce(req *http.Request) (*http.Response, error) {
+	ctx, cancel := h.ctx, context.CancelFunc(func() {})
+	if h.timeout > 0 {
+		ctx, cancel = context.WithTimeout(h.ctx, h.timeout)
+	}
+	defer cancel()
+
+	r := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get request body: %w", err)
+		}
+		r.Body = body
+	}
+
+	if h.auth != nil {
+		err := h.auth.authorize(ctx, h.client, r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := h.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// read body before the timeout context is canceled
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if resp.StatusCode == http.StatusUnauthorized && h.auth != nil {
+		// token may be revoked
+		h.auth.invalidate()
+	}
+
+	return resp, nil
+}
+
+func (h *HTTPValidator) checkResponseQueries(tplVar *templateVars, statusCode int, respBody []byte) error {
+	input, err := unmarshalJSONOrYAML(respBody)
+	if err != nil {
+		input = string(respBody)
+	}
+
+	// same order as httpResponseQueryVariables
+	variables := []interface{}{tplVar.DataKey, statusCode, tplVar.Context.jqValue()}
+
+	for i, code := range h.expectResponseQueries {
+		iter := code.Run(input, variables...)
+		matched := false
+		for {
+			v, ok := iter.Next()
+			if !ok {
+				break
+			}
+
+			switch t := v.(type) {
+			case error:
+				return fmt.Errorf("response body query #%d failed: %w", i, t)
+			case bool:
+				matched = t
+			default:
+				return fmt.Errorf("response body query #%d: unexpected result type %T, expecting bool", i, v)
+			}
+
+			if !matched {
+				break
+			}
+		}
+
+		if !matched {
+			return fmt.Errorf("response body not valid: query #%d not satisfied", i)
+		}
+	}
+
+	return nil
+}
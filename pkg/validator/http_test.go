@@ -1,7 +1,107 @@
 package validator
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/dataref"
+)
 
 func TestNewHTTPValidator(t *testing.T) {
 
 }
+
+func TestHTTPValidator(t *testing.T) {
+	var tokenRequests, failures int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tokenRequests, 1)
+
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "foo" || secret != "bar" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = fmt.Fprint(w, `{"access_token": "test-token", "token_type": "bearer", "expires_in": 3600}`)
+	})
+	mux.HandleFunc("/validate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		// fail the first request to test retry
+		if atomic.AddInt32(&failures, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		_, _ = fmt.Fprintf(w, `{"valid": %s, "version": "v1.2.3"}`, body)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx := dataref.WithNamespace(dataref.WithGetter(context.TODO(), testDataGetter{
+		"creds/clientID":     []byte("foo"),
+		"creds/clientSecret": []byte("bar\n"),
+	}), "default")
+
+	config := &HTTPConfig{}
+	config.Request.URLTemplate = srv.URL + "/validate"
+	config.Request.Action = "patch"
+	config.Request.Body = `{{- .Data | toString -}}`
+	config.Request.Timeout = time.Second
+	config.Request.Retry = HTTPRetryConfig{MaxAttempts: 2, Backoff: 10 * time.Millisecond}
+	config.Request.Auth = &HTTPAuthConfig{
+		Type:      HTTPAuthOAuth2,
+		SecretRef: "creds",
+		TokenURL:  srv.URL + "/token",
+	}
+	config.Expect.ResponseBodyRegex = `"version": "v1\.`
+	config.Expect.ResponseBodyQueries = []string{`.valid`, `$statusCode == 200`, `$dataKey == "foo"`}
+
+	v, err := NewHTTPValidator(ctx, log.Log.WithName("test"), &Config{
+		Method:   MethodHTTP,
+		DataKeys: []string{"foo"},
+		HTTP:     config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := v.Validate(&ValidationContext{}, map[string][]byte{"foo": []byte("true")})
+	if _, ok := result.Data["foo"]; !ok {
+		t.Errorf("expected data accepted after retry, got error %v", result.Errors["foo"])
+	}
+
+	result = v.Validate(&ValidationContext{}, map[string][]byte{"foo": []byte("false")})
+	if _, ok := result.Errors["foo"]; !ok {
+		t.Errorf("expected data rejected by response body query")
+	}
+
+	if n := atomic.LoadInt32(&tokenRequests); n != 1 {
+		t.Errorf("expected oauth2 token requested once, got %d", n)
+	}
+
+	config.Request.Auth.SecretRef = "kube-system/creds"
+	_, err = NewHTTPValidator(ctx, log.Log.WithName("test"), &Config{
+		Method:   MethodHTTP,
+		DataKeys: []string{"foo"},
+		HTTP:     config,
+	})
+	if err == nil {
+		t.Errorf("expected secret ref in other namespace rejected")
+	}
+}
@@ -0,0 +1,147 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"arhat.dev/pkg/log"
+	"arhat.dev/pkg/tlshelper"
+
+	"arhat.dev/ksync/pkg/dataref"
+)
+
+func init() {
+	RegisterValidator(MethodJSONSchema, NewJSONSchemaValidator)
+}
+
+const (
+	MethodJSONSchema = "jsonschema"
+)
+
+type JSONSchemaConfig struct {
+	// Schema inline in json or yaml format
+	Schema string `json:"schema" yaml:"schema"`
+
+	// SchemaURL to download schema from
+	SchemaURL string `json:"schemaURL" yaml:"schemaURL"`
+	// TLS config for SchemaURL
+	TLS tlshelper.TLSConfig `json:"tls" yaml:"tls"`
+
+	// SchemaRef to the data key contains schema in the namespace of the sync config
+	// 	{configmap|secret}://<name>/<key>
+	SchemaRef string `json:"schemaRef" yaml:"schemaRef"`
+}
+
+func NewJSONSchemaValidator(ctx context.Context, logger log.Interface, config *Config) (Interface, error) {
+	if config.JSONSchema == nil {
+		return nil, fmt.Errorf("no jsonschema validator configuration provided")
+	}
+
+	schemaData, err := loadJSONSchema(ctx, config.JSONSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := compileJSONSchema(schemaData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json schema: %w", err)
+	}
+
+	return &JSONSchemaValidator{
+		dataKeys: config.DataKeys,
+		schema:   schema,
+	}, nil
+}
+
+func loadJSONSchema(ctx context.Context, config *JSONSchemaConfig) ([]byte, error) {
+	sources := 0
+	for _, s := range []string{config.Schema, config.SchemaURL, config.SchemaRef} {
+		if s != "" {
+			sources++
+		}
+	}
+
+	if sources != 1 {
+		return nil, fmt.Errorf("exactly one of schema, schemaURL and schemaRef is required")
+	}
+
+	switch {
+	case config.Schema != "":
+		return []byte(config.Schema), nil
+	case config.SchemaRef != "":
+		return dataref.Resolve(ctx, config.SchemaRef)
+	}
+
+	tlsConfig, err := config.TLS.GetTLSConfig(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tls config: %w", err)
+	}
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.SchemaURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download schema: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download schema: unexpected status code %d", resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	return data, nil
+}
+
+// JSONSchemaValidator validates json or yaml data with json schema
+type JSONSchemaValidator struct {
+	dataKeys []string
+	schema   *jsonSchema
+}
+
+func (j *JSONSchemaValidator) Validate(vctx *ValidationContext, data map[string][]byte) *DataMsg {
+	result := &DataMsg{
+		Data:   make(map[string][]byte),
+		Errors: make(map[string]error),
+	}
+
+	for _, k := range j.dataKeys {
+		d, ok := data[k]
+		if !ok {
+			continue
+		}
+
+		doc, err := unmarshalJSONOrYAML(d)
+		if err != nil {
+			result.Errors[k] = fmt.Errorf("failed to parse data as json or yaml: %w", err)
+			continue
+		}
+
+		if violations := j.schema.validateDocument(doc); len(violations) != 0 {
+			result.Errors[k] = jsonSchemaError(violations)
+			continue
+		}
+
+		result.Data[k] = d
+	}
+
+	return result
+}
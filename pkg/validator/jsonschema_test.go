@@ -0,0 +1,89 @@
+package validator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/dataref"
+)
+
+func TestJSONSchemaValidator(t *testing.T) {
+	const schema = `
+type: object
+required: [name]
+properties:
+  name: {$ref: "#/definitions/name"}
+definitions:
+  name: {type: string, pattern: "^[a-z]+$"}
+`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/schema.yaml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, _ = w.Write([]byte(schema))
+	}))
+	defer srv.Close()
+
+	ctx := dataref.WithNamespace(dataref.WithGetter(context.TODO(), testDataGetter{
+		"schemas/app.yaml": []byte(schema),
+	}), "default")
+
+	tests := []struct {
+		name      string
+		config    *JSONSchemaConfig
+		expectErr bool
+	}{
+		{name: "Inline", config: &JSONSchemaConfig{Schema: schema}},
+		{name: "URL", config: &JSONSchemaConfig{SchemaURL: srv.URL + "/schema.yaml"}},
+		{name: "Ref", config: &JSONSchemaConfig{SchemaRef: "configmap://schemas/app.yaml"}},
+		{name: "URL Not Found", config: &JSONSchemaConfig{SchemaURL: srv.URL + "/missing.yaml"}, expectErr: true},
+		{name: "Ref Not Found", config: &JSONSchemaConfig{SchemaRef: "configmap://schemas/missing"}, expectErr: true},
+		{name: "Ref Other Namespace", config: &JSONSchemaConfig{SchemaRef: "configmap://kube-system/schemas/app.yaml"}, expectErr: true},
+		{name: "Multiple Sources", config: &JSONSchemaConfig{Schema: schema, SchemaRef: "configmap://schemas/app.yaml"}, expectErr: true},
+		{name: "Unsupported Keyword", config: &JSONSchemaConfig{Schema: `{unevaluatedProperties: false}`}, expectErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := NewJSONSchemaValidator(ctx, log.Log.WithName("test"), &Config{
+				Method:     MethodJSONSchema,
+				DataKeys:   []string{"valid", "invalid", "malformed"},
+				JSONSchema: test.config,
+			})
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("validator created with invalid schema source")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := v.Validate(&ValidationContext{}, map[string][]byte{
+				"valid":     []byte("name: foo"),
+				"invalid":   []byte(`{"name": "Foo"}`),
+				"malformed": []byte("{"),
+				"other":     []byte("not validated"),
+			})
+
+			if _, ok := result.Data["valid"]; !ok || len(result.Data) != 1 {
+				t.Errorf("unexpected accepted data %v", result.Data)
+			}
+
+			for _, k := range []string{"invalid", "malformed"} {
+				if _, ok := result.Errors[k]; !ok {
+					t.Errorf("data %q not rejected", k)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"arhat.dev/pkg/log"
+	"github.com/itchyny/gojq"
+)
+
+func init() {
+	RegisterValidator(MethodPolicy, NewPolicyValidator)
+}
+
+const (
+	MethodPolicy = "policy"
+)
+
+// variables available to policy rules in addition to the parsed data (`.`)
+var policyVariables = []string{"$namespace", "$name", "$kind", "$dataKey", "$syncer", "$ctx"}
+
+type PolicyConfig struct {
+	// Schema of data, one of plaintext, json, yaml
+	Schema string `json:"schema" yaml:"schema"`
+
+	// Rules to deny data, data is accepted only when no rule denies it
+	Rules []PolicyRule `json:"rules" yaml:"rules"`
+}
+
+type PolicyRule struct {
+	// Name of the rule, used as default deny message
+	Name string `json:"name" yaml:"name"`
+
+	// Deny jq expression, data is denied if it outputs true or a string (used as deny message)
+	//
+	// parsed data is the input (`.`), and following variables are available:
+	// 	$namespace, $name, $kind (of the sync target), $dataKey, $syncer, $ctx (validation context)
+	Deny string `json:"deny" yaml:"deny"`
+
+	// Message to report when Deny outputs true
+	Message string `json:"message" yaml:"message"`
+}
+
+func NewPolicyValidator(ctx context.Context, logger log.Interface, config *Config) (Interface, error) {
+	if config.Policy == nil {
+		return nil, fmt.Errorf("no policy validator configuration provided")
+	}
+
+	switch config.Policy.Schema {
+	case "", TextSchemaPlainText, TextSchemaJSON, TextSchemaYAML:
+	default:
+		return nil, fmt.Errorf("unsupported schema %q", config.Policy.Schema)
+	}
+
+	if len(config.Policy.Rules) == 0 {
+		return nil, fmt.Errorf("no policy rule provided")
+	}
+
+	rules := make([]*policyRule, len(config.Policy.Rules))
+	for i, r := range config.Policy.Rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("rule#%d", i)
+		}
+
+		q, err := gojq.Parse(r.Deny)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse deny expression of %s: %w", name, err)
+		}
+
+		code, err := gojq.Compile(q, gojq.WithVariables(policyVariables))
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile deny expression of %s: %w", name, err)
+		}
+
+		msg := r.Message
+		if msg == "" {
+			msg = "denied by " + name
+		}
+
+		rules[i] = &policyRule{
+			name:    name,
+			code:    code,
+			message: msg,
+		}
+	}
+
+	return &PolicyValidator{
+		dataKeys: config.DataKeys,
+		schema:   config.Policy.Schema,
+		rules:    rules,
+	}, nil
+}
+
+type policyRule struct {
+	name    string
+	code    *gojq.Code
+	message string
+}
+
+// eval rule and return deny messages
+func (r *policyRule) eval(data interface{}, variables []interface{}) []string {
+	var denies []string
+
+	iter := r.code.Run(data, variables...)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+
+		switch t := v.(type) {
+		case error:
+			// fail closed
+			return append(denies, fmt.Sprintf("%s: evaluation failed: %v", r.name, t))
+		case nil:
+		case bool:
+			if t {
+				denies = append(denies, r.message)
+			}
+		case string:
+			denies = append(denies, t)
+		default:
+			return append(denies, fmt.Sprintf("%s: unexpected result type %T, expecting bool or string", r.name, v))
+		}
+	}
+
+	return denies
+}
+
+// policyError contains all deny messages
+type policyError []string
+
+func (e policyError) Error() string {
+	return fmt.Sprintf("denied by policy: %s", strings.Join(e, "; "))
+}
+
+// PolicyValidator denies data with rules
+type PolicyValidator struct {
+	dataKeys []string
+	schema   string
+	rules    []*policyRule
+}
+
+// TargetDependent returns true, rules may check the sync target
+func (p *PolicyValidator) TargetDependent() bool {
+	return true
+}
+
+func (p *PolicyValidator) Validate(vctx *ValidationContext, data map[string][]byte) *DataMsg {
+	result := &DataMsg{
+		Data:   make(map[string][]byte),
+		Errors: make(map[string]error),
+	}
+
+	for _, k := range p.dataKeys {
+		d, ok := data[k]
+		if !ok {
+			continue
+		}
+
+		dataInput, err := parseTextData(p.schema, d)
+		if err != nil {
+			result.Errors[k] = fmt.Errorf("%s schema not valid: %w", p.schema, err)
+			continue
+		}
+
+		// same order as policyVariables
+		target := vctx.Target
+		variables := []interface{}{target.Namespace, target.Name, target.Kind, k, target.Syncer, vctx.jqValue()}
+
+		var denies policyError
+		for _, r := range p.rules {
+			denies = append(denies, r.eval(dataInput, variables)...)
+		}
+
+		if len(denies) != 0 {
+			result.Errors[k] = denies
+			continue
+		}
+
+		result.Data[k] = d
+	}
+
+	return result
+}
@@ -0,0 +1,66 @@
+package validator
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPolicyValidator(t *testing.T) {
+	target := SyncTarget{Kind: "configmap", Namespace: "prod-a", Name: "foo"}
+	ctx := WithSyncTarget(context.TODO(), target)
+
+	v, err := NewPolicyValidator(ctx, nil, &Config{
+		Method:   MethodPolicy,
+		DataKeys: []string{"json", "yaml"},
+		Policy: &PolicyConfig{
+			Schema: TextSchemaYAML,
+			Rules: []PolicyRule{
+				{
+					Name: "rate-limit",
+					Deny: `if .rateLimit > 100 then "rate limit \(.rateLimit) exceeds 100" else false end`,
+				},
+				{
+					Name:    "no-debug-in-prod",
+					Deny:    `.debug == true and ($namespace | startswith("prod"))`,
+					Message: "debug is not allowed in prod",
+				},
+				{
+					Name:    "locked",
+					Deny:    `$ctx.bufferedData.lock == "true"`,
+					Message: "config is locked",
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create policy validator: %v", err)
+	}
+
+	result := v.Validate(&ValidationContext{Target: target}, map[string][]byte{
+		"json": []byte(`{"rateLimit": 10, "debug": false}`),
+		"yaml": []byte("rateLimit: 200\ndebug: true\n"),
+	})
+
+	if _, ok := result.Data["json"]; !ok {
+		t.Errorf("expected json data accepted, got error %v", result.Errors["json"])
+	}
+
+	denies, ok := result.Errors["yaml"].(policyError)
+	if !ok || len(denies) != 2 {
+		t.Fatalf("expected 2 deny messages, got %v", result.Errors["yaml"])
+	}
+
+	if denies[0] != "rate limit 200 exceeds 100" || denies[1] != "debug is not allowed in prod" {
+		t.Errorf("unexpected deny messages: %v", denies)
+	}
+
+	result = v.Validate(&ValidationContext{
+		Target:       target,
+		BufferedData: map[string]string{"lock": "true"},
+	}, map[string][]byte{
+		"json": []byte(`{"rateLimit": 10, "debug": false}`),
+	})
+	if denies, ok := result.Errors["json"].(policyError); !ok || len(denies) != 1 || denies[0] != "config is locked" {
+		t.Errorf("expected json data denied by buffered data, got %v", result.Errors["json"])
+	}
+}
@@ -0,0 +1,458 @@
+package validator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/dataref"
+)
+
+func init() {
+	RegisterValidator(MethodSignature, NewSignatureValidator)
+}
+
+const (
+	MethodSignature = "signature"
+)
+
+const (
+	// SignatureFormatJWS for data in jws compact serialization
+	SignatureFormatJWS = "jws"
+	// SignatureFormatEnvelope for data in json `{"payload": "<base64>", "signature": "<base64>", "keyID": "<key-id>"}`
+	SignatureFormatEnvelope = "envelope"
+	// SignatureFormatDetached for data signed with signature in another data key
+	SignatureFormatDetached = "detached"
+)
+
+const (
+	defaultSignatureKeySuffix = ".sig"
+
+	// minimum interval between two public key refreshes caused by unknown key id
+	signatureKeyRefreshInterval = time.Minute
+)
+
+type SignatureConfig struct {
+	// Format of signed data, one of jws, envelope, detached
+	Format string `json:"format" yaml:"format"`
+
+	// SignatureKeySuffix is the suffix of the data key containing signature for detached format,
+	// signature data is `{ | <key-id>:}<base64-signature>`, defaults to `.sig`
+	SignatureKeySuffix string `json:"signatureKeySuffix" yaml:"signatureKeySuffix"`
+
+	// PayloadAsData to use verified payload as data instead of signed data (jws and envelope only)
+	PayloadAsData bool `json:"payloadAsData" yaml:"payloadAsData"`
+
+	// PublicKeys to verify signatures, all keys are tried for signatures without key id
+	PublicKeys []SignaturePublicKey `json:"publicKeys" yaml:"publicKeys"`
+}
+
+type SignaturePublicKey struct {
+	// KeyID of the public key, required when there are multiple public keys
+	KeyID string `json:"keyID" yaml:"keyID"`
+
+	// PEM encoded public key (ed25519 or ecdsa)
+	PEM string `json:"pem" yaml:"pem"`
+
+	// Ref to the data key containing PEM encoded public key in the namespace of the sync config
+	// 	{configmap|secret}://<name>/<key>
+	Ref string `json:"ref" yaml:"ref"`
+}
+
+func NewSignatureValidator(ctx context.Context, logger log.Interface, config *Config) (Interface, error) {
+	if config.Signature == nil {
+		return nil, fmt.Errorf("no signature validator configuration provided")
+	}
+
+	switch config.Signature.Format {
+	case SignatureFormatJWS, SignatureFormatEnvelope, SignatureFormatDetached:
+	default:
+		return nil, fmt.Errorf("unsupported signature format %q", config.Signature.Format)
+	}
+
+	if len(config.Signature.PublicKeys) == 0 {
+		return nil, fmt.Errorf("no public key provided")
+	}
+
+	suffix := config.Signature.SignatureKeySuffix
+	if suffix == "" {
+		suffix = defaultSignatureKeySuffix
+	}
+
+	v := &SignatureValidator{
+		ctx:      ctx,
+		logger:   logger,
+		dataKeys: config.DataKeys,
+
+		format:        config.Signature.Format,
+		sigKeySuffix:  suffix,
+		payloadAsData: config.Signature.PayloadAsData,
+		keyConfigs:    config.Signature.PublicKeys,
+
+		mu: new(sync.RWMutex),
+	}
+
+	keys, err := v.loadKeys()
+	if err != nil {
+		return nil, err
+	}
+	v.keys = keys
+	v.keysLoadedAt = time.Now()
+
+	return v, nil
+}
+
+// SignatureValidator verifies signatures of data
+type SignatureValidator struct {
+	ctx      context.Context
+	logger   log.Interface
+	dataKeys []string
+
+	format        string
+	sigKeySuffix  string
+	payloadAsData bool
+	keyConfigs    []SignaturePublicKey
+
+	keys         []*signaturePublicKey
+	keysLoadedAt time.Time
+	mu           *sync.RWMutex
+}
+
+type signaturePublicKey struct {
+	id  string
+	key interface{}
+}
+
+func (s *SignatureValidator) loadKeys() ([]*signaturePublicKey, error) {
+	var keys []*signaturePublicKey
+	for i, kc := range s.keyConfigs {
+		if len(s.keyConfigs) > 1 && kc.KeyID == "" {
+			return nil, fmt.Errorf("keyID of public key #%d is required when there are multiple public keys", i)
+		}
+
+		var (
+			data []byte
+			err  error
+		)
+		switch {
+		case kc.PEM != "" && kc.Ref == "":
+			data = []byte(kc.PEM)
+		case kc.Ref != "" && kc.PEM == "":
+			data, err = dataref.Resolve(s.ctx, kc.Ref)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load public key %q: %w", kc.KeyID, err)
+			}
+		default:
+			return nil, fmt.Errorf("exactly one of pem and ref is required for public key %q", kc.KeyID)
+		}
+
+		key, err := parseSignaturePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key %q: %w", kc.KeyID, err)
+		}
+
+		keys = append(keys, &signaturePublicKey{id: kc.KeyID, key: key})
+	}
+
+	return keys, nil
+}
+
+// findKeys returns keys matching keyID, public keys are reloaded if keyID is unknown to support
+// key rotation
+func (s *SignatureValidator) findKeys(keyID string) []*signaturePublicKey {
+	match := func() []*signaturePublicKey {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		if keyID == "" {
+			return s.keys
+		}
+
+		for _, k := range s.keys {
+			if k.id == keyID {
+				return []*signaturePublicKey{k}
+			}
+		}
+
+		return nil
+	}
+
+	if keys := match(); len(keys) != 0 {
+		return keys
+	}
+
+	s.mu.Lock()
+	if time.Since(s.keysLoadedAt) > signatureKeyRefreshInterval {
+		s.keysLoadedAt = time.Now()
+
+		keys, err := s.loadKeys()
+		if err != nil {
+			s.logger.I("failed to reload public keys", log.Error(err))
+		} else {
+			s.keys = keys
+		}
+	}
+	s.mu.Unlock()
+
+	return match()
+}
+
+func (s *SignatureValidator) Validate(vctx *ValidationContext, data map[string][]byte) *DataMsg {
+	result := &DataMsg{
+		Data:   make(map[string][]byte),
+		Errors: make(map[string]error),
+	}
+
+	for _, k := range s.dataKeys {
+		d, ok := data[k]
+		if !ok {
+			continue
+		}
+
+		var (
+			payload []byte
+			err     error
+		)
+		switch s.format {
+		case SignatureFormatJWS:
+			payload, err = s.verifyJWS(d)
+		case SignatureFormatEnvelope:
+			payload, err = s.verifyEnvelope(d)
+		case SignatureFormatDetached:
+			sig, ok := data[k+s.sigKeySuffix]
+			if !ok {
+				err = fmt.Errorf("signature key %q not found", k+s.sigKeySuffix)
+				break
+			}
+
+			payload, err = s.verifyDetached(d, sig)
+		}
+
+		if err != nil {
+			result.Errors[k] = fmt.Errorf("signature verification failed: %w", err)
+			continue
+		}
+
+		if s.payloadAsData {
+			result.Data[k] = payload
+		} else {
+			result.Data[k] = d
+		}
+	}
+
+	return result
+}
+
+func (s *SignatureValidator) verifyJWS(data []byte) ([]byte, error) {
+	parts := strings.Split(strings.TrimSpace(string(data)), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid jws compact serialization")
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid jws header encoding: %w", err)
+	}
+
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	err = json.Unmarshal(headerBytes, &header)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jws header: %w", err)
+	}
+
+	if parts[1] == "" {
+		return nil, fmt.Errorf("detached jws payload is not supported")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid jws payload encoding: %w", err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid jws signature encoding: %w", err)
+	}
+
+	var curve elliptic.Curve
+	switch header.Alg {
+	case "EdDSA":
+	case "ES256":
+		curve = elliptic.P256()
+	case "ES384":
+		curve = elliptic.P384()
+	case "ES512":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported jws algorithm %q", header.Alg)
+	}
+
+	signingInput := []byte(parts[0] + "." + parts[1])
+	for _, k := range s.findKeys(header.Kid) {
+		switch key := k.key.(type) {
+		case ed25519.PublicKey:
+			if curve == nil && ed25519.Verify(key, signingInput, sig) {
+				return payload, nil
+			}
+		case *ecdsa.PublicKey:
+			// jws ecdsa signature must be in raw format
+			if curve != nil && key.Curve == curve && len(sig) == 2*ecdsaKeySize(key) &&
+				verifyECDSA(key, signingInput, sig) {
+				return payload, nil
+			}
+		}
+	}
+
+	return nil, signatureMismatchError(header.Kid)
+}
+
+func (s *SignatureValidator) verifyEnvelope(data []byte) ([]byte, error) {
+	envelope := struct {
+		Payload   string `json:"payload"`
+		Signature string `json:"signature"`
+		KeyID     string `json:"keyID"`
+	}{}
+
+	err := json.Unmarshal(data, &envelope)
+	if err != nil {
+		return nil, fmt.Errorf("invalid envelope: %w", err)
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload encoding: %w", err)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(envelope.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	if s.verify(envelope.KeyID, payload, sig) {
+		return payload, nil
+	}
+
+	return nil, signatureMismatchError(envelope.KeyID)
+}
+
+func (s *SignatureValidator) verifyDetached(data, sigData []byte) ([]byte, error) {
+	var (
+		keyID      string
+		encodedSig = strings.TrimSpace(string(sigData))
+	)
+	if i := strings.IndexByte(encodedSig, ':'); i >= 0 {
+		keyID, encodedSig = encodedSig[:i], encodedSig[i+1:]
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	if s.verify(keyID, data, sig) {
+		return data, nil
+	}
+
+	return nil, signatureMismatchError(keyID)
+}
+
+// verify raw signature (ecdsa signatures in raw or asn.1 format)
+func (s *SignatureValidator) verify(keyID string, data, sig []byte) bool {
+	for _, k := range s.findKeys(keyID) {
+		switch key := k.key.(type) {
+		case ed25519.PublicKey:
+			if ed25519.Verify(key, data, sig) {
+				return true
+			}
+		case *ecdsa.PublicKey:
+			if verifyECDSA(key, data, sig) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func signatureMismatchError(keyID string) error {
+	if keyID == "" {
+		return fmt.Errorf("no public key matches the signature")
+	}
+
+	return fmt.Errorf("signature does not match public key %q", keyID)
+}
+
+func parseSignaturePublicKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no pem block found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+func ecdsaKeySize(key *ecdsa.PublicKey) int {
+	return (key.Curve.Params().BitSize + 7) / 8
+}
+
+// verifyECDSA verifies signature in raw (r || s) or asn.1 format
+func verifyECDSA(key *ecdsa.PublicKey, data, sig []byte) bool {
+	var h hash.Hash
+	switch key.Curve.Params().BitSize {
+	case 256:
+		h = sha256.New()
+	case 384:
+		h = sha512.New384()
+	default:
+		h = sha512.New()
+	}
+	_, _ = h.Write(data)
+	digest := h.Sum(nil)
+
+	if size := ecdsaKeySize(key); len(sig) == 2*size {
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if ecdsa.Verify(key, digest, r, s) {
+			return true
+		}
+	}
+
+	var asn1Sig struct {
+		R, S *big.Int
+	}
+	rest, err := asn1.Unmarshal(sig, &asn1Sig)
+	if err != nil || len(rest) != 0 {
+		return false
+	}
+
+	return ecdsa.Verify(key, digest, asn1Sig.R, asn1Sig.S)
+}
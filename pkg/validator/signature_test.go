@@ -0,0 +1,115 @@
+package validator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+
+	"arhat.dev/pkg/log"
+)
+
+func encodePublicKeyPEM(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestSignatureValidator(t *testing.T) {
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []SignaturePublicKey{
+		{KeyID: "ed", PEM: encodePublicKeyPEM(t, edPub)},
+		{KeyID: "ec", PEM: encodePublicKeyPEM(t, &ecPriv.PublicKey)},
+	}
+
+	newValidator := func(format string) Interface {
+		v, err2 := NewSignatureValidator(context.TODO(), log.Log.WithName("test"), &Config{
+			Method:   MethodSignature,
+			DataKeys: []string{"good", "bad"},
+			Signature: &SignatureConfig{
+				Format:        format,
+				PayloadAsData: true,
+				PublicKeys:    keys,
+			},
+		})
+		if err2 != nil {
+			t.Fatal(err2)
+		}
+		return v
+	}
+
+	payload := []byte("foo: bar")
+	check := func(t *testing.T, result *DataMsg) {
+		if string(result.Data["good"]) != string(payload) {
+			t.Errorf("expected verified payload, got %q, error %v", result.Data["good"], result.Errors["good"])
+		}
+
+		if result.Errors["bad"] == nil {
+			t.Errorf("expected bad signature rejected")
+		}
+	}
+
+	t.Run("JWS", func(t *testing.T) {
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"EdDSA","kid":"ed"}`))
+		signingInput := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+		sig := base64.RawURLEncoding.EncodeToString(ed25519.Sign(edPriv, []byte(signingInput)))
+		badSig := base64.RawURLEncoding.EncodeToString(ed25519.Sign(edPriv, []byte("other")))
+
+		check(t, newValidator(SignatureFormatJWS).Validate(&ValidationContext{}, map[string][]byte{
+			"good": []byte(signingInput + "." + sig),
+			"bad":  []byte(signingInput + "." + badSig),
+		}))
+	})
+
+	t.Run("Envelope", func(t *testing.T) {
+		digest := sha256.Sum256(payload)
+		sig, err2 := ecdsa.SignASN1(rand.Reader, ecPriv, digest[:])
+		if err2 != nil {
+			t.Fatal(err2)
+		}
+
+		envelope := func(keyID string) []byte {
+			data, _ := json.Marshal(map[string]string{
+				"payload":   base64.StdEncoding.EncodeToString(payload),
+				"signature": base64.StdEncoding.EncodeToString(sig),
+				"keyID":     keyID,
+			})
+			return data
+		}
+
+		check(t, newValidator(SignatureFormatEnvelope).Validate(&ValidationContext{}, map[string][]byte{
+			"good": envelope("ec"),
+			"bad":  envelope("ed"),
+		}))
+	})
+
+	t.Run("Detached", func(t *testing.T) {
+		sig := base64.StdEncoding.EncodeToString(ed25519.Sign(edPriv, payload))
+
+		check(t, newValidator(SignatureFormatDetached).Validate(&ValidationContext{}, map[string][]byte{
+			"good":     payload,
+			"good.sig": []byte(sig),
+			"bad":      []byte("foo: baz"),
+			"bad.sig":  []byte("ed:" + sig),
+		}))
+	})
+}
@@ -0,0 +1,137 @@
+package validator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+
+	"arhat.dev/pkg/log"
+)
+
+func init() {
+	RegisterValidator(MethodTemplate, NewTemplateValidator)
+}
+
+const (
+	MethodTemplate = "template"
+)
+
+type TemplateConfig struct {
+	// Templates to render new data keys
+	Templates []TemplateSpec `json:"templates" yaml:"templates"`
+
+	// DropDataKeys are data keys not to be written, e.g. source keys of templates
+	DropDataKeys []string `json:"dropDataKeys" yaml:"dropDataKeys"`
+}
+
+type TemplateSpec struct {
+	// DataKey to store rendered data
+	DataKey string `json:"dataKey" yaml:"dataKey"`
+
+	// Template (sprig and jq funcs available) to render data
+	Template string `json:"template" yaml:"template"`
+}
+
+// variables used when rendering templates
+type templateRenderVars struct {
+	// DataKeys are source data keys
+	DataKeys []string
+	// DataKey to be rendered
+	DataKey string
+	// Data of all data keys
+	Data map[string]string
+
+	// Context of the validation
+	Context *ValidationContext
+}
+
+func NewTemplateValidator(ctx context.Context, logger log.Interface, config *Config) (Interface, error) {
+	if config.Template == nil {
+		return nil, fmt.Errorf("no template validator configuration provided")
+	}
+
+	if len(config.Template.Templates) == 0 && len(config.Template.DropDataKeys) == 0 {
+		return nil, fmt.Errorf("no template provided")
+	}
+
+	var templates []*dataKeyTemplate
+	for _, t := range config.Template.Templates {
+		if t.DataKey == "" {
+			return nil, fmt.Errorf("no data key provided for template")
+		}
+
+		tpl, err := template.New(t.DataKey).Funcs(funcMapWithConverters()).Option("missingkey=error").Parse(t.Template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template for %q: %w", t.DataKey, err)
+		}
+
+		templates = append(templates, &dataKeyTemplate{
+			dataKey:  t.DataKey,
+			template: tpl,
+		})
+	}
+
+	return &TemplateValidator{
+		dataKeys:     config.DataKeys,
+		templates:    templates,
+		dropDataKeys: config.Template.DropDataKeys,
+	}, nil
+}
+
+type dataKeyTemplate struct {
+	dataKey  string
+	template *template.Template
+}
+
+// TemplateValidator renders new data keys from data, templates are rendered only when all source
+// data keys (if any) are available
+type TemplateValidator struct {
+	dataKeys     []string
+	templates    []*dataKeyTemplate
+	dropDataKeys []string
+}
+
+func (t *TemplateValidator) Validate(vctx *ValidationContext, data map[string][]byte) *DataMsg {
+	result := &DataMsg{
+		Data:   make(map[string][]byte),
+		Errors: make(map[string]error),
+	}
+
+	for _, k := range t.dataKeys {
+		if _, ok := data[k]; !ok {
+			// not all source data available
+			return result
+		}
+	}
+
+	vars := &templateRenderVars{
+		DataKeys: t.dataKeys,
+		Data:     make(map[string]string, len(data)),
+		Context:  vctx,
+	}
+	for k, v := range data {
+		vars.Data[k] = string(v)
+	}
+
+	for _, tpl := range t.templates {
+		vars.DataKey = tpl.dataKey
+
+		buf := new(bytes.Buffer)
+		err := tpl.template.Execute(buf, vars)
+		if err != nil {
+			result.Errors[tpl.dataKey] = fmt.Errorf("failed to render template: %w", err)
+			continue
+		}
+
+		result.Data[tpl.dataKey] = buf.Bytes()
+	}
+
+	for _, k := range t.dropDataKeys {
+		if _, rendered := result.Data[k]; !rendered {
+			result.Drop = append(result.Drop, k)
+		}
+	}
+
+	return result
+}
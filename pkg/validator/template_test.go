@@ -0,0 +1,84 @@
+package validator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+func TestTemplateValidator(t *testing.T) {
+	v, err := NewTemplateValidator(context.TODO(), nil, &Config{
+		Method:   MethodTemplate,
+		DataKeys: []string{"foo", "bar"},
+		Template: &TemplateConfig{
+			Templates: []TemplateSpec{
+				{
+					DataKey:  "app.yaml",
+					Template: `{{- dict "foo" (.Data.foo | fromJson) "bar" .Data.bar | toYaml -}}`,
+				},
+				{
+					DataKey:  "app.toml",
+					Template: `{{- .Data.foo | fromJson | toToml -}}`,
+				},
+				{
+					DataKey:  "app.ini",
+					Template: `{{- .Data.foo | fromJson | toIni -}}`,
+				},
+				{
+					DataKey:  "app.properties",
+					Template: `{{- .Data.foo | fromJson | toProperties -}}`,
+				},
+			},
+			DropDataKeys: []string{"foo", "bar"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	foo := []byte(`{"name": "a b", "db": {"port": 5432, "hosts": ["x", "y"]}}`)
+
+	result := v.Validate(&ValidationContext{}, map[string][]byte{"foo": foo})
+	if len(result.Data) != 0 || len(result.Drop) != 0 {
+		t.Fatalf("expected nothing rendered without all source keys, got %v", result)
+	}
+
+	result = v.Validate(&ValidationContext{}, map[string][]byte{
+		"foo": foo,
+		"bar": []byte("baz"),
+	})
+
+	expected := map[string]string{
+		"app.yaml":       "bar: baz\nfoo:\n  db:\n    hosts:\n    - x\n    - \"y\"\n    port: 5432\n  name: a b\n",
+		"app.toml":       "name = \"a b\"\n\n[db]\nhosts = [\"x\", \"y\"]\nport = 5432\n",
+		"app.ini":        "name = a b\n\n[db]\nhosts = x,y\nport = 5432\n",
+		"app.properties": "db.hosts.0=x\ndb.hosts.1=y\ndb.port=5432\nname=a b\n",
+	}
+	for k, exp := range expected {
+		if actual := string(result.Data[k]); actual != exp {
+			t.Errorf("unexpected %s:\nexpected: %q\nactual:   %q\nerror: %v", k, exp, actual, result.Errors[k])
+		}
+	}
+
+	if len(result.Drop) != 2 {
+		t.Errorf("expected source keys dropped, got %v", result.Drop)
+	}
+}
+
+func TestFuncMapWithConverters(t *testing.T) {
+	shared, converters, sprigFuncs := funcMapWithJQ(), funcMapWithConverters(), sprig.HermeticTxtFuncMap()
+	for _, name := range []string{"fromJson", "fromYaml", "toYaml", "toToml", "toIni", "toProperties"} {
+		if _, ok := converters[name]; !ok {
+			t.Errorf("converter %q not registered", name)
+		}
+
+		if _, ok := shared[name]; ok {
+			t.Errorf("converter %q registered in shared func map", name)
+		}
+
+		if _, ok := sprigFuncs[name]; ok {
+			t.Errorf("sprig func %q overridden", name)
+		}
+	}
+}
@@ -93,7 +93,7 @@ type TextValidator struct {
 	expectDataTpl *template.Template
 }
 
-func (j *TextValidator) Validate(data map[string][]byte) *DataMsg {
+func (j *TextValidator) Validate(vctx *ValidationContext, data map[string][]byte) *DataMsg {
 	result := &DataMsg{
 		Data:   make(map[string][]byte),
 		Errors: make(map[string]error),
@@ -108,6 +108,7 @@ func (j *TextValidator) Validate(data map[string][]byte) *DataMsg {
 			DataKeys: j.dataKeys,
 			DataKey:  k,
 			Data:     d,
+			Context:  vctx,
 		}
 
 		vm, err := j.variables.EvalAndConvertToStringInterfacesMap(tplVar)
@@ -116,42 +117,19 @@ func (j *TextValidator) Validate(data map[string][]byte) *DataMsg {
 			continue
 		}
 
-		variables := make(map[string]interface{})
+		variables := map[string]interface{}{
+			"$ctx": vctx.jqValue(),
+		}
 		for k, vs := range vm {
 			if len(vs) != 0 {
 				variables[k] = vs[0]
 			}
 		}
 
-		var (
-			unmarshal func([]byte, interface{}) error
-			dataInput interface{}
-		)
-
-		switch j.expectSchema {
-		case TextSchemaJSON:
-			unmarshal = json.Unmarshal
-		case TextSchemaYAML:
-			unmarshal = func(bytes []byte, i interface{}) error {
-				return yaml.UnmarshalStrict(bytes, i)
-			}
-		case "", TextSchemaPlainText:
-			dataInput = string(d)
-		}
-
-		if unmarshal != nil {
-			dataInput = make(map[string]interface{})
-			err = unmarshal(d, &dataInput)
-			if err != nil {
-				// maybe it's an array
-				dataInput = []interface{}{}
-				err = unmarshal(d, &dataInput)
-			}
-
-			if err != nil {
-				result.Errors[k] = fmt.Errorf("%s shcema not valid: %w", j.expectSchema, err)
-				continue
-			}
+		dataInput, err := parseTextData(j.expectSchema, d)
+		if err != nil {
+			result.Errors[k] = fmt.Errorf("%s shcema not valid: %w", j.expectSchema, err)
+			continue
 		}
 
 		queryRet, found, err := textquery.RunQuery(j.query, dataInput, variables)
@@ -199,3 +177,36 @@ func (j *TextValidator) Validate(data map[string][]byte) *DataMsg {
 
 	return result
 }
+
+// parseTextData parses data according to schema (one of plaintext, json, yaml), plaintext data
+// is returned as string, nil is returned for unknown schema
+func parseTextData(schema string, d []byte) (interface{}, error) {
+	var unmarshal func([]byte, interface{}) error
+
+	switch schema {
+	case TextSchemaJSON:
+		unmarshal = json.Unmarshal
+	case TextSchemaYAML:
+		unmarshal = func(bytes []byte, i interface{}) error {
+			return yaml.UnmarshalStrict(bytes, i)
+		}
+	case "", TextSchemaPlainText:
+		return string(d), nil
+	default:
+		return nil, nil
+	}
+
+	var dataInput interface{} = make(map[string]interface{})
+	err := unmarshal(d, &dataInput)
+	if err != nil {
+		// maybe it's an array
+		dataInput = []interface{}{}
+		err = unmarshal(d, &dataInput)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dataInput, nil
+}
@@ -6,6 +6,8 @@ import (
 	"sync"
 
 	"arhat.dev/pkg/log"
+
+	"arhat.dev/ksync/pkg/constant"
 )
 
 // variables used when evaluating templates
@@ -14,9 +16,116 @@ type templateVars struct {
 	DataKey  string
 	Data     []byte
 
+	// Context of the validation
+	Context *ValidationContext
+
 	Extra interface{}
 }
 
+// ValidationContext is the context of a validation, available in templates as `.Context` and in
+// jq queries as `$ctx`
+type ValidationContext struct {
+	// Target to be updated with validated data
+	Target SyncTarget `json:"target"`
+
+	// TargetData is current data of the sync target, nil if the target not found
+	TargetData map[string]string `json:"targetData"`
+
+	// BufferedData is validated data waiting to be written (from all fetchers)
+	BufferedData map[string]string `json:"bufferedData"`
+
+	// Fetcher is the method of the fetcher produced the data, empty for transformers and target
+	// dependent validators
+	Fetcher string `json:"fetcher"`
+}
+
+// NewValidationContext creates validation context for data from fetcher with buffered data,
+// current data of the sync target is retrieved with getter in ctx
+func NewValidationContext(
+	ctx context.Context, fetcher string, buffered map[string][]byte,
+) (*ValidationContext, error) {
+	vctx := &ValidationContext{
+		Target:       SyncTargetFromContext(ctx),
+		BufferedData: make(map[string]string, len(buffered)),
+		Fetcher:      fetcher,
+	}
+
+	for k, v := range buffered {
+		vctx.BufferedData[k] = string(v)
+	}
+
+	if vctx.Target.Name == "" {
+		return vctx, nil
+	}
+
+	obj, err := getTargetObject(ctx, vctx.Target)
+	if err != nil {
+		return vctx, err
+	}
+
+	if obj != nil {
+		vctx.TargetData = make(map[string]string, len(obj.Data))
+		for k, v := range obj.Data {
+			vctx.TargetData[k] = string(v)
+		}
+	}
+
+	return vctx, nil
+}
+
+// jqValue converts context to value usable in jq queries
+func (c *ValidationContext) jqValue() interface{} {
+	if c == nil {
+		return nil
+	}
+
+	toMap := func(m map[string]string) interface{} {
+		if m == nil {
+			return nil
+		}
+
+		ret := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			ret[k] = v
+		}
+		return ret
+	}
+
+	return map[string]interface{}{
+		"target": map[string]interface{}{
+			"kind":      c.Target.Kind,
+			"namespace": c.Target.Namespace,
+			"name":      c.Target.Name,
+			"syncer":    c.Target.Syncer,
+		},
+		"targetData":   toMap(c.TargetData),
+		"bufferedData": toMap(c.BufferedData),
+		"fetcher":      c.Fetcher,
+	}
+}
+
+// SyncTarget is the object to be updated with validated data
+type SyncTarget struct {
+	// Kind of the target, one of configmap, secret
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// Syncer is the reference to the syncer config
+	Syncer string `json:"syncer"`
+}
+
+// WithSyncTarget returns a context with sync target for validators
+func WithSyncTarget(ctx context.Context, target SyncTarget) context.Context {
+	return context.WithValue(ctx, constant.ContextKeySyncTarget, target)
+}
+
+// SyncTargetFromContext returns empty sync target if not set
+func SyncTargetFromContext(ctx context.Context) SyncTarget {
+	t, _ := ctx.Value(constant.ContextKeySyncTarget).(SyncTarget)
+	return t
+}
+
 type FactoryFunc func(context.Context, log.Interface, *Config) (Interface, error)
 
 var (
@@ -31,6 +140,24 @@ func RegisterValidator(name string, factory FactoryFunc) {
 	validators[name] = factory
 }
 
+// UnregisterValidator removes the validator registered with name, validators created are not
+// affected
+func UnregisterValidator(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	delete(validators, name)
+}
+
+// HasValidator returns true if a validator is registered with name
+func HasValidator(name string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	_, ok := validators[name]
+	return ok
+}
+
 type DataMsg struct {
 	// Data is the dataKey to data content map
 	Data map[string][]byte
@@ -38,10 +165,27 @@ type DataMsg struct {
 	// Errors happened when retrieving the target data
 	// key: <data-key> (for fetcher) or  <fetcher-name>/<data-key> (for syncer)
 	Errors map[string]error
+
+	// Drop are data keys to be removed from data without error
+	Drop []string
 }
 
 type Interface interface {
-	Validate(data map[string][]byte) *DataMsg
+	// Validate data in the validation context
+	Validate(vctx *ValidationContext, data map[string][]byte) *DataMsg
+}
+
+// TargetDependent is implemented by validators whose result depends on the sync target in the
+// validation context, they are evaluated for each write target of the syncer
+type TargetDependent interface {
+	TargetDependent() bool
+}
+
+// IsTargetDependent returns true if validator v implements TargetDependent and depends on
+// the sync target
+func IsTargetDependent(v Interface) bool {
+	td, ok := v.(TargetDependent)
+	return ok && td.TargetDependent()
 }
 
 // Config for a single validator to validate data
@@ -56,6 +200,25 @@ type Config struct {
 	HTTP *HTTPConfig `json:"http" yaml:"http"`
 	// Text validator configuration
 	Text *TextConfig `json:"text" yaml:"text"`
+	// JSONSchema validator configuration
+	JSONSchema *JSONSchemaConfig `json:"jsonschema" yaml:"jsonschema"`
+	// Policy validator configuration
+	Policy *PolicyConfig `json:"policy" yaml:"policy"`
+	// Signature validator configuration
+	Signature *SignatureConfig `json:"signature" yaml:"signature"`
+	// Decrypt validator configuration
+	Decrypt *DecryptConfig `json:"decrypt" yaml:"decrypt"`
+	// Template validator configuration
+	Template *TemplateConfig `json:"template" yaml:"template"`
+	// Exec validator configuration
+	Exec *ExecConfig `json:"exec" yaml:"exec"`
+	// X509 validator configuration
+	X509 *X509Config `json:"x509" yaml:"x509"`
+	// Guard validator configuration
+	Guard *GuardConfig `json:"guard" yaml:"guard"`
+
+	// Plugin configuration, for methods served by plugins
+	Plugin map[string]string `json:"plugin" yaml:"plugin"`
 }
 
 func New(ctx context.Context, logger log.Interface, config *Config) (Interface, error) {
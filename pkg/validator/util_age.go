@@ -0,0 +1,438 @@
+package validator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"arhat.dev/ksync/pkg/validator/internal/xcrypto/chacha20poly1305"
+	"arhat.dev/ksync/pkg/validator/internal/xcrypto/curve25519"
+	"arhat.dev/ksync/pkg/validator/internal/xcrypto/hkdf"
+)
+
+// age file format: https://age-encryption.org/v1
+
+const (
+	ageIntroLine      = "age-encryption.org/v1"
+	ageStanzaPrefix   = "-> "
+	ageFooterPrefix   = "---"
+	ageArmorBegin     = "-----BEGIN AGE ENCRYPTED FILE-----"
+	ageArmorEnd       = "-----END AGE ENCRYPTED FILE-----"
+	ageColumnsPerLine = 64
+
+	ageX25519StanzaType = "X25519"
+	ageX25519Label      = "age-encryption.org/v1/X25519"
+	ageSecretKeyHRP     = "age-secret-key-"
+
+	ageFileKeySize   = 16
+	ageNonceSize     = 16
+	ageChunkSize     = 64 * 1024
+	ageEncChunkSize  = ageChunkSize + chacha20poly1305.Overhead
+	ageLastChunkFlag = 0x01
+)
+
+var ageB64 = base64.RawStdEncoding.Strict()
+
+// ageIdentity is a native X25519 age identity
+type ageIdentity struct {
+	secretKey []byte
+	publicKey []byte
+}
+
+type ageStanza struct {
+	Type string
+	Args []string
+	Body []byte
+}
+
+type ageHeader struct {
+	stanzas []*ageStanza
+	mac     []byte
+
+	// header bytes covered by mac
+	raw []byte
+}
+
+// parseAgeIdentities parses X25519 identities in age key file format,
+// empty lines and comments are ignored
+func parseAgeIdentities(data []byte) ([]*ageIdentity, error) {
+	var ret []*ageIdentity
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hrp, key, err := bech32Decode(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity at line %d: %w", i+1, err)
+		}
+
+		if hrp != ageSecretKeyHRP {
+			return nil, fmt.Errorf("unsupported identity type %q at line %d", hrp, i+1)
+		}
+
+		if len(key) != curve25519.ScalarSize {
+			return nil, fmt.Errorf("invalid X25519 identity size at line %d", i+1)
+		}
+
+		pub, err := curve25519.X25519(key, curve25519.Basepoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid X25519 identity at line %d: %w", i+1, err)
+		}
+
+		ret = append(ret, &ageIdentity{secretKey: key, publicKey: pub})
+	}
+
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("no identity found")
+	}
+
+	return ret, nil
+}
+
+// unwrap file key from X25519 stanza
+func (id *ageIdentity) unwrap(s *ageStanza) ([]byte, error) {
+	if len(s.Args) != 1 {
+		return nil, fmt.Errorf("invalid X25519 stanza")
+	}
+
+	share, err := ageB64.DecodeString(s.Args[0])
+	if err != nil || len(share) != curve25519.PointSize {
+		return nil, fmt.Errorf("invalid X25519 stanza share")
+	}
+
+	if len(s.Body) != ageFileKeySize+chacha20poly1305.Overhead {
+		return nil, fmt.Errorf("invalid X25519 stanza body")
+	}
+
+	// fails with low order share
+	shared, err := curve25519.X25519(id.secretKey, share)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 0, len(share)+len(id.publicKey))
+	salt = append(salt, share...)
+	salt = append(salt, id.publicKey...)
+
+	wrappingKey, err := ageHKDF(shared, salt, ageX25519Label, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.New(wrappingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), s.Body, nil)
+}
+
+// decryptAge decrypts age encrypted data (binary or armored) with any of the identities
+func decryptAge(identities []*ageIdentity, data []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte(ageArmorBegin)) {
+		var err error
+		data, err = dearmorAge(trimmed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r := bufio.NewReader(bytes.NewReader(data))
+	hdr, err := parseAgeHeader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid age header: %w", err)
+	}
+
+	var fileKey []byte
+	for _, s := range hdr.stanzas {
+		if s.Type != ageX25519StanzaType {
+			continue
+		}
+
+		for _, id := range identities {
+			fileKey, err = id.unwrap(s)
+			if err == nil {
+				break
+			}
+		}
+
+		if fileKey != nil {
+			break
+		}
+	}
+
+	if fileKey == nil {
+		return nil, fmt.Errorf("no identity matched any of the recipients")
+	}
+
+	macKey, err := ageHKDF(fileKey, nil, "header", sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	h := hmac.New(sha256.New, macKey)
+	_, _ = h.Write(hdr.raw)
+	if !hmac.Equal(h.Sum(nil), hdr.mac) {
+		return nil, fmt.Errorf("bad header mac")
+	}
+
+	payload, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptAgePayload(fileKey, payload)
+}
+
+func decryptAgePayload(fileKey, payload []byte) ([]byte, error) {
+	if len(payload) < ageNonceSize {
+		return nil, fmt.Errorf("payload too short")
+	}
+
+	streamKey, err := ageHKDF(fileKey, payload[:ageNonceSize], "payload", chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.New(streamKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ret   []byte
+		nonce [chacha20poly1305.NonceSize]byte
+	)
+	payload = payload[ageNonceSize:]
+	for counter := uint64(0); ; counter++ {
+		last := len(payload) <= ageEncChunkSize
+		chunk := payload
+		if !last {
+			chunk = payload[:ageEncChunkSize]
+		}
+
+		// only the last chunk of an empty payload can be empty
+		if len(chunk) < chacha20poly1305.Overhead || (len(chunk) == chacha20poly1305.Overhead && counter != 0) {
+			return nil, fmt.Errorf("invalid payload chunk size")
+		}
+
+		// 11 bytes big endian counter, followed by the last chunk flag
+		binary.BigEndian.PutUint64(nonce[3:11], counter)
+		nonce[11] = 0
+		if last {
+			nonce[11] = ageLastChunkFlag
+		}
+
+		ret, err = aead.Open(ret, nonce[:], chunk, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt payload chunk %d: %w", counter, err)
+		}
+
+		if last {
+			return ret, nil
+		}
+
+		payload = payload[ageEncChunkSize:]
+	}
+}
+
+func parseAgeHeader(r *bufio.Reader) (*ageHeader, error) {
+	raw := new(bytes.Buffer)
+	readLine := func() (string, error) {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("failed to read line: %w", err)
+		}
+
+		raw.WriteString(line)
+		return strings.TrimSuffix(line, "\n"), nil
+	}
+
+	line, err := readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	if line != ageIntroLine {
+		return nil, fmt.Errorf("unexpected intro %q", line)
+	}
+
+	hdr := &ageHeader{}
+	for {
+		line, err = readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(line, ageFooterPrefix) {
+			mac := strings.TrimPrefix(line, ageFooterPrefix+" ")
+			if mac == line {
+				return nil, fmt.Errorf("malformed footer")
+			}
+
+			hdr.mac, err = ageB64.DecodeString(mac)
+			if err != nil {
+				return nil, fmt.Errorf("invalid header mac: %w", err)
+			}
+
+			// mac covers the header up to and including the footer prefix
+			rawHeader := raw.Bytes()
+			hdr.raw = rawHeader[:len(rawHeader)-len(line)-1+len(ageFooterPrefix)]
+			return hdr, nil
+		}
+
+		if !strings.HasPrefix(line, ageStanzaPrefix) {
+			return nil, fmt.Errorf("unexpected line %q", line)
+		}
+
+		args := strings.Split(strings.TrimPrefix(line, ageStanzaPrefix), " ")
+		if len(args) == 0 || args[0] == "" {
+			return nil, fmt.Errorf("malformed stanza")
+		}
+
+		s := &ageStanza{Type: args[0], Args: args[1:]}
+		for {
+			line, err = readLine()
+			if err != nil {
+				return nil, err
+			}
+
+			b, err := ageB64.DecodeString(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid stanza body: %w", err)
+			}
+
+			if len(line) > ageColumnsPerLine {
+				return nil, fmt.Errorf("stanza body line too long")
+			}
+
+			s.Body = append(s.Body, b...)
+
+			// body ends with a short line
+			if len(line) < ageColumnsPerLine {
+				break
+			}
+		}
+
+		hdr.stanzas = append(hdr.stanzas, s)
+	}
+}
+
+func dearmorAge(data []byte) ([]byte, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) < 2 || lines[0] != ageArmorBegin || strings.TrimSpace(lines[len(lines)-1]) != ageArmorEnd {
+		return nil, fmt.Errorf("invalid armored age file")
+	}
+
+	var encoded strings.Builder
+	for _, line := range lines[1 : len(lines)-1] {
+		encoded.WriteString(strings.TrimSpace(line))
+	}
+
+	ret, err := base64.StdEncoding.Strict().DecodeString(encoded.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid armored age file: %w", err)
+	}
+
+	return ret, nil
+}
+
+func ageHKDF(secret, salt []byte, info string, size int) ([]byte, error) {
+	ret := make([]byte, size)
+	_, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+
+	return chk
+}
+
+// bech32Decode decodes bech32 string (without length limit as used by age),
+// returns lower case human readable part and data
+func bech32Decode(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, fmt.Errorf("mixed case")
+	}
+	s = strings.ToLower(s)
+
+	pos := strings.LastIndex(s, "1")
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, fmt.Errorf("separator not found")
+	}
+
+	hrp := s[:pos]
+	values := make([]byte, 0, len(hrp)*2+1+len(s)-pos-1)
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, fmt.Errorf("invalid character in human readable part")
+		}
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]&31)
+	}
+
+	dataStart := len(values)
+	for i := pos + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return "", nil, fmt.Errorf("invalid character %q", s[i])
+		}
+		values = append(values, byte(v))
+	}
+
+	if bech32Polymod(values) != 1 {
+		return "", nil, fmt.Errorf("invalid checksum")
+	}
+
+	// convert 5 bits groups without checksum to bytes
+	var (
+		acc  uint32
+		bits uint
+		ret  []byte
+	)
+	for _, v := range values[dataStart : len(values)-6] {
+		acc = acc<<5 | uint32(v)
+		bits += 5
+		for bits >= 8 {
+			bits -= 8
+			ret = append(ret, byte(acc>>bits))
+		}
+	}
+
+	if bits >= 5 || acc&(1<<bits-1) != 0 {
+		return "", nil, fmt.Errorf("invalid padding")
+	}
+
+	return hrp, ret, nil
+}
@@ -1,10 +1,18 @@
 package validator
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"arhat.dev/pkg/textquery"
 	"github.com/Masterminds/sprig/v3"
+	"sigs.k8s.io/yaml"
 )
 
 func funcMapWithJQ() template.FuncMap {
@@ -13,3 +21,263 @@ func funcMapWithJQ() template.FuncMap {
 	fm["jqBytes"] = textquery.JQBytes
 	return fm
 }
+
+// funcMapWithConverters adds format converters to funcMapWithJQ, only used by the template
+// validator to avoid conflicts with funcs of the same names in other validators' templates
+// (e.g. newer sprig releases)
+func funcMapWithConverters() template.FuncMap {
+	fm := funcMapWithJQ()
+	fm["fromJson"] = fromJSON
+	fm["fromYaml"] = fromYAML
+	fm["toYaml"] = toYAML
+	fm["toToml"] = toTOML
+	fm["toIni"] = toINI
+	fm["toProperties"] = toProperties
+	return fm
+}
+
+func fromJSON(s string) (interface{}, error) {
+	var ret interface{}
+	err := json.Unmarshal([]byte(s), &ret)
+	return ret, err
+}
+
+func fromYAML(s string) (interface{}, error) {
+	var ret interface{}
+	err := yaml.Unmarshal([]byte(s), &ret)
+	return ret, err
+}
+
+func toYAML(v interface{}) (string, error) {
+	data, err := yaml.Marshal(v)
+	return string(data), err
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func toStringMap(v interface{}) (map[string]interface{}, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expecting map[string]interface{}, got %T", v)
+	}
+
+	return m, nil
+}
+
+var tomlBareKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(k string) string {
+	if tomlBareKeyRegex.MatchString(k) {
+		return k
+	}
+
+	return strconv.Quote(k)
+}
+
+func tomlValue(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		// json string escapes are valid in toml basic strings
+		data, err := json.Marshal(t)
+		return string(data), err
+	case bool, int, int64, float64, json.Number:
+		return fmt.Sprint(t), nil
+	case []interface{}:
+		values := make([]string, len(t))
+		for i, item := range t {
+			var err error
+			values[i], err = tomlValue(item)
+			if err != nil {
+				return "", err
+			}
+		}
+		return "[" + strings.Join(values, ", ") + "]", nil
+	case map[string]interface{}:
+		var values []string
+		for _, k := range sortedKeys(t) {
+			if t[k] == nil {
+				continue
+			}
+
+			value, err := tomlValue(t[k])
+			if err != nil {
+				return "", err
+			}
+			values = append(values, tomlKey(k)+" = "+value)
+		}
+		return "{" + strings.Join(values, ", ") + "}", nil
+	default:
+		return "", fmt.Errorf("unsupported toml value type %T", v)
+	}
+}
+
+// toTOML converts map to toml document, nested maps are converted to tables
+func toTOML(v interface{}) (string, error) {
+	m, err := toStringMap(v)
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+	err = writeTOMLTable(buf, nil, m)
+	return buf.String(), err
+}
+
+func isTOMLTableArray(v interface{}) bool {
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) == 0 {
+		return false
+	}
+
+	for _, item := range arr {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func writeTOMLTable(buf *bytes.Buffer, path []string, m map[string]interface{}) error {
+	var tables []string
+	for _, k := range sortedKeys(m) {
+		switch m[k].(type) {
+		case nil:
+			// toml has no null
+			continue
+		case map[string]interface{}:
+			tables = append(tables, k)
+			continue
+		}
+
+		if isTOMLTableArray(m[k]) {
+			tables = append(tables, k)
+			continue
+		}
+
+		value, err := tomlValue(m[k])
+		if err != nil {
+			return fmt.Errorf("invalid value of %q: %w", k, err)
+		}
+
+		buf.WriteString(tomlKey(k) + " = " + value + "\n")
+	}
+
+	for _, k := range tables {
+		tablePath := append(path[:len(path):len(path)], tomlKey(k))
+		header := strings.Join(tablePath, ".")
+
+		if sub, ok := m[k].(map[string]interface{}); ok {
+			buf.WriteString("\n[" + header + "]\n")
+			if err := writeTOMLTable(buf, tablePath, sub); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, item := range m[k].([]interface{}) {
+			buf.WriteString("\n[[" + header + "]]\n")
+			if err := writeTOMLTable(buf, tablePath, item.(map[string]interface{})); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func scalarString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		values := make([]string, len(t))
+		for i, item := range t {
+			values[i] = scalarString(item)
+		}
+		return strings.Join(values, ",")
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// toINI converts map to ini document, nested maps are converted to sections (with dotted names
+// for deeper levels) and lists to comma separated values
+func toINI(v interface{}) (string, error) {
+	m, err := toStringMap(v)
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+	writeINISection(buf, "", m)
+	return buf.String(), nil
+}
+
+func writeINISection(buf *bytes.Buffer, name string, m map[string]interface{}) {
+	var sections []string
+	for _, k := range sortedKeys(m) {
+		if _, ok := m[k].(map[string]interface{}); ok {
+			sections = append(sections, k)
+			continue
+		}
+
+		buf.WriteString(k + " = " + scalarString(m[k]) + "\n")
+	}
+
+	for _, k := range sections {
+		sectionName := k
+		if name != "" {
+			sectionName = name + "." + k
+		}
+
+		buf.WriteString("\n[" + sectionName + "]\n")
+		writeINISection(buf, sectionName, m[k].(map[string]interface{}))
+	}
+}
+
+var propertiesKeyEscaper = strings.NewReplacer(
+	`\`, `\\`, " ", `\ `, "=", `\=`, ":", `\:`, "#", `\#`, "!", `\!`, "\n", `\n`,
+)
+
+var propertiesValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// toProperties converts map to java properties, nested keys are joined with dots and list
+// items are indexed
+func toProperties(v interface{}) (string, error) {
+	m, err := toStringMap(v)
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+	writeProperties(buf, "", m)
+	return buf.String(), nil
+}
+
+func writeProperties(buf *bytes.Buffer, prefix string, v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(t) {
+			key := propertiesKeyEscaper.Replace(k)
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			writeProperties(buf, key, t[k])
+		}
+	case []interface{}:
+		for i, item := range t {
+			writeProperties(buf, prefix+"."+strconv.Itoa(i), item)
+		}
+	default:
+		buf.WriteString(prefix + "=" + propertiesValueEscaper.Replace(scalarString(t)) + "\n")
+	}
+}
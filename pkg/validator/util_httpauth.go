@@ -0,0 +1,211 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	pkgurl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"arhat.dev/ksync/pkg/dataref"
+)
+
+const (
+	HTTPAuthBearer = "bearer"
+	HTTPAuthBasic  = "basic"
+	HTTPAuthOAuth2 = "oauth2"
+)
+
+// data keys of credentials in the auth secret
+const (
+	httpAuthKeyToken        = "token"
+	httpAuthKeyUsername     = "username"
+	httpAuthKeyPassword     = "password"
+	httpAuthKeyClientID     = "clientID"
+	httpAuthKeyClientSecret = "clientSecret"
+)
+
+// refresh oauth2 token a little earlier than it expires
+const oauth2TokenExpiryDelta = 10 * time.Second
+
+type HTTPAuthConfig struct {
+	// Type of the auth, one of bearer, basic, oauth2 (client credentials)
+	Type string `json:"type" yaml:"type"`
+
+	// SecretRef is the name of the secret containing credentials in the namespace of the sync config
+	//
+	// 	bearer: token
+	// 	basic: username, password
+	// 	oauth2: clientID, clientSecret
+	SecretRef string `json:"secretRef" yaml:"secretRef"`
+
+	// TokenURL of the oauth2 token endpoint
+	TokenURL string `json:"tokenURL" yaml:"tokenURL"`
+
+	// Scopes to request for oauth2 token
+	Scopes []string `json:"scopes" yaml:"scopes"`
+}
+
+func newHTTPAuth(ctx context.Context, config *HTTPAuthConfig) (*httpAuth, error) {
+	switch config.Type {
+	case HTTPAuthBearer, HTTPAuthBasic:
+	case HTTPAuthOAuth2:
+		if config.TokenURL == "" {
+			return nil, fmt.Errorf("no token url provided for oauth2 auth")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported http auth type %q", config.Type)
+	}
+
+	if config.SecretRef == "" {
+		return nil, fmt.Errorf("no secret ref provided for %s auth", config.Type)
+	}
+
+	if strings.Contains(config.SecretRef, "/") {
+		return nil, fmt.Errorf("invalid secret ref %q: only secret in the namespace of the sync config is allowed",
+			config.SecretRef)
+	}
+
+	return &httpAuth{
+		ctx:    ctx,
+		config: config,
+	}, nil
+}
+
+// httpAuth sets authorization header of requests, credentials are read from the referenced
+// secret every time, so they can be rotated without restart
+type httpAuth struct {
+	ctx    context.Context
+	config *HTTPAuthConfig
+
+	// cached oauth2 token
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+	tokenClient string
+}
+
+func (a *httpAuth) credential(key string) (string, error) {
+	data, err := dataref.Resolve(a.ctx, fmt.Sprintf("%s://%s/%s", dataref.KindSecret, a.config.SecretRef, key))
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s auth credential: %w", a.config.Type, err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (a *httpAuth) authorize(ctx context.Context, client *http.Client, req *http.Request) error {
+	switch a.config.Type {
+	case HTTPAuthBearer:
+		token, err := a.credential(httpAuthKeyToken)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+	case HTTPAuthBasic:
+		username, err := a.credential(httpAuthKeyUsername)
+		if err != nil {
+			return err
+		}
+
+		password, err := a.credential(httpAuthKeyPassword)
+		if err != nil {
+			return err
+		}
+
+		req.SetBasicAuth(username, password)
+	case HTTPAuthOAuth2:
+		token, err := a.oauth2Token(ctx, client)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return nil
+}
+
+// invalidate cached token, called when the server rejected the token
+func (a *httpAuth) invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.token = ""
+}
+
+func (a *httpAuth) oauth2Token(ctx context.Context, client *http.Client) (string, error) {
+	clientID, err := a.credential(httpAuthKeyClientID)
+	if err != nil {
+		return "", err
+	}
+
+	clientSecret, err := a.credential(httpAuthKeyClientSecret)
+	if err != nil {
+		return "", err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// reuse token unless expired or credentials rotated
+	tokenClient := clientID + ":" + clientSecret
+	if a.token != "" && a.tokenClient == tokenClient &&
+		(a.tokenExpiry.IsZero() || time.Now().Before(a.tokenExpiry)) {
+		return a.token, nil
+	}
+
+	form := pkgurl.Values{"grant_type": {"client_credentials"}}
+	if len(a.config.Scopes) != 0 {
+		form.Set("scope", strings.Join(a.config.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create oauth2 token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(pkgurl.QueryEscape(clientID), pkgurl.QueryEscape(clientSecret))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request oauth2 token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read oauth2 token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to request oauth2 token: unexpected status code %d", resp.StatusCode)
+	}
+
+	tokenResp := &struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}{}
+	err = json.Unmarshal(body, tokenResp)
+	if err != nil {
+		return "", fmt.Errorf("invalid oauth2 token response: %w", err)
+	}
+
+	if tokenResp.AccessToken == "" {
+		return "", fmt.Errorf("no access token in oauth2 token response")
+	}
+
+	a.token = tokenResp.AccessToken
+	a.tokenClient = tokenClient
+	a.tokenExpiry = time.Time{}
+	if tokenResp.ExpiresIn > 0 {
+		a.tokenExpiry = time.Now().Add(time.Duration(tokenResp.ExpiresIn)*time.Second - oauth2TokenExpiryDelta)
+	}
+
+	return a.token, nil
+}
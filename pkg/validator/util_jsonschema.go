@@ -0,0 +1,764 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"sigs.k8s.io/yaml"
+)
+
+// max depth of nested schema evaluation, to stop infinite $ref recursion
+const jsonSchemaMaxDepth = 128
+
+// jsonSchema is a json schema (draft-04 to draft-07 subset) validator
+//
+// supported keywords:
+//   - type, enum, const
+//   - multipleOf, maximum, exclusiveMaximum, minimum, exclusiveMinimum
+//   - maxLength, minLength, pattern, format (date-time, date, time, email, hostname, ipv4, ipv6, uri)
+//   - items, additionalItems, maxItems, minItems, uniqueItems, contains
+//   - maxProperties, minProperties, required, properties, patternProperties,
+//     additionalProperties, dependencies, propertyNames
+//   - allOf, anyOf, oneOf, not, if, then, else
+//   - $ref (local references only, e.g. `#/definitions/foo`)
+//
+// schemas with other keywords (except annotations) or formats are rejected when compiled,
+// since ignoring them would accept data the schema author intended to reject
+type jsonSchema struct {
+	root interface{}
+
+	patterns map[string]*regexp.Regexp
+}
+
+// jsonSchemaViolation is a violation found at instance location pointed by Pointer (json pointer)
+type jsonSchemaViolation struct {
+	Pointer string
+	Message string
+}
+
+func (v jsonSchemaViolation) String() string {
+	ptr := v.Pointer
+	if ptr == "" {
+		ptr = "(root)"
+	}
+
+	return ptr + ": " + v.Message
+}
+
+// jsonSchemaError contains all violations found
+type jsonSchemaError []jsonSchemaViolation
+
+func (e jsonSchemaError) Error() string {
+	msgs := make([]string, len(e))
+	for i, v := range e {
+		msgs[i] = v.String()
+	}
+
+	return fmt.Sprintf("%d schema violation(s): %s", len(e), strings.Join(msgs, "; "))
+}
+
+// compileJSONSchema parses schema in json or yaml format
+func compileJSONSchema(data []byte) (*jsonSchema, error) {
+	root, err := unmarshalJSONOrYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	s := &jsonSchema{
+		root:     root,
+		patterns: make(map[string]*regexp.Regexp),
+	}
+
+	if err = s.prepare(root, ""); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// jsonSchemaKeywords are keywords supported by jsonSchema, including annotations
+var jsonSchemaKeywords = map[string]struct{}{
+	// annotations and identifiers
+	"$schema": {}, "$id": {}, "id": {}, "$comment": {}, "title": {}, "description": {},
+	"default": {}, "examples": {}, "readOnly": {}, "writeOnly": {}, "deprecated": {},
+	"contentMediaType": {}, "contentEncoding": {}, "definitions": {}, "$defs": {},
+
+	"$ref": {}, "type": {}, "enum": {}, "const": {},
+
+	"multipleOf": {}, "maximum": {}, "exclusiveMaximum": {}, "minimum": {}, "exclusiveMinimum": {},
+	"maxLength": {}, "minLength": {}, "pattern": {}, "format": {},
+
+	"items": {}, "additionalItems": {}, "maxItems": {}, "minItems": {}, "uniqueItems": {}, "contains": {},
+
+	"maxProperties": {}, "minProperties": {}, "required": {}, "properties": {}, "patternProperties": {},
+	"additionalProperties": {}, "dependencies": {}, "propertyNames": {},
+
+	"allOf": {}, "anyOf": {}, "oneOf": {}, "not": {}, "if": {}, "then": {}, "else": {},
+}
+
+// jsonSchemaDrafts are meta schemas jsonSchema follows, later drafts changed semantics of
+// supported keywords
+var jsonSchemaDrafts = map[string]struct{}{
+	"http://json-schema.org/draft-04/schema": {},
+	"http://json-schema.org/draft-06/schema": {},
+	"http://json-schema.org/draft-07/schema": {},
+}
+
+// jsonSchemaFormats are formats checked by jsonSchema
+var jsonSchemaFormats = map[string]struct{}{
+	"date-time": {}, "date": {}, "time": {}, "email": {}, "hostname": {}, "ipv4": {}, "ipv6": {}, "uri": {},
+}
+
+// unmarshalJSONOrYAML unmarshals data into json compatible values
+func unmarshalJSONOrYAML(data []byte) (interface{}, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret interface{}
+	err = json.Unmarshal(jsonData, &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// prepare checks references and compiles patterns in the schema
+// nolint:gocyclo
+func (s *jsonSchema) prepare(node interface{}, ptr string) error {
+	var sch map[string]interface{}
+	switch t := node.(type) {
+	case nil, bool:
+		// absent or boolean schema
+		return nil
+	case map[string]interface{}:
+		sch = t
+	default:
+		return fmt.Errorf("%s: schema must be an object or boolean", ptr)
+	}
+
+	for k := range sch {
+		if _, ok := jsonSchemaKeywords[k]; !ok {
+			return fmt.Errorf("%s/%s: unsupported keyword", ptr, escapeJSONPointer(k))
+		}
+	}
+
+	if v, ok := sch["$schema"]; ok {
+		if _, ok = jsonSchemaDrafts[strings.TrimSuffix(fmt.Sprint(v), "#")]; !ok {
+			return fmt.Errorf("%s/$schema: unsupported draft %v", ptr, v)
+		}
+	}
+
+	if v, ok := sch["format"]; ok {
+		f, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s/format: must be a string", ptr)
+		}
+
+		if _, ok = jsonSchemaFormats[f]; !ok {
+			return fmt.Errorf("%s/format: unsupported format %q", ptr, f)
+		}
+	}
+
+	if v, ok := sch["$ref"]; ok {
+		ref, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s/$ref: must be a string", ptr)
+		}
+
+		if _, err := s.resolveRef(ref); err != nil {
+			return fmt.Errorf("%s/$ref: %w", ptr, err)
+		}
+	}
+
+	if v, ok := sch["pattern"]; ok {
+		p, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s/pattern: must be a string", ptr)
+		}
+
+		if err := s.compilePattern(p); err != nil {
+			return fmt.Errorf("%s/pattern: %w", ptr, err)
+		}
+	}
+
+	if v, ok := sch["patternProperties"].(map[string]interface{}); ok {
+		for p := range v {
+			if err := s.compilePattern(p); err != nil {
+				return fmt.Errorf("%s/patternProperties: %w", ptr, err)
+			}
+		}
+	}
+
+	// keywords with schema value
+	for _, k := range []string{
+		"not", "if", "then", "else",
+		"additionalItems", "additionalProperties", "propertyNames", "contains",
+	} {
+		if err := s.prepare(sch[k], ptr+"/"+k); err != nil {
+			return err
+		}
+	}
+
+	// items is a schema or an array of schemas
+	if _, ok := sch["items"].([]interface{}); !ok {
+		if err := s.prepare(sch["items"], ptr+"/items"); err != nil {
+			return err
+		}
+	}
+
+	// keywords with schema array value
+	for _, k := range []string{"allOf", "anyOf", "oneOf", "items"} {
+		arr, _ := sch[k].([]interface{})
+		for i, v := range arr {
+			if err := s.prepare(v, ptr+"/"+k+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// keywords with schema map value
+	for _, k := range []string{"properties", "patternProperties", "definitions", "$defs"} {
+		m, _ := sch[k].(map[string]interface{})
+		for name, v := range m {
+			if err := s.prepare(v, ptr+"/"+k+"/"+escapeJSONPointer(name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// dependencies are property arrays or schemas
+	deps, _ := sch["dependencies"].(map[string]interface{})
+	for name, v := range deps {
+		if _, ok := v.([]interface{}); ok {
+			continue
+		}
+
+		if err := s.prepare(v, ptr+"/dependencies/"+escapeJSONPointer(name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *jsonSchema) compilePattern(p string) error {
+	if _, ok := s.patterns[p]; ok {
+		return nil
+	}
+
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", p, err)
+	}
+
+	s.patterns[p] = re
+	return nil
+}
+
+// resolveRef resolves local reference in the root schema
+func (s *jsonSchema) resolveRef(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported non-local reference %q", ref)
+	}
+
+	ptr, err := url.PathUnescape(strings.TrimPrefix(ref, "#"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference %q: %w", ref, err)
+	}
+
+	node := s.root
+	if ptr == "" {
+		return node, nil
+	}
+
+	if !strings.HasPrefix(ptr, "/") {
+		return nil, fmt.Errorf("unsupported reference %q, only json pointer is supported", ref)
+	}
+
+	for _, token := range strings.Split(ptr[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+
+		switch n := node.(type) {
+		case map[string]interface{}:
+			v, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("reference %q not found", ref)
+			}
+			node = v
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, fmt.Errorf("reference %q not found", ref)
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("reference %q not found", ref)
+		}
+	}
+
+	return node, nil
+}
+
+// validateDocument validates instance (json compatible values) against the schema
+func (s *jsonSchema) validateDocument(instance interface{}) []jsonSchemaViolation {
+	var ret []jsonSchemaViolation
+	s.validate(s.root, instance, "", 0, &ret)
+	return ret
+}
+
+// isValid checks instance against the schema without collecting violations
+func (s *jsonSchema) isValid(schema, instance interface{}, ptr string, depth int) bool {
+	var ret []jsonSchemaViolation
+	s.validate(schema, instance, ptr, depth, &ret)
+	return len(ret) == 0
+}
+
+// nolint:gocyclo
+func (s *jsonSchema) validate(schema, instance interface{}, ptr string, depth int, out *[]jsonSchemaViolation) {
+	report := func(format string, args ...interface{}) {
+		*out = append(*out, jsonSchemaViolation{Pointer: ptr, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if depth > jsonSchemaMaxDepth {
+		report("schema too deep, maybe recursive reference")
+		return
+	}
+
+	var sch map[string]interface{}
+	switch t := schema.(type) {
+	case bool:
+		if !t {
+			report("not allowed")
+		}
+		return
+	case map[string]interface{}:
+		sch = t
+	default:
+		// invalid schema, treat as empty schema
+		return
+	}
+
+	if ref, ok := sch["$ref"].(string); ok {
+		// $ref overrides all other keywords
+		target, err := s.resolveRef(ref)
+		if err != nil {
+			report("%v", err)
+			return
+		}
+
+		s.validate(target, instance, ptr, depth+1, out)
+		return
+	}
+
+	// generic keywords
+
+	if t, ok := sch["type"]; ok && !jsonTypeMatches(t, instance) {
+		report("expected %s, got %s", formatJSONTypes(t), jsonTypeOf(instance))
+	}
+
+	if enum, ok := sch["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, instance) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			report("value not in enum %s", toJSONString(enum))
+		}
+	}
+
+	if c, ok := sch["const"]; ok && !reflect.DeepEqual(c, instance) {
+		report("value must be %s", toJSONString(c))
+	}
+
+	// combination keywords
+
+	if allOf, ok := sch["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			s.validate(sub, instance, ptr, depth+1, out)
+		}
+	}
+
+	if anyOf, ok := sch["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if s.isValid(sub, instance, ptr, depth+1) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			report("value does not match any schema in anyOf")
+		}
+	}
+
+	if oneOf, ok := sch["oneOf"].([]interface{}); ok {
+		matched := 0
+		for _, sub := range oneOf {
+			if s.isValid(sub, instance, ptr, depth+1) {
+				matched++
+			}
+		}
+
+		if matched != 1 {
+			report("value must match exactly one schema in oneOf, matched %d", matched)
+		}
+	}
+
+	if not, ok := sch["not"]; ok && s.isValid(not, instance, ptr, depth+1) {
+		report("value must not match the schema in not")
+	}
+
+	if cond, ok := sch["if"]; ok {
+		if s.isValid(cond, instance, ptr, depth+1) {
+			if then, ok := sch["then"]; ok {
+				s.validate(then, instance, ptr, depth+1, out)
+			}
+		} else if els, ok := sch["else"]; ok {
+			s.validate(els, instance, ptr, depth+1, out)
+		}
+	}
+
+	switch inst := instance.(type) {
+	case float64:
+		s.validateNumber(sch, inst, report)
+	case string:
+		s.validateString(sch, inst, report)
+	case []interface{}:
+		s.validateArray(sch, inst, ptr, depth, out, report)
+	case map[string]interface{}:
+		s.validateObject(sch, inst, ptr, depth, out, report)
+	}
+}
+
+func (s *jsonSchema) validateNumber(sch map[string]interface{}, n float64, report func(string, ...interface{})) {
+	if m, ok := sch["multipleOf"].(float64); ok && m > 0 {
+		q := n / m
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			report("%v is not a multiple of %v", n, m)
+		}
+	}
+
+	// draft-04 uses boolean exclusiveMaximum/exclusiveMinimum
+	if max, ok := sch["maximum"].(float64); ok {
+		if ex, _ := sch["exclusiveMaximum"].(bool); ex {
+			if n >= max {
+				report("%v must be less than %v", n, max)
+			}
+		} else if n > max {
+			report("%v must be less than or equal to %v", n, max)
+		}
+	}
+
+	if max, ok := sch["exclusiveMaximum"].(float64); ok && n >= max {
+		report("%v must be less than %v", n, max)
+	}
+
+	if min, ok := sch["minimum"].(float64); ok {
+		if ex, _ := sch["exclusiveMinimum"].(bool); ex {
+			if n <= min {
+				report("%v must be greater than %v", n, min)
+			}
+		} else if n < min {
+			report("%v must be greater than or equal to %v", n, min)
+		}
+	}
+
+	if min, ok := sch["exclusiveMinimum"].(float64); ok && n <= min {
+		report("%v must be greater than %v", n, min)
+	}
+}
+
+func (s *jsonSchema) validateString(sch map[string]interface{}, str string, report func(string, ...interface{})) {
+	length := utf8.RuneCountInString(str)
+	if max, ok := sch["maxLength"].(float64); ok && float64(length) > max {
+		report("length %d must be less than or equal to %v", length, max)
+	}
+
+	if min, ok := sch["minLength"].(float64); ok && float64(length) < min {
+		report("length %d must be greater than or equal to %v", length, min)
+	}
+
+	if p, ok := sch["pattern"].(string); ok {
+		if re := s.patterns[p]; re != nil && !re.MatchString(str) {
+			report("value does not match pattern %q", p)
+		}
+	}
+
+	if f, ok := sch["format"].(string); ok {
+		if err := checkJSONSchemaFormat(f, str); err != nil {
+			report("value is not a valid %s: %v", f, err)
+		}
+	}
+}
+
+func (s *jsonSchema) validateArray(
+	sch map[string]interface{},
+	arr []interface{},
+	ptr string,
+	depth int,
+	out *[]jsonSchemaViolation,
+	report func(string, ...interface{}),
+) {
+	if max, ok := sch["maxItems"].(float64); ok && float64(len(arr)) > max {
+		report("%d items, must have at most %v items", len(arr), max)
+	}
+
+	if min, ok := sch["minItems"].(float64); ok && float64(len(arr)) < min {
+		report("%d items, must have at least %v items", len(arr), min)
+	}
+
+	if unique, _ := sch["uniqueItems"].(bool); unique {
+	loop:
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if reflect.DeepEqual(arr[i], arr[j]) {
+					report("items at %d and %d are not unique", i, j)
+					break loop
+				}
+			}
+		}
+	}
+
+	switch items := sch["items"].(type) {
+	case []interface{}:
+		// tuple validation
+		for i, v := range arr {
+			itemPtr := ptr + "/" + strconv.Itoa(i)
+			if i < len(items) {
+				s.validate(items[i], v, itemPtr, depth+1, out)
+			} else if additional, ok := sch["additionalItems"]; ok {
+				s.validate(additional, v, itemPtr, depth+1, out)
+			}
+		}
+	case nil:
+	default:
+		for i, v := range arr {
+			s.validate(items, v, ptr+"/"+strconv.Itoa(i), depth+1, out)
+		}
+	}
+
+	if contains, ok := sch["contains"]; ok {
+		found := false
+		for i, v := range arr {
+			if s.isValid(contains, v, ptr+"/"+strconv.Itoa(i), depth+1) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			report("no item matches the schema in contains")
+		}
+	}
+}
+
+// nolint:gocyclo
+func (s *jsonSchema) validateObject(
+	sch map[string]interface{},
+	obj map[string]interface{},
+	ptr string,
+	depth int,
+	out *[]jsonSchemaViolation,
+	report func(string, ...interface{}),
+) {
+	if max, ok := sch["maxProperties"].(float64); ok && float64(len(obj)) > max {
+		report("%d properties, must have at most %v properties", len(obj), max)
+	}
+
+	if min, ok := sch["minProperties"].(float64); ok && float64(len(obj)) < min {
+		report("%d properties, must have at least %v properties", len(obj), min)
+	}
+
+	if required, ok := sch["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, ok := obj[name]; !ok {
+				report("missing required property %q", name)
+			}
+		}
+	}
+
+	// iterate in order for stable output
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	properties, _ := sch["properties"].(map[string]interface{})
+	patternProperties, _ := sch["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := sch["additionalProperties"]
+	propertyNames, hasPropertyNames := sch["propertyNames"]
+	dependencies, _ := sch["dependencies"].(map[string]interface{})
+
+	for _, k := range keys {
+		v := obj[k]
+		propPtr := ptr + "/" + escapeJSONPointer(k)
+
+		matched := false
+		if propSchema, ok := properties[k]; ok {
+			matched = true
+			s.validate(propSchema, v, propPtr, depth+1, out)
+		}
+
+		for p, propSchema := range patternProperties {
+			if re := s.patterns[p]; re != nil && re.MatchString(k) {
+				matched = true
+				s.validate(propSchema, v, propPtr, depth+1, out)
+			}
+		}
+
+		if !matched && hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				*out = append(*out, jsonSchemaViolation{Pointer: propPtr, Message: "additional property not allowed"})
+			} else {
+				s.validate(additional, v, propPtr, depth+1, out)
+			}
+		}
+
+		if hasPropertyNames && !s.isValid(propertyNames, k, propPtr, depth+1) {
+			*out = append(*out, jsonSchemaViolation{Pointer: propPtr, Message: "property name not allowed"})
+		}
+
+		switch dep := dependencies[k].(type) {
+		case []interface{}:
+			for _, d := range dep {
+				name, _ := d.(string)
+				if _, ok := obj[name]; !ok {
+					report("property %q is required by property %q", name, k)
+				}
+			}
+		case nil:
+		default:
+			s.validate(dep, obj, ptr, depth+1, out)
+		}
+	}
+}
+
+func checkJSONSchemaFormat(format, value string) error {
+	var err error
+	switch format {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, value)
+	case "date":
+		_, err = time.Parse("2006-01-02", value)
+	case "time":
+		_, err = time.Parse("15:04:05Z07:00", value)
+	case "email":
+		_, err = mail.ParseAddress(value)
+	case "hostname":
+		if !hostnameRegexp.MatchString(value) || len(value) > 253 {
+			err = fmt.Errorf("invalid hostname")
+		}
+	case "ipv4":
+		if ip := net.ParseIP(value); ip == nil || ip.To4() == nil || strings.Contains(value, ":") {
+			err = fmt.Errorf("invalid ipv4 address")
+		}
+	case "ipv6":
+		if ip := net.ParseIP(value); ip == nil || !strings.Contains(value, ":") {
+			err = fmt.Errorf("invalid ipv6 address")
+		}
+	case "uri":
+		var u *url.URL
+		u, err = url.Parse(value)
+		if err == nil && !u.IsAbs() {
+			err = fmt.Errorf("not an absolute uri")
+		}
+	}
+
+	return err
+}
+
+var hostnameRegexp = regexp.MustCompile(
+	`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`,
+)
+
+func jsonTypeOf(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if t == math.Trunc(t) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return reflect.TypeOf(v).String()
+	}
+}
+
+func jsonTypeMatches(expected, v interface{}) bool {
+	match := func(t string) bool {
+		actual := jsonTypeOf(v)
+		return t == actual || (t == "number" && actual == "integer")
+	}
+
+	switch t := expected.(type) {
+	case string:
+		return match(t)
+	case []interface{}:
+		for _, e := range t {
+			if s, ok := e.(string); ok && match(s) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+func formatJSONTypes(t interface{}) string {
+	switch types := t.(type) {
+	case string:
+		return types
+	case []interface{}:
+		var ret []string
+		for _, e := range types {
+			ret = append(ret, fmt.Sprint(e))
+		}
+		return "one of " + strings.Join(ret, ", ")
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func escapeJSONPointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+func toJSONString(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(data)
+}
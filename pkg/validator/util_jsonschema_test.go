@@ -0,0 +1,123 @@
+package validator
+
+import (
+	"testing"
+)
+
+func TestJSONSchemaValidateDocument(t *testing.T) {
+	const schema = `
+type: object
+required: [name, replicas]
+properties:
+  name:
+    type: string
+    pattern: "^[a-z]+$"
+  replicas:
+    $ref: "#/definitions/positive"
+  tags:
+    type: array
+    items: {type: string}
+    uniqueItems: true
+additionalProperties: false
+definitions:
+  positive:
+    type: integer
+    minimum: 1
+`
+
+	s, err := compileJSONSchema([]byte(schema))
+	if err != nil {
+		t.Fatalf("failed to compile schema: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		pointers []string
+	}{
+		{
+			name: "Valid JSON",
+			data: `{"name": "foo", "replicas": 2, "tags": ["a", "b"]}`,
+		},
+		{
+			name: "Valid YAML",
+			data: "name: foo\nreplicas: 1\n",
+		},
+		{
+			name:     "Missing Required",
+			data:     `{"name": "foo"}`,
+			pointers: []string{""},
+		},
+		{
+			name:     "All Violations",
+			data:     `{"name": "Foo", "replicas": 0, "tags": ["a", "a"], "extra": true}`,
+			pointers: []string{"/extra", "/name", "/replicas", "/tags"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := unmarshalJSONOrYAML([]byte(test.data))
+			if err != nil {
+				t.Fatalf("failed to parse data: %v", err)
+			}
+
+			violations := s.validateDocument(doc)
+			if len(violations) != len(test.pointers) {
+				t.Fatalf("expected %d violations, got %v", len(test.pointers), jsonSchemaError(violations))
+			}
+
+			for _, ptr := range test.pointers {
+				found := false
+				for _, v := range violations {
+					if v.Pointer == ptr {
+						found = true
+						break
+					}
+				}
+
+				if !found {
+					t.Errorf("no violation at %q: %v", ptr, jsonSchemaError(violations))
+				}
+			}
+		})
+	}
+}
+
+func TestCompileJSONSchemaUnsupported(t *testing.T) {
+	tests := []struct {
+		name      string
+		schema    string
+		expectErr bool
+	}{
+		{
+			name: "Supported",
+			schema: `
+$schema: "http://json-schema.org/draft-07/schema#"
+title: foo
+if: {properties: {kind: {const: a}}}
+then: {required: [a]}
+dependencies:
+  b: [c]
+  c: {required: [d]}
+items: [{type: string, format: ipv4}]
+`,
+		},
+		{name: "Unsupported Keyword", schema: `{unevaluatedProperties: false}`, expectErr: true},
+		{name: "Unsupported Nested Keyword", schema: `{properties: {a: {dependentRequired: {a: [b]}}}}`, expectErr: true},
+		{name: "Unsupported Dependency Keyword", schema: `{dependencies: {a: {minContains: 1}}}`, expectErr: true},
+		{name: "Unsupported Format", schema: `{format: uuid}`, expectErr: true},
+		{name: "Unsupported Draft", schema: `{$schema: "https://json-schema.org/draft/2020-12/schema"}`, expectErr: true},
+		{name: "Remote Reference", schema: `{$ref: "https://example.com/schema.json"}`, expectErr: true},
+		{name: "Invalid Schema", schema: `{not: 1}`, expectErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := compileJSONSchema([]byte(test.schema))
+			if test.expectErr != (err != nil) {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
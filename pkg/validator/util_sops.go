@@ -0,0 +1,401 @@
+package validator
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// sops encrypted value: ENC[AES256_GCM,data:<base64>,iv:<base64>,tag:<base64>,type:<type>]
+var sopsEncryptedValueRegex = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.+),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+type sopsMetadata struct {
+	LastModified      string `yaml:"lastmodified"`
+	MAC               string `yaml:"mac"`
+	UnencryptedSuffix string `yaml:"unencrypted_suffix"`
+	EncryptedSuffix   string `yaml:"encrypted_suffix"`
+	UnencryptedRegex  string `yaml:"unencrypted_regex"`
+	EncryptedRegex    string `yaml:"encrypted_regex"`
+	MACOnlyEncrypted  bool   `yaml:"mac_only_encrypted"`
+
+	Age []sopsAgeRecipient `yaml:"age"`
+	PGP []interface{}      `yaml:"pgp"`
+	KMS []interface{}      `yaml:"kms"`
+}
+
+// sopsAgeRecipient contains the data key encrypted for the age recipient
+type sopsAgeRecipient struct {
+	Recipient string `yaml:"recipient"`
+	Enc       string `yaml:"enc"`
+}
+
+// sopsDocument is a sops encrypted json or yaml document
+type sopsDocument struct {
+	tree     yaml.MapSlice
+	metadata *sopsMetadata
+
+	unencryptedRegex *regexp.Regexp
+	encryptedRegex   *regexp.Regexp
+}
+
+func parseSOPSDocument(data []byte) (*sopsDocument, error) {
+	doc := &sopsDocument{}
+	err := yaml.Unmarshal(data, &doc.tree)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+
+	md := struct {
+		SOPS *sopsMetadata `yaml:"sops"`
+	}{}
+	err = yaml.Unmarshal(data, &md)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sops metadata: %w", err)
+	}
+
+	if md.SOPS == nil {
+		return nil, fmt.Errorf("not a sops document: no sops metadata")
+	}
+	doc.metadata = md.SOPS
+
+	if r := md.SOPS.UnencryptedRegex; r != "" {
+		doc.unencryptedRegex, err = regexp.Compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unencrypted_regex: %w", err)
+		}
+	}
+
+	if r := md.SOPS.EncryptedRegex; r != "" {
+		doc.encryptedRegex, err = regexp.Compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encrypted_regex: %w", err)
+		}
+	}
+
+	// remove metadata from the document
+	tree := doc.tree[:0]
+	for _, item := range doc.tree {
+		if fmt.Sprint(item.Key) != "sops" {
+			tree = append(tree, item)
+		}
+	}
+	doc.tree = tree
+
+	return doc, nil
+}
+
+// unwrapAgeDataKey decrypts the data key from age recipients with any of the identities
+func (d *sopsDocument) unwrapAgeDataKey(identities []*ageIdentity) ([]byte, error) {
+	if len(d.metadata.Age) == 0 {
+		return nil, fmt.Errorf("no age recipient in sops metadata")
+	}
+
+	var errs []string
+	for _, r := range d.metadata.Age {
+		dataKey, err := decryptAge(identities, []byte(r.Enc))
+		if err == nil {
+			return dataKey, nil
+		}
+
+		errs = append(errs, fmt.Sprintf("%s: %v", r.Recipient, err))
+	}
+
+	return nil, fmt.Errorf("failed to unwrap data key: %s", strings.Join(errs, "; "))
+}
+
+// decrypt all values with data key and verify the message authentication code
+func (d *sopsDocument) decrypt(dataKey []byte) error {
+	gcm, err := newSOPSCipher(dataKey)
+	if err != nil {
+		return err
+	}
+
+	h := sha512.New()
+	d.tree, err = d.decryptMap(gcm, d.tree, nil, h)
+	if err != nil {
+		return err
+	}
+
+	if d.metadata.MAC == "" {
+		return fmt.Errorf("no mac in sops metadata")
+	}
+
+	mac, err := decryptSOPSValue(gcm, d.metadata.MAC, d.metadata.LastModified)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt mac: %w", err)
+	}
+
+	expected, ok := mac.(string)
+	if !ok || !strings.EqualFold(expected, fmt.Sprintf("%X", h.Sum(nil))) {
+		return fmt.Errorf("mac mismatch, document may have been tampered with")
+	}
+
+	return nil
+}
+
+func (d *sopsDocument) decryptMap(
+	gcm cipher.AEAD, m yaml.MapSlice, path []string, h interface{ Write([]byte) (int, error) },
+) (yaml.MapSlice, error) {
+	ret := make(yaml.MapSlice, len(m))
+	for i, item := range m {
+		v, err := d.decryptValue(gcm, item.Value, append(path[:len(path):len(path)], fmt.Sprint(item.Key)), h)
+		if err != nil {
+			return nil, err
+		}
+
+		ret[i] = yaml.MapItem{Key: item.Key, Value: v}
+	}
+
+	return ret, nil
+}
+
+func (d *sopsDocument) decryptValue(
+	gcm cipher.AEAD, in interface{}, path []string, h interface{ Write([]byte) (int, error) },
+) (interface{}, error) {
+	switch t := in.(type) {
+	case yaml.MapSlice:
+		return d.decryptMap(gcm, t, path, h)
+	case []interface{}:
+		// list items share the path of the list
+		ret := make([]interface{}, len(t))
+		for i, v := range t {
+			var err error
+			ret[i], err = d.decryptValue(gcm, v, path, h)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return ret, nil
+	}
+
+	encrypted := d.isEncrypted(path)
+
+	v := in
+	if s, ok := in.(string); ok && encrypted {
+		var err error
+		v, err = decryptSOPSValue(gcm, s, strings.Join(path, ":")+":")
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %q: %w", strings.Join(path, "."), err)
+		}
+	}
+
+	if encrypted || !d.metadata.MACOnlyEncrypted {
+		b, err := sopsValueBytes(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %q: %w", strings.Join(path, "."), err)
+		}
+		_, _ = h.Write(b)
+	}
+
+	return v, nil
+}
+
+func (d *sopsDocument) isEncrypted(path []string) bool {
+	md := d.metadata
+
+	encrypted := true
+	if md.UnencryptedSuffix != "" {
+		for _, p := range path {
+			if strings.HasSuffix(p, md.UnencryptedSuffix) {
+				encrypted = false
+				break
+			}
+		}
+	}
+
+	if md.EncryptedSuffix != "" {
+		encrypted = false
+		for _, p := range path {
+			if strings.HasSuffix(p, md.EncryptedSuffix) {
+				encrypted = true
+				break
+			}
+		}
+	}
+
+	if d.unencryptedRegex != nil {
+		for _, p := range path {
+			if d.unencryptedRegex.MatchString(p) {
+				encrypted = false
+				break
+			}
+		}
+	}
+
+	if d.encryptedRegex != nil {
+		encrypted = false
+		for _, p := range path {
+			if d.encryptedRegex.MatchString(p) {
+				encrypted = true
+				break
+			}
+		}
+	}
+
+	return encrypted
+}
+
+// marshal decrypted document in the same format as the input
+func (d *sopsDocument) marshal(schema string) ([]byte, error) {
+	if schema == TextSchemaYAML {
+		return yaml.Marshal(d.tree)
+	}
+
+	buf := new(bytes.Buffer)
+	err := writeOrderedJSON(buf, d.tree)
+	if err != nil {
+		return nil, err
+	}
+
+	out := new(bytes.Buffer)
+	err = json.Indent(out, buf.Bytes(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func newSOPSCipher(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sops data key: %w", err)
+	}
+
+	// sops uses 32 bytes iv
+	gcm, err := cipher.NewGCMWithNonceSize(block, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aes-gcm cipher: %w", err)
+	}
+
+	return gcm, nil
+}
+
+func decryptSOPSValue(gcm cipher.AEAD, value, additionalData string) (interface{}, error) {
+	// sops does not encrypt empty values
+	if value == "" {
+		return "", nil
+	}
+
+	match := sopsEncryptedValueRegex.FindStringSubmatch(value)
+	if match == nil {
+		return nil, fmt.Errorf("value is not in sops encrypted format")
+	}
+
+	var parts [3][]byte
+	for i := range parts {
+		var err error
+		parts[i], err = base64.StdEncoding.DecodeString(match[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid encoding: %w", err)
+		}
+	}
+
+	data, iv, tag := parts[0], parts[1], parts[2]
+	if len(iv) != gcm.NonceSize() || len(tag) != gcm.Overhead() {
+		return nil, fmt.Errorf("invalid iv or tag size")
+	}
+
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	switch valueType := match[4]; valueType {
+	case "str":
+		return string(plaintext), nil
+	case "int":
+		return strconv.Atoi(string(plaintext))
+	case "float":
+		return strconv.ParseFloat(string(plaintext), 64)
+	case "bool":
+		return strconv.ParseBool(string(plaintext))
+	case "bytes":
+		return plaintext, nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %q", valueType)
+	}
+}
+
+// sopsValueBytes converts value to bytes for mac calculation the same way as sops
+func sopsValueBytes(v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case string:
+		return []byte(t), nil
+	case []byte:
+		return t, nil
+	case int:
+		return []byte(strconv.Itoa(t)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(t, 'f', -1, 64)), nil
+	case bool:
+		if t {
+			return []byte("True"), nil
+		}
+		return []byte("False"), nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+// writeOrderedJSON writes value as json with the order of object keys preserved
+func writeOrderedJSON(buf *bytes.Buffer, v interface{}) error {
+	switch t := v.(type) {
+	case yaml.MapSlice:
+		buf.WriteByte('{')
+		for i, item := range t {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+
+			key, err := json.Marshal(fmt.Sprint(item.Key))
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+
+			err = writeOrderedJSON(buf, item.Value)
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range t {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+
+			err := writeOrderedJSON(buf, item)
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case []byte:
+		data, err := json.Marshal(string(t))
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	default:
+		data, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+
+	return nil
+}
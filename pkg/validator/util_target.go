@@ -0,0 +1,32 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"arhat.dev/ksync/pkg/dataref"
+)
+
+// getTargetObject gets current sync target with object getter in ctx, returns nil if not found
+func getTargetObject(ctx context.Context, target SyncTarget) (*dataref.Object, error) {
+	if target.Name == "" {
+		return nil, fmt.Errorf("no sync target")
+	}
+
+	getter := dataref.ObjectGetterFromContext(ctx)
+	if getter == nil {
+		return nil, fmt.Errorf("no object getter available")
+	}
+
+	obj, err := getter.GetObject(ctx, dataref.Kind(target.Kind), target.Namespace, target.Name)
+	if err != nil {
+		if errors.Is(err, dataref.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return obj, nil
+}
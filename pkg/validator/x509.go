@@ -0,0 +1,391 @@
+package validator
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"arhat.dev/pkg/log"
+	"go.uber.org/multierr"
+
+	"arhat.dev/ksync/pkg/dataref"
+)
+
+func init() {
+	RegisterValidator(MethodX509, NewX509Validator)
+}
+
+const (
+	MethodX509 = "x509"
+)
+
+const (
+	defaultX509CertDataKey = "tls.crt"
+	defaultX509KeyDataKey  = "tls.key"
+)
+
+type X509Config struct {
+	// CertDataKey of PEM encoded certificate (chain), defaults to `tls.crt`
+	CertDataKey string `json:"certDataKey" yaml:"certDataKey"`
+	// KeyDataKey of PEM encoded private key, defaults to `tls.key`
+	KeyDataKey string `json:"keyDataKey" yaml:"keyDataKey"`
+
+	// CABundle in PEM format to verify certificate chain
+	CABundle string `json:"caBundle" yaml:"caBundle"`
+	// CABundleRef to the data key containing CA bundle in the namespace of the sync config
+	// 	{configmap|secret}://<name>/<key>
+	CABundleRef string `json:"caBundleRef" yaml:"caBundleRef"`
+
+	// MinRemainingValidity of the certificate
+	MinRemainingValidity time.Duration `json:"minRemainingValidity" yaml:"minRemainingValidity"`
+
+	// AllowedSANs are glob patterns (with template support) of allowed subject alternative names,
+	// `.Kind`, `.Namespace` and `.Name` of the sync target are available, patterns are matched
+	// against dns names label by label (`*` never matches dots), certificate without any san is
+	// rejected when set
+	AllowedSANs []string `json:"allowedSANs" yaml:"allowedSANs"`
+
+	// NoDowngrade rejects certificate expiring earlier than the one in the sync target
+	NoDowngrade bool `json:"noDowngrade" yaml:"noDowngrade"`
+}
+
+func NewX509Validator(ctx context.Context, logger log.Interface, config *Config) (Interface, error) {
+	if config.X509 == nil {
+		return nil, fmt.Errorf("no x509 validator configuration provided")
+	}
+
+	v := &X509Validator{
+		ctx: ctx,
+
+		certDataKey:          config.X509.CertDataKey,
+		keyDataKey:           config.X509.KeyDataKey,
+		minRemainingValidity: config.X509.MinRemainingValidity,
+		noDowngrade:          config.X509.NoDowngrade,
+	}
+
+	if v.certDataKey == "" {
+		v.certDataKey = defaultX509CertDataKey
+	}
+
+	if v.keyDataKey == "" {
+		v.keyDataKey = defaultX509KeyDataKey
+	}
+
+	var caBundle []byte
+	switch {
+	case config.X509.CABundle != "" && config.X509.CABundleRef != "":
+		return nil, fmt.Errorf("only one of caBundle and caBundleRef can be set")
+	case config.X509.CABundle != "":
+		caBundle = []byte(config.X509.CABundle)
+	case config.X509.CABundleRef != "":
+		var err error
+		caBundle, err = dataref.Resolve(ctx, config.X509.CABundleRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ca bundle: %w", err)
+		}
+	}
+
+	if len(caBundle) != 0 {
+		v.roots = x509.NewCertPool()
+		if !v.roots.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificate found in ca bundle")
+		}
+	}
+
+	for _, p := range config.X509.AllowedSANs {
+		tpl, err := template.New(p).Funcs(funcMapWithJQ()).Parse(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse allowed san %q as template: %w", p, err)
+		}
+
+		v.allowedSANs = append(v.allowedSANs, tpl)
+	}
+
+	// check patterns early with the sync target the syncer created for
+	if _, err := v.renderAllowedSANs(SyncTargetFromContext(ctx)); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// X509Validator validates PEM encoded certificate and private key
+type X509Validator struct {
+	ctx context.Context
+
+	certDataKey          string
+	keyDataKey           string
+	roots                *x509.CertPool
+	minRemainingValidity time.Duration
+	allowedSANs          []*template.Template
+	noDowngrade          bool
+}
+
+// TargetDependent returns true, allowed sans, counterpart lookup and no downgrade check
+// depend on the sync target
+func (v *X509Validator) TargetDependent() bool {
+	return true
+}
+
+// renderAllowedSANs executes allowed san templates for the sync target
+func (v *X509Validator) renderAllowedSANs(target SyncTarget) ([]string, error) {
+	patterns := make([]string, 0, len(v.allowedSANs))
+	for _, tpl := range v.allowedSANs {
+		buf := new(bytes.Buffer)
+		err := tpl.Execute(buf, target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute allowed san template %q: %w", tpl.Name(), err)
+		}
+
+		pattern := buf.String()
+		if _, err = path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid allowed san pattern %q: %w", pattern, err)
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+// Validate certificate and private key, the counterpart of a certificate (or private key) not
+// in data is looked up in buffered data and then the sync target to check they are paired, so
+// deliver them together (e.g. with atomic or dependsOn) when both are rotated
+func (v *X509Validator) Validate(vctx *ValidationContext, data map[string][]byte) *DataMsg {
+	result := &DataMsg{
+		Data:   make(map[string][]byte),
+		Errors: make(map[string]error),
+	}
+
+	certPEM, hasCert := data[v.certDataKey]
+	keyPEM, hasKey := data[v.keyDataKey]
+
+	switch {
+	case !hasCert && !hasKey:
+		return result
+	case !hasCert:
+		current, found := lookupX509Counterpart(vctx, v.certDataKey)
+		if !found {
+			result.Errors[v.keyDataKey] = fmt.Errorf(
+				"certificate %q is required to validate private key", v.certDataKey)
+			return result
+		}
+
+		if _, err := tls.X509KeyPair(current, keyPEM); err != nil {
+			result.Errors[v.keyDataKey] = fmt.Errorf("private key does not match certificate: %w", err)
+			return result
+		}
+
+		result.Data[v.keyDataKey] = keyPEM
+		return result
+	case !hasKey:
+		// nil if not found, only the certificate itself is validated
+		keyPEM, _ = lookupX509Counterpart(vctx, v.keyDataKey)
+	}
+
+	err := v.validate(vctx, certPEM, keyPEM)
+	if err != nil {
+		result.Errors[v.certDataKey] = err
+		if hasKey {
+			result.Errors[v.keyDataKey] = err
+		}
+
+		return result
+	}
+
+	result.Data[v.certDataKey] = certPEM
+	if hasKey {
+		result.Data[v.keyDataKey] = keyPEM
+	}
+
+	return result
+}
+
+// lookupX509Counterpart finds data of key in buffered data and then in the sync target
+func lookupX509Counterpart(vctx *ValidationContext, key string) ([]byte, bool) {
+	if vctx == nil {
+		return nil, false
+	}
+
+	if d, ok := vctx.BufferedData[key]; ok {
+		return []byte(d), true
+	}
+
+	if d, ok := vctx.TargetData[key]; ok {
+		return []byte(d), true
+	}
+
+	return nil, false
+}
+
+// validate certificate chain, and check it's paired with private key if keyPEM is not nil
+func (v *X509Validator) validate(vctx *ValidationContext, certPEM, keyPEM []byte) error {
+	certs, err := parsePEMCertificates(certPEM)
+	if err != nil {
+		return err
+	}
+
+	leaf := certs[0]
+
+	if keyPEM != nil {
+		_, err = tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("private key does not match certificate: %w", err)
+		}
+	}
+
+	now := time.Now()
+	if now.Before(leaf.NotBefore) {
+		return fmt.Errorf("certificate is not valid before %s", leaf.NotBefore.Format(time.RFC3339))
+	}
+
+	if remaining := leaf.NotAfter.Sub(now); remaining < v.minRemainingValidity {
+		return fmt.Errorf("certificate expires at %s, less than %s remaining",
+			leaf.NotAfter.Format(time.RFC3339), v.minRemainingValidity)
+	}
+
+	if v.roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, c := range certs[1:] {
+			intermediates.AddCert(c)
+		}
+
+		_, err = leaf.Verify(x509.VerifyOptions{
+			Roots:         v.roots,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to verify certificate chain: %w", err)
+		}
+	}
+
+	if len(v.allowedSANs) != 0 {
+		var patterns []string
+		patterns, err = v.renderAllowedSANs(vctx.Target)
+		if err != nil {
+			return err
+		}
+
+		if err = checkSANs(patterns, leaf); err != nil {
+			return err
+		}
+	}
+
+	if v.noDowngrade {
+		current, found := vctx.TargetData[v.certDataKey]
+		if found {
+			currentCerts, err := parsePEMCertificates([]byte(current))
+			// ignore invalid current certificate
+			if err == nil && leaf.NotAfter.Before(currentCerts[0].NotAfter) {
+				return fmt.Errorf("certificate expires at %s, earlier than current one (%s)",
+					leaf.NotAfter.Format(time.RFC3339), currentCerts[0].NotAfter.Format(time.RFC3339))
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkSANs checks all subject alternative names of cert match one of allowed patterns
+func checkSANs(patterns []string, cert *x509.Certificate) error {
+	var sans []string
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+
+	if len(cert.DNSNames)+len(sans) == 0 {
+		return fmt.Errorf("certificate has no subject alternative name")
+	}
+
+	var err error
+	for _, san := range cert.DNSNames {
+		if !sanAllowed(patterns, san, matchDNSPattern) {
+			err = multierr.Append(err, fmt.Errorf("san %q is not allowed", san))
+		}
+	}
+
+	for _, san := range sans {
+		if !sanAllowed(patterns, san, func(pattern, san string) bool {
+			ok, _ := path.Match(pattern, san)
+			return ok
+		}) {
+			err = multierr.Append(err, fmt.Errorf("san %q is not allowed", san))
+		}
+	}
+
+	return err
+}
+
+func sanAllowed(patterns []string, san string, match func(pattern, san string) bool) bool {
+	for _, p := range patterns {
+		if match(p, san) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchDNSPattern matches dns name against glob pattern label by label (case insensitive), so
+// wildcards never match across labels, e.g. `*.example.com` matches `a.example.com` but not
+// `a.b.example.com`, and wildcard dns name in certificate only matches the same wildcard
+func matchDNSPattern(pattern, name string) bool {
+	patternLabels := strings.Split(strings.ToLower(strings.TrimSuffix(pattern, ".")), ".")
+	nameLabels := strings.Split(strings.ToLower(strings.TrimSuffix(name, ".")), ".")
+	if len(patternLabels) != len(nameLabels) {
+		return false
+	}
+
+	for i, p := range patternLabels {
+		if nameLabels[i] == "" {
+			return false
+		}
+
+		if ok, _ := path.Match(p, nameLabels[i]); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found")
+	}
+
+	return certs, nil
+}
@@ -0,0 +1,254 @@
+package validator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func newTestCert(
+	t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, isCA bool, notAfter time.Time, dnsNames ...string,
+) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		DNSNames:              dnsNames,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+
+	if parent == nil {
+		parent, parentKey = tpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestX509Validator(t *testing.T) {
+	now := time.Now()
+	ca, caKey, caPEM, _ := newTestCert(t, nil, nil, true, now.Add(365*24*time.Hour))
+	_, _, otherCAPEM, _ := newTestCert(t, nil, nil, true, now.Add(365*24*time.Hour))
+
+	_, _, goodCert, goodKey := newTestCert(t, ca, caKey, false, now.Add(90*24*time.Hour), "foo.default.svc")
+	_, _, _, otherKey := newTestCert(t, ca, caKey, false, now.Add(90*24*time.Hour), "foo.default.svc")
+	_, _, badSANCert, badSANKey := newTestCert(t, ca, caKey, false, now.Add(90*24*time.Hour), "evil.com")
+	_, _, shortCert, shortKey := newTestCert(t, ca, caKey, false, now.Add(time.Hour), "foo.default.svc")
+	_, _, olderCert, olderKey := newTestCert(t, ca, caKey, false, now.Add(60*24*time.Hour), "foo.default.svc")
+	_, _, currentCert, _ := newTestCert(t, ca, caKey, false, now.Add(80*24*time.Hour), "foo.default.svc")
+
+	_, _, noSANCert, noSANKey := newTestCert(t, ca, caKey, false, now.Add(90*24*time.Hour))
+	_, _, nestedCert, nestedKey := newTestCert(t, ca, caKey, false, now.Add(90*24*time.Hour), "a.foo.default.svc")
+	_, _, prodCert, prodKey := newTestCert(t, ca, caKey, false, now.Add(90*24*time.Hour), "foo.prod.svc")
+
+	defaultTarget := SyncTarget{Kind: "secret", Namespace: "default", Name: "foo"}
+	ctx := WithSyncTarget(context.TODO(), defaultTarget)
+
+	newValidator := func(caBundle []byte) Interface {
+		v, err := NewX509Validator(ctx, nil, &Config{
+			Method: MethodX509,
+			X509: &X509Config{
+				CABundle:             string(caBundle),
+				MinRemainingValidity: 24 * time.Hour,
+				AllowedSANs:          []string{"{{ .Name }}.{{ .Namespace }}.svc", "*.default.svc"},
+				NoDowngrade:          true,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name     string
+		caBundle []byte
+		cert     []byte
+		key      []byte
+		target   *SyncTarget
+		valid    bool
+	}{
+		{name: "Valid", caBundle: caPEM, cert: goodCert, key: goodKey, valid: true},
+		{name: "Key Mismatch", caBundle: caPEM, cert: goodCert, key: otherKey},
+		{name: "Untrusted Chain", caBundle: otherCAPEM, cert: goodCert, key: goodKey},
+		{name: "SAN Not Allowed", caBundle: caPEM, cert: badSANCert, key: badSANKey},
+		{name: "Expiring Soon", caBundle: caPEM, cert: shortCert, key: shortKey},
+		{name: "Downgrade", caBundle: caPEM, cert: olderCert, key: olderKey},
+		{name: "No SAN", caBundle: caPEM, cert: noSANCert, key: noSANKey},
+		{name: "Wildcard Across Labels", caBundle: caPEM, cert: nestedCert, key: nestedKey},
+		{
+			name: "SAN Of Other Target", caBundle: caPEM, cert: prodCert, key: prodKey,
+			target: &SyncTarget{Kind: "secret", Namespace: "prod", Name: "foo"}, valid: true,
+		},
+		{
+			name: "SAN Not Allowed For Target", caBundle: caPEM, cert: prodCert, key: prodKey,
+			target: &SyncTarget{Kind: "secret", Namespace: "prod", Name: "bar"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := defaultTarget
+			if test.target != nil {
+				target = *test.target
+			}
+
+			vctx := &ValidationContext{Target: target, TargetData: map[string]string{"tls.crt": string(currentCert)}}
+			result := newValidator(test.caBundle).Validate(vctx, map[string][]byte{
+				"tls.crt": test.cert,
+				"tls.key": test.key,
+			})
+
+			if test.valid {
+				if len(result.Data) != 2 {
+					t.Errorf("expected valid, got %v", result.Errors)
+				}
+				return
+			}
+
+			if len(result.Data) != 0 || len(result.Errors) != 2 {
+				t.Errorf("expected rejected, got data keys %d, errors %v", len(result.Data), result.Errors)
+			}
+		})
+	}
+}
+
+func TestX509ValidatorSplitDelivery(t *testing.T) {
+	now := time.Now()
+	_, _, cert, key := newTestCert(t, nil, nil, false, now.Add(90*24*time.Hour), "foo.default.svc")
+	_, _, otherCert, otherKey := newTestCert(t, nil, nil, false, now.Add(90*24*time.Hour), "foo.default.svc")
+
+	v, err := NewX509Validator(context.TODO(), nil, &Config{Method: MethodX509, X509: &X509Config{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		data     map[string][]byte
+		buffered map[string]string
+		target   map[string]string
+		valid    bool
+	}{
+		{
+			name:   "Cert Paired With Target Key",
+			data:   map[string][]byte{"tls.crt": cert},
+			target: map[string]string{"tls.crt": string(otherCert), "tls.key": string(key)},
+			valid:  true,
+		},
+		{
+			name:   "Cert Not Paired With Target Key",
+			data:   map[string][]byte{"tls.crt": cert},
+			target: map[string]string{"tls.crt": string(otherCert), "tls.key": string(otherKey)},
+		},
+		{
+			name:     "Cert Paired With Buffered Key",
+			data:     map[string][]byte{"tls.crt": cert},
+			buffered: map[string]string{"tls.key": string(key)},
+			target:   map[string]string{"tls.key": string(otherKey)},
+			valid:    true,
+		},
+		{
+			name:  "Cert Without Key",
+			data:  map[string][]byte{"tls.crt": cert},
+			valid: true,
+		},
+		{
+			name:   "Key Paired With Target Cert",
+			data:   map[string][]byte{"tls.key": key},
+			target: map[string]string{"tls.crt": string(cert), "tls.key": string(otherKey)},
+			valid:  true,
+		},
+		{
+			name:   "Key Not Paired With Target Cert",
+			data:   map[string][]byte{"tls.key": key},
+			target: map[string]string{"tls.crt": string(otherCert)},
+		},
+		{
+			name:     "Key Paired With Buffered Cert",
+			data:     map[string][]byte{"tls.key": key},
+			buffered: map[string]string{"tls.crt": string(cert)},
+			target:   map[string]string{"tls.crt": string(otherCert)},
+			valid:    true,
+		},
+		{
+			name: "Key Without Cert",
+			data: map[string][]byte{"tls.key": key},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := v.Validate(&ValidationContext{
+				BufferedData: test.buffered,
+				TargetData:   test.target,
+			}, test.data)
+
+			if test.valid {
+				if len(result.Data) != len(test.data) || len(result.Errors) != 0 {
+					t.Errorf("expected valid, got %v", result.Errors)
+				}
+				return
+			}
+
+			if len(result.Data) != 0 || len(result.Errors) != len(test.data) {
+				t.Errorf("expected rejected, got data keys %d, errors %v", len(result.Data), result.Errors)
+			}
+		})
+	}
+}
+
+func TestMatchDNSPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{pattern: "*.example.com", name: "a.example.com", match: true},
+		{pattern: "*.example.com", name: "A.Example.com.", match: true},
+		{pattern: "*.example.com", name: "a.b.example.com"},
+		{pattern: "*.example.com", name: "example.com"},
+		{pattern: "*.example.com", name: ".example.com"},
+		{pattern: "*", name: "a.example.com"},
+		{pattern: "api-*.example.com", name: "api-1.example.com", match: true},
+		{pattern: "*.example.com", name: "*.example.com", match: true},
+		{pattern: "a.example.com", name: "*.example.com"},
+	}
+
+	for _, test := range tests {
+		if match := matchDNSPattern(test.pattern, test.name); match != test.match {
+			t.Errorf("%q matching %q: expected %v, got %v", test.pattern, test.name, test.match, match)
+		}
+	}
+}
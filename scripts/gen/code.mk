@@ -15,7 +15,12 @@
 install.codegen:
 	sh scripts/gen/codegen.sh install_controller_gen
 	sh scripts/gen/codegen.sh install_deepcopy_gen
+	sh scripts/gen/codegen.sh install_protoc_gen
 
 # gen.code.<api group name>.<api group version>
 gen.code.samplecrd.v1alpha1:
 	sh scripts/gen/codegen.sh gen $@
+
+# gen.proto generates go code of the plugin protocol, requires protoc
+gen.proto:
+	sh scripts/gen/codegen.sh gen_proto
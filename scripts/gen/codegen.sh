@@ -44,18 +44,47 @@ _install_go_bin() {
   cd -
 }
 
+install_protoc_gen_proto() {
+  PATH="${GOPATH}/bin:${PATH}" protoc \
+    -I ./pkg/plugin \
+    --go_out=paths=source_relative:./pkg/plugin \
+    --go-grpc_out=paths=source_relative,require_unimplemented_servers=false:./pkg/plugin \
+    ./pkg/plugin/plugin.proto
+}
+
+gen() {
+  _install_go_bin "google.golang.org/protobuf@v1.27.1" "./cmd/protoc-gen-go" "${GOPATH}/bin/protoc-gen-go"
+  _install_go_bin "google.golang.org/grpc/cmd/protoc-gen-go-grpc@v1.2.0" "." "${GOPATH}/bin/protoc-gen-go-grpc"
+}
+
 _do_sync_gopath() {
   mkdir -p "${GOPATH}/src/arhat.dev"
   rsync -avh "$(pwd)" "${GOPATH}/src/arhat.dev/"
 }
 
-install_deepcopy_gen() {
+install_deepcopy_gen_proto() {
+  PATH="${GOPATH}/bin:${PATH}" protoc \
+    -I ./pkg/plugin \
+    --go_out=paths=source_relative:./pkg/plugin \
+    --go-grpc_out=paths=source_relative,require_unimplemented_servers=false:./pkg/plugin \
+    ./pkg/plugin/plugin.proto
+}
+
+gen() {
   _install_go_bin "k8s.io/code-generator@v0.18.10" "./cmd/client-gen" "${GOPATH}/bin/client-gen"
   _install_go_bin "k8s.io/code-generator@v0.18.10" "./cmd/lister-gen" "${GOPATH}/bin/lister-gen"
   _install_go_bin "k8s.io/code-generator@v0.18.10" "./cmd/informer-gen" "${GOPATH}/bin/informer-gen"
 }
 
-install_controller_gen() {
+install_controller_gen_proto() {
+  PATH="${GOPATH}/bin:${PATH}" protoc \
+    -I ./pkg/plugin \
+    --go_out=paths=source_relative:./pkg/plugin \
+    --go-grpc_out=paths=source_relative,require_unimplemented_servers=false:./pkg/plugin \
+    ./pkg/plugin/plugin.proto
+}
+
+gen() {
   _install_go_bin "sigs.k8s.io/controller-tools@v0.4.0" "./cmd/controller-gen" "${CONTROLLER_GEN}"
 }
 
@@ -99,6 +128,14 @@ _do_gen_crd_manifests() {
   "${CONTROLLER_GEN}" crd:preserveUnknownFields=true,crdVersions=v1beta1 output:dir=./cicd/deploy/charts/ksync/crds/ paths="./pkg/apis/${group_name}/..."
 }
 
+gen_proto() {
+  PATH="${GOPATH}/bin:${PATH}" protoc \
+    -I ./pkg/plugin \
+    --go_out=paths=source_relative:./pkg/plugin \
+    --go-grpc_out=paths=source_relative,require_unimplemented_servers=false:./pkg/plugin \
+    ./pkg/plugin/plugin.proto
+}
+
 gen() {
   cmd=$(printf "%s" "$@" | tr '.'  ' ')
 